@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sponsoration/api/internal/emaillint"
+	"github.com/sponsoration/api/internal/service"
+)
+
+func main() {
+	fmt.Println("🔍 Linting email templates...")
+	fmt.Println()
+
+	rc := service.NewEmailService().RenderContext()
+
+	failed := 0
+	for _, tmpl := range service.Templates() {
+		msg := tmpl.Sample(rc)
+		issues := emaillint.Lint(msg.HTML)
+		issues = append(issues, emaillint.CheckPlaceholders(msg.Subject+"\n"+msg.Text)...)
+
+		if len(issues) == 0 {
+			fmt.Printf("   ✅ %s (%d bytes)\n", tmpl.Name, len(msg.HTML))
+			continue
+		}
+
+		failed++
+		fmt.Printf("   ❌ %s (%d bytes)\n", tmpl.Name, len(msg.HTML))
+		for _, issue := range issues {
+			fmt.Printf("      - %s\n", issue)
+		}
+	}
+
+	fmt.Println()
+	if failed > 0 {
+		fmt.Printf("⚠️  %d template(s) have email-client compatibility issues\n", failed)
+		os.Exit(1)
+	}
+	fmt.Println("🎉 All templates passed!")
+}
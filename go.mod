@@ -4,10 +4,13 @@ go 1.24.0
 
 toolchain go1.24.10
 
-require github.com/sendgrid/sendgrid-go v3.14.0+incompatible
+require (
+	github.com/mattn/go-sqlite3 v1.14.32
+	github.com/sendgrid/sendgrid-go v3.14.0+incompatible
+	golang.org/x/net v0.47.0
+)
 
 require (
 	github.com/sendgrid/rest v2.6.9+incompatible // indirect
 	github.com/stretchr/testify v1.11.1 // indirect
-	golang.org/x/net v0.47.0 // indirect
 )
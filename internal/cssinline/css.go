@@ -0,0 +1,147 @@
+package cssinline
+
+import (
+	"fmt"
+	"strings"
+)
+
+// declaration is a single "property: value" pair
+type declaration struct {
+	property  string
+	value     string
+	important bool
+}
+
+// rule is a style rule whose selectors can all be inlined
+type rule struct {
+	selectors    []selector
+	declarations []declaration
+	order        int
+}
+
+// stylesheet is a parsed <style> block split into rules that are inlined and
+// CSS that has to stay in the document head (media queries, pseudo-classes,
+// font faces and other at-rules)
+type stylesheet struct {
+	rules []rule
+	kept  []string
+}
+
+// parseStylesheet parses css. order is the source position of the first rule
+// so that rules from several <style> blocks cascade in document order.
+func parseStylesheet(css string, order int) (stylesheet, error) {
+	var sheet stylesheet
+	css = stripComments(css)
+
+	for {
+		css = strings.TrimSpace(css)
+		if css == "" {
+			return sheet, nil
+		}
+
+		open := strings.IndexByte(css, '{')
+		semi := strings.IndexByte(css, ';')
+
+		// Statement at-rules such as @charset or @import end at ';'
+		if strings.HasPrefix(css, "@") && semi >= 0 && (open < 0 || semi < open) {
+			sheet.kept = append(sheet.kept, strings.TrimSpace(css[:semi+1]))
+			css = css[semi+1:]
+			continue
+		}
+		if open < 0 {
+			return sheet, fmt.Errorf("cssinline: expected '{' after %q", css)
+		}
+
+		end, err := matchingBrace(css, open)
+		if err != nil {
+			return sheet, err
+		}
+		prelude := strings.TrimSpace(css[:open])
+		body := css[open+1 : end]
+		block := strings.TrimSpace(css[:end+1])
+		css = css[end+1:]
+
+		if strings.HasPrefix(prelude, "@") {
+			sheet.kept = append(sheet.kept, block)
+			continue
+		}
+
+		decls := parseDeclarations(body)
+		var inline []selector
+		var keep []string
+		for _, raw := range strings.Split(prelude, ",") {
+			if sel, ok := parseSelector(raw); ok {
+				inline = append(inline, sel)
+			} else {
+				keep = append(keep, strings.TrimSpace(raw))
+			}
+		}
+
+		if len(inline) > 0 {
+			sheet.rules = append(sheet.rules, rule{selectors: inline, declarations: decls, order: order})
+			order++
+		}
+		if len(keep) > 0 {
+			sheet.kept = append(sheet.kept, strings.Join(keep, ", ")+" { "+strings.TrimSpace(body)+" }")
+		}
+	}
+}
+
+// parseDeclarations parses the body of a rule or a style attribute
+func parseDeclarations(s string) []declaration {
+	var decls []declaration
+	for _, part := range strings.Split(s, ";") {
+		prop, value, ok := strings.Cut(part, ":")
+		if !ok {
+			continue
+		}
+		prop = strings.ToLower(strings.TrimSpace(prop))
+		value = strings.TrimSpace(value)
+		if prop == "" || value == "" {
+			continue
+		}
+
+		d := declaration{property: prop, value: value}
+		if i := strings.Index(strings.ToLower(value), "!important"); i >= 0 {
+			d.important = true
+			d.value = strings.TrimSpace(value[:i])
+		}
+		decls = append(decls, d)
+	}
+	return decls
+}
+
+// matchingBrace returns the index of the brace closing the one at open
+func matchingBrace(s string, open int) (int, error) {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("cssinline: unbalanced braces in %q", s)
+}
+
+// stripComments removes /* ... */ comments
+func stripComments(css string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(css, "/*")
+		if start < 0 {
+			b.WriteString(css)
+			return b.String()
+		}
+		b.WriteString(css[:start])
+		end := strings.Index(css[start+2:], "*/")
+		if end < 0 {
+			return b.String()
+		}
+		css = css[start+2+end+2:]
+	}
+}
@@ -0,0 +1,272 @@
+// Package cssinline moves the rules of <style> blocks into inline style
+// attributes so HTML emails can be authored with a stylesheet.
+//
+// Rules are applied with normal cascade semantics: !important first, then
+// specificity, then source order. Existing style attributes win over
+// stylesheet rules unless the rule is !important. Media queries, at-rules
+// and selectors that cannot be inlined (pseudo-classes, sibling
+// combinators) are left in a <style> block so responsive clients still see
+// them; use !important inside media queries to override inlined values.
+package cssinline
+
+import (
+	"io"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// inlineSpecificity ranks declarations from a style attribute above any
+// selector
+const inlineSpecificity = 1 << 30
+
+// voidElements never have an end tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"source": true, "track": true, "wbr": true,
+}
+
+// node is an element in document order, with just enough structure for
+// selector matching
+type node struct {
+	tag     string
+	attrs   map[string]string
+	classes []string
+	parent  *node
+}
+
+// hasClass reports whether the element has the given class
+func (n *node) hasClass(class string) bool {
+	for _, c := range n.classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// Inline applies the rules of every <style> block in doc as inline styles.
+// Markup that is not touched is copied byte for byte.
+func Inline(doc string) (string, error) {
+	nodes, sheets, err := scan(doc)
+	if err != nil {
+		return "", err
+	}
+
+	var rules []rule
+	for _, sheet := range sheets {
+		rules = append(rules, sheet.rules...)
+	}
+
+	styles := make([]string, len(nodes))
+	changed := make([]bool, len(nodes))
+	for i, n := range nodes {
+		if len(rules) == 0 || n.tag == "style" || n.tag == "head" || n.parent != nil && n.parent.tag == "head" {
+			continue
+		}
+		styles[i], changed[i] = computeStyle(n, rules)
+	}
+
+	return rewrite(doc, styles, changed, sheets), nil
+}
+
+// scan tokenizes doc into elements and parsed style blocks
+func scan(doc string) ([]*node, []stylesheet, error) {
+	var nodes []*node
+	var sheets []stylesheet
+	var stack []*node
+	order := 0
+
+	z := html.NewTokenizer(strings.NewReader(doc))
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if err := z.Err(); err != io.EOF {
+				return nil, nil, err
+			}
+			return nodes, sheets, nil
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			n := &node{tag: tok.Data, attrs: map[string]string{}}
+			for _, a := range tok.Attr {
+				n.attrs[a.Key] = a.Val
+			}
+			n.classes = strings.Fields(n.attrs["class"])
+			if len(stack) > 0 {
+				n.parent = stack[len(stack)-1]
+			}
+			nodes = append(nodes, n)
+
+			// <style> holds raw text only, so it is never pushed
+			if tt == html.StartTagToken && !voidElements[n.tag] && n.tag != "style" {
+				stack = append(stack, n)
+			}
+			if n.tag == "style" {
+				css := ""
+				if z.Next() == html.TextToken {
+					css = string(z.Text())
+				}
+				sheet, err := parseStylesheet(css, order)
+				if err != nil {
+					return nil, nil, err
+				}
+				order += len(sheet.rules)
+				sheets = append(sheets, sheet)
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i].tag == string(name) {
+					stack = stack[:i]
+					break
+				}
+			}
+		}
+	}
+}
+
+// computeStyle resolves the cascade for n. changed is false when no rule
+// matched and the element can be copied unmodified.
+func computeStyle(n *node, rules []rule) (style string, changed bool) {
+	type candidate struct {
+		declaration
+		specificity int
+		order       int
+	}
+
+	var candidates []candidate
+	for _, r := range rules {
+		best := -1
+		for _, sel := range r.selectors {
+			if sel.specificity > best && sel.matches(n) {
+				best = sel.specificity
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		for _, d := range r.declarations {
+			candidates = append(candidates, candidate{declaration: d, specificity: best, order: r.order})
+		}
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	for _, d := range parseDeclarations(n.attrs["style"]) {
+		candidates = append(candidates, candidate{declaration: d, specificity: inlineSpecificity})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.important != b.important {
+			return !a.important
+		}
+		if a.specificity != b.specificity {
+			return a.specificity < b.specificity
+		}
+		return a.order < b.order
+	})
+
+	// Later candidates win; properties keep the position they were first
+	// declared at so output reads in authoring order
+	values := map[string]string{}
+	var props []string
+	for _, c := range candidates {
+		if _, seen := values[c.property]; !seen {
+			props = append(props, c.property)
+		}
+		values[c.property] = c.value
+	}
+
+	parts := make([]string, len(props))
+	for i, p := range props {
+		parts[i] = p + ": " + values[p] + ";"
+	}
+	return strings.Join(parts, " "), true
+}
+
+// rewrite copies doc, replacing the style attribute of changed elements and
+// reducing each <style> block to the CSS that could not be inlined
+func rewrite(doc string, styles []string, changed []bool, sheets []stylesheet) string {
+	var b strings.Builder
+	b.Grow(len(doc) + len(doc)/2)
+
+	z := html.NewTokenizer(strings.NewReader(doc))
+	index := 0
+	sheet := 0
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return b.String()
+		case html.StartTagToken, html.SelfClosingTagToken:
+			raw := string(z.Raw())
+			tok := z.Token()
+			i := index
+			index++
+
+			if tok.Data == "style" {
+				kept := sheets[sheet].kept
+				sheet++
+				// Consume the CSS text and the end tag
+				if z.Next() == html.TextToken {
+					z.Next()
+				}
+				if len(kept) == 0 {
+					continue
+				}
+				b.WriteString(raw)
+				b.WriteString("\n    ")
+				b.WriteString(strings.Join(kept, "\n    "))
+				b.WriteString("\n  </style>")
+				continue
+			}
+
+			if !changed[i] {
+				b.WriteString(raw)
+				continue
+			}
+			writeTag(&b, tok, styles[i], tt == html.SelfClosingTagToken)
+		default:
+			b.Write(z.Raw())
+		}
+	}
+}
+
+// writeTag serializes a start tag with the given style attribute
+func writeTag(b *strings.Builder, tok html.Token, style string, selfClosing bool) {
+	b.WriteString("<")
+	b.WriteString(tok.Data)
+	wroteStyle := false
+	for _, a := range tok.Attr {
+		val := a.Val
+		if a.Key == "style" {
+			val = style
+			wroteStyle = true
+		}
+		writeAttr(b, a.Key, val)
+	}
+	if !wroteStyle {
+		writeAttr(b, "style", style)
+	}
+	if selfClosing {
+		b.WriteString(" /")
+	}
+	b.WriteString(">")
+}
+
+// writeAttr writes ` key="value"`, escaping only what a double quoted
+// attribute requires
+func writeAttr(b *strings.Builder, key, val string) {
+	val = strings.ReplaceAll(val, "&", "&amp;")
+	val = strings.ReplaceAll(val, `"`, "&quot;")
+	b.WriteString(" ")
+	b.WriteString(key)
+	b.WriteString(`="`)
+	b.WriteString(val)
+	b.WriteString(`"`)
+}
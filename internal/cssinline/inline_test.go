@@ -0,0 +1,105 @@
+package cssinline
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInline(t *testing.T) {
+	tests := []struct {
+		name    string
+		html    string
+		want    []string
+		notWant []string
+	}{
+		{
+			name: "type, class and id selectors",
+			html: `<style>p { color: red; } .lead { font-size: 18px; } #intro { margin: 0; }</style>` +
+				`<p class="lead" id="intro">Hi</p>`,
+			want: []string{`<p class="lead" id="intro" style="color: red; font-size: 18px; margin: 0;">`},
+		},
+		{
+			name: "higher specificity wins regardless of order",
+			html: `<style>.box p { color: blue; } p { color: red; }</style><div class="box"><p>x</p></div>`,
+			want: []string{`<p style="color: blue;">`},
+		},
+		{
+			name: "later rule wins at equal specificity",
+			html: `<style>p { color: red; } p { color: green; }</style><p>x</p>`,
+			want: []string{`<p style="color: green;">`},
+		},
+		{
+			name: "existing inline style wins",
+			html: `<style>p { color: red; padding: 0; }</style><p style="color: black">x</p>`,
+			want: []string{`<p style="color: black; padding: 0;">`},
+		},
+		{
+			name: "important rule beats inline style",
+			html: `<style>p { color: red !important; }</style><p style="color: black">x</p>`,
+			want: []string{`<p style="color: red;">`},
+		},
+		{
+			name: "child combinator",
+			html: `<style>td > p { color: red; }</style><table><tr><td><p>a</p><div><p>b</p></div></td></tr></table>`,
+			want: []string{`<p style="color: red;">a`, `<div><p>b`},
+		},
+		{
+			name: "attribute selector",
+			html: `<style>td[align="center"] { padding: 4px; }</style><td align="center">a</td><td align="left">b</td>`,
+			want: []string{`<td align="center" style="padding: 4px;">`, `<td align="left">`},
+		},
+		{
+			name:    "media queries stay in the head",
+			html:    `<head><style>p { color: red; } @media (max-width: 600px) { p { color: blue !important; } }</style></head><body><p>x</p></body>`,
+			want:    []string{`@media (max-width: 600px) { p { color: blue !important; } }`, `<p style="color: red;">`},
+			notWant: []string{`p { color: red; }`},
+		},
+		{
+			name:    "pseudo-classes are kept, not inlined",
+			html:    `<style>a:hover { color: red; } a, b { color: blue; }</style><a href="https://x.test">x</a>`,
+			want:    []string{`a:hover { color: red; }`, `<a href="https://x.test" style="color: blue;">`},
+			notWant: []string{`color: red;">`},
+		},
+		{
+			name:    "fully inlined style block is removed",
+			html:    `<style>/* base */ p { margin: 0; }</style><p>x</p>`,
+			want:    []string{`<p style="margin: 0;">x</p>`},
+			notWant: []string{`<style>`, `base`},
+		},
+		{
+			name: "untouched markup is copied verbatim",
+			html: `<style>p { margin: 0; }</style><div style="font-family: 'Courier New', monospace;">&copy; 2025<br></div><p>x</p>`,
+			want: []string{`<div style="font-family: 'Courier New', monospace;">&copy; 2025<br></div>`},
+		},
+		{
+			name: "void elements do not swallow siblings",
+			html: `<style>.row > span { color: red; }</style><div class="row"><img src="https://x.test/a.png"><span>x</span></div>`,
+			want: []string{`<span style="color: red;">`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Inline(tt.html)
+			if err != nil {
+				t.Fatalf("Inline() error = %v", err)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("output missing %q\ngot: %s", w, got)
+				}
+			}
+			for _, nw := range tt.notWant {
+				if strings.Contains(got, nw) {
+					t.Errorf("output should not contain %q\ngot: %s", nw, got)
+				}
+			}
+		})
+	}
+}
+
+func TestInline_UnbalancedBraces(t *testing.T) {
+	if _, err := Inline(`<style>p { color: red; </style><p>x</p>`); err == nil {
+		t.Error("Inline() should fail on unbalanced braces")
+	}
+}
@@ -0,0 +1,238 @@
+package cssinline
+
+import (
+	"strings"
+)
+
+// combinator joins two compound selectors
+type combinator int
+
+const (
+	descendant combinator = iota
+	child
+)
+
+// attrMatcher is an attribute selector such as [align] or [align="center"]
+type attrMatcher struct {
+	key   string
+	op    string
+	value string
+}
+
+// compound is a sequence of simple selectors that all apply to one element,
+// e.g. td.header#top[align="center"]
+type compound struct {
+	tag     string
+	id      string
+	classes []string
+	attrs   []attrMatcher
+}
+
+// selector is a chain of compounds. combinators[i] joins parts[i] to
+// parts[i+1].
+type selector struct {
+	parts       []compound
+	combinators []combinator
+	specificity int
+}
+
+// parseSelector parses a single selector (no commas). ok is false for
+// selectors that cannot be expressed as inline styles, such as pseudo-classes
+// and sibling combinators.
+func parseSelector(s string) (sel selector, ok bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return selector{}, false
+	}
+
+	var cur compound
+	pendingComb := -1 // -1: none, otherwise a combinator waiting for the next compound
+	started := false
+
+	flush := func() {
+		if pendingComb >= 0 {
+			sel.combinators = append(sel.combinators, combinator(pendingComb))
+		}
+		sel.parts = append(sel.parts, cur)
+		cur = compound{}
+		pendingComb = -1
+		started = false
+	}
+
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if started {
+				flush()
+				pendingComb = int(descendant)
+			}
+			i++
+		case c == '>':
+			if started {
+				flush()
+			}
+			if len(sel.parts) == 0 {
+				return selector{}, false
+			}
+			pendingComb = int(child)
+			i++
+		case c == '+' || c == '~' || c == ':' || c == ',':
+			return selector{}, false
+		case c == '*':
+			started = true
+			i++
+		case c == '#':
+			name, n := readIdent(s[i+1:])
+			if name == "" {
+				return selector{}, false
+			}
+			cur.id = name
+			sel.specificity += 10000
+			started = true
+			i += 1 + n
+		case c == '.':
+			name, n := readIdent(s[i+1:])
+			if name == "" {
+				return selector{}, false
+			}
+			cur.classes = append(cur.classes, name)
+			sel.specificity += 100
+			started = true
+			i += 1 + n
+		case c == '[':
+			end := strings.IndexByte(s[i:], ']')
+			if end < 0 {
+				return selector{}, false
+			}
+			m, valid := parseAttrMatcher(s[i+1 : i+end])
+			if !valid {
+				return selector{}, false
+			}
+			cur.attrs = append(cur.attrs, m)
+			sel.specificity += 100
+			started = true
+			i += end + 1
+		default:
+			name, n := readIdent(s[i:])
+			if name == "" {
+				return selector{}, false
+			}
+			cur.tag = strings.ToLower(name)
+			sel.specificity++
+			started = true
+			i += n
+		}
+	}
+
+	if !started {
+		// Trailing combinator
+		return selector{}, false
+	}
+	flush()
+	return sel, true
+}
+
+// readIdent reads a CSS identifier from the start of s
+func readIdent(s string) (string, int) {
+	n := 0
+	for n < len(s) {
+		c := s[n]
+		if c == '-' || c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' {
+			n++
+			continue
+		}
+		break
+	}
+	return s[:n], n
+}
+
+// parseAttrMatcher parses the inside of an attribute selector
+func parseAttrMatcher(s string) (attrMatcher, bool) {
+	for _, op := range []string{"~=", "^=", "$=", "*=", "="} {
+		if key, value, found := strings.Cut(s, op); found {
+			key = strings.TrimSpace(key)
+			value = strings.Trim(strings.TrimSpace(value), `"'`)
+			if key == "" {
+				return attrMatcher{}, false
+			}
+			return attrMatcher{key: strings.ToLower(key), op: op, value: value}, true
+		}
+	}
+
+	key := strings.TrimSpace(s)
+	if key == "" {
+		return attrMatcher{}, false
+	}
+	return attrMatcher{key: strings.ToLower(key)}, true
+}
+
+// matches reports whether the selector matches n
+func (sel selector) matches(n *node) bool {
+	return sel.matchFrom(len(sel.parts)-1, n)
+}
+
+// matchFrom matches parts[0..i] with parts[i] applied to n
+func (sel selector) matchFrom(i int, n *node) bool {
+	if !sel.parts[i].matches(n) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+
+	switch sel.combinators[i-1] {
+	case child:
+		return n.parent != nil && sel.matchFrom(i-1, n.parent)
+	default:
+		for p := n.parent; p != nil; p = p.parent {
+			if sel.matchFrom(i-1, p) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// matches reports whether every simple selector in c applies to n
+func (c compound) matches(n *node) bool {
+	if c.tag != "" && c.tag != n.tag {
+		return false
+	}
+	if c.id != "" && c.id != n.attrs["id"] {
+		return false
+	}
+	for _, class := range c.classes {
+		if !n.hasClass(class) {
+			return false
+		}
+	}
+	for _, a := range c.attrs {
+		v, ok := n.attrs[a.key]
+		if !ok {
+			return false
+		}
+		switch a.op {
+		case "=":
+			ok = v == a.value
+		case "~=":
+			ok = false
+			for _, f := range strings.Fields(v) {
+				if f == a.value {
+					ok = true
+				}
+			}
+		case "^=":
+			ok = strings.HasPrefix(v, a.value)
+		case "$=":
+			ok = strings.HasSuffix(v, a.value)
+		case "*=":
+			ok = strings.Contains(v, a.value)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
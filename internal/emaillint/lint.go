@@ -0,0 +1,249 @@
+// Package emaillint checks rendered HTML emails for constructs that break or
+// degrade in common email clients (Gmail, Outlook, Apple Mail).
+package emaillint
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// GmailClipSize is the HTML size in bytes above which Gmail clips a message
+// and hides the rest behind a "View entire message" link
+const GmailClipSize = 102 * 1024
+
+// Rule names reported in Issue.Rule
+const (
+	RuleImageAlt           = "img-alt"
+	RuleImageDimensions    = "img-dimensions"
+	RuleUnsupportedCSS     = "unsupported-css"
+	RuleExternalStylesheet = "external-stylesheet"
+	RuleRelativeLink       = "relative-link"
+	RuleSize               = "size"
+	RulePlaceholder        = "placeholder"
+	RuleParse              = "parse"
+)
+
+// Issue is a single lint finding
+type Issue struct {
+	Rule    string
+	Message string
+}
+
+// String formats the issue as "rule: message"
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s", i.Rule, i.Message)
+}
+
+// unsupportedProperties are CSS properties that are ignored or mangled by at
+// least one major email client
+var unsupportedProperties = map[string]bool{
+	"position":        true,
+	"float":           true,
+	"transform":       true,
+	"transition":      true,
+	"animation":       true,
+	"filter":          true,
+	"backdrop-filter": true,
+	"clip-path":       true,
+	"object-fit":      true,
+	"box-shadow":      true,
+	"flex":            true,
+	"flex-direction":  true,
+	"flex-wrap":       true,
+	"justify-content": true,
+	"align-items":     true,
+	"grid":            true,
+	"grid-template":   true,
+	"gap":             true,
+}
+
+// unsupportedDisplays are values of the display property that email clients
+// do not lay out
+var unsupportedDisplays = map[string]bool{
+	"flex":        true,
+	"inline-flex": true,
+	"grid":        true,
+	"inline-grid": true,
+}
+
+// placeholderPattern matches leftovers from fmt verbs, fmt errors and
+// template delimiters
+var placeholderPattern = regexp.MustCompile(`%!|%[sdvq]\b|\{\{[^{}]*\}\}`)
+
+// absoluteSchemes are URL prefixes that resolve outside the message
+var absoluteSchemes = []string{"http://", "https://", "mailto:", "tel:", "cid:", "data:"}
+
+// Lint reports every email-client compatibility issue found in doc
+func Lint(doc string) []Issue {
+	var issues []Issue
+
+	if len(doc) > GmailClipSize {
+		issues = append(issues, Issue{
+			Rule:    RuleSize,
+			Message: fmt.Sprintf("HTML is %d bytes, Gmail clips messages above %d bytes", len(doc), GmailClipSize),
+		})
+	}
+	issues = append(issues, CheckPlaceholders(doc)...)
+
+	z := html.NewTokenizer(strings.NewReader(doc))
+	inStyle := false
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if err := z.Err(); err != io.EOF {
+				issues = append(issues, Issue{Rule: RuleParse, Message: err.Error()})
+			}
+			return issues
+		case html.TextToken:
+			if inStyle {
+				issues = append(issues, checkStylesheet(string(z.Text()))...)
+			}
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == "style" {
+				inStyle = false
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			if tok.Data == "style" {
+				inStyle = true
+			}
+			issues = append(issues, checkElement(tok)...)
+		}
+	}
+}
+
+// CheckPlaceholders reports unreplaced placeholders in s. It applies to both
+// HTML and plain-text bodies.
+func CheckPlaceholders(s string) []Issue {
+	var issues []Issue
+	seen := map[string]bool{}
+	for _, m := range placeholderPattern.FindAllString(s, -1) {
+		if seen[m] {
+			continue
+		}
+		seen[m] = true
+		issues = append(issues, Issue{
+			Rule:    RulePlaceholder,
+			Message: fmt.Sprintf("unreplaced placeholder %q", m),
+		})
+	}
+	return issues
+}
+
+// checkElement runs the per-element rules on a start tag
+func checkElement(tok html.Token) []Issue {
+	var issues []Issue
+	attrs := map[string]string{}
+	for _, a := range tok.Attr {
+		attrs[a.Key] = a.Val
+	}
+
+	switch tok.Data {
+	case "img":
+		src := attrs["src"]
+		if _, ok := attrs["alt"]; !ok {
+			issues = append(issues, Issue{
+				Rule:    RuleImageAlt,
+				Message: fmt.Sprintf("<img src=%q> has no alt attribute", src),
+			})
+		}
+		if attrs["width"] == "" || attrs["height"] == "" {
+			issues = append(issues, Issue{
+				Rule:    RuleImageDimensions,
+				Message: fmt.Sprintf("<img src=%q> needs width and height attributes", src),
+			})
+		}
+	case "link":
+		if strings.EqualFold(attrs["rel"], "stylesheet") {
+			issues = append(issues, Issue{
+				Rule:    RuleExternalStylesheet,
+				Message: fmt.Sprintf("external stylesheet %q is stripped by most clients", attrs["href"]),
+			})
+		}
+	}
+
+	for _, key := range []string{"href", "src"} {
+		if v, ok := attrs[key]; ok && !isAbsoluteURL(v) {
+			issues = append(issues, Issue{
+				Rule:    RuleRelativeLink,
+				Message: fmt.Sprintf("<%s %s=%q> is not an absolute URL", tok.Data, key, v),
+			})
+		}
+	}
+
+	if style, ok := attrs["style"]; ok {
+		issues = append(issues, checkDeclarations(style, "<"+tok.Data+">")...)
+	}
+	return issues
+}
+
+// checkStylesheet checks the contents of a <style> block
+func checkStylesheet(css string) []Issue {
+	var issues []Issue
+	if strings.Contains(css, "@import") {
+		issues = append(issues, Issue{
+			Rule:    RuleExternalStylesheet,
+			Message: "@import in <style> is not supported by most clients",
+		})
+	}
+
+	// Only the declaration blocks matter, selectors and at-rule preludes
+	// are skipped
+	for _, block := range strings.Split(css, "{") {
+		decls, _, _ := strings.Cut(block, "}")
+		issues = append(issues, checkDeclarations(decls, "<style>")...)
+	}
+	return issues
+}
+
+// checkDeclarations checks a semicolon separated list of CSS declarations
+func checkDeclarations(decls, where string) []Issue {
+	var issues []Issue
+	for _, decl := range strings.Split(decls, ";") {
+		prop, value, ok := strings.Cut(decl, ":")
+		if !ok {
+			continue
+		}
+		prop = strings.ToLower(strings.TrimSpace(prop))
+		value = strings.ToLower(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "!important")))
+
+		switch {
+		case unsupportedProperties[prop] || strings.HasPrefix(prop, "grid-"):
+			issues = append(issues, Issue{
+				Rule:    RuleUnsupportedCSS,
+				Message: fmt.Sprintf("%s uses unsupported property %q", where, prop),
+			})
+		case prop == "display" && unsupportedDisplays[value]:
+			issues = append(issues, Issue{
+				Rule:    RuleUnsupportedCSS,
+				Message: fmt.Sprintf("%s uses unsupported value display: %s", where, value),
+			})
+		case strings.Contains(value, "var(--"):
+			issues = append(issues, Issue{
+				Rule:    RuleUnsupportedCSS,
+				Message: fmt.Sprintf("%s uses a CSS custom property in %q", where, prop),
+			})
+		}
+	}
+	return issues
+}
+
+// isAbsoluteURL reports whether a link resolves without a base URL.
+// In-message anchors ("#top") are allowed.
+func isAbsoluteURL(u string) bool {
+	u = strings.TrimSpace(u)
+	if strings.HasPrefix(u, "#") {
+		return true
+	}
+	lower := strings.ToLower(u)
+	for _, scheme := range absoluteSchemes {
+		if strings.HasPrefix(lower, scheme) {
+			return true
+		}
+	}
+	return false
+}
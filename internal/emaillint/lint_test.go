@@ -0,0 +1,103 @@
+package emaillint
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name      string
+		html      string
+		wantRules []string
+	}{
+		{
+			name: "clean document",
+			html: `<!DOCTYPE html><html><head><style>p { color: #111; }</style></head>` +
+				`<body><a href="https://example.com">x</a><img src="https://example.com/a.png" alt="" width="10" height="10"></body></html>`,
+			wantRules: nil,
+		},
+		{
+			name:      "image without alt",
+			html:      `<img src="https://example.com/a.png" width="10" height="10">`,
+			wantRules: []string{RuleImageAlt},
+		},
+		{
+			name:      "image without dimensions",
+			html:      `<img src="https://example.com/a.png" alt="logo">`,
+			wantRules: []string{RuleImageDimensions},
+		},
+		{
+			name:      "unsupported inline property",
+			html:      `<div style="position: absolute; color: red;">x</div>`,
+			wantRules: []string{RuleUnsupportedCSS},
+		},
+		{
+			name:      "flex display in style block",
+			html:      `<style>.row { display: flex; }</style>`,
+			wantRules: []string{RuleUnsupportedCSS},
+		},
+		{
+			name:      "css custom property",
+			html:      `<p style="color: var(--brand)">x</p>`,
+			wantRules: []string{RuleUnsupportedCSS},
+		},
+		{
+			name:      "external stylesheet",
+			html:      `<link rel="stylesheet" href="https://example.com/a.css">`,
+			wantRules: []string{RuleExternalStylesheet},
+		},
+		{
+			name:      "css import",
+			html:      `<style>@import url("https://example.com/a.css");</style>`,
+			wantRules: []string{RuleExternalStylesheet},
+		},
+		{
+			name:      "relative link",
+			html:      `<a href="/dashboard">x</a>`,
+			wantRules: []string{RuleRelativeLink},
+		},
+		{
+			name:      "anchors, mailto and tel are allowed",
+			html:      `<a href="#top">x</a><a href="mailto:a@example.com">y</a><a href="tel:+15555550100">z</a>`,
+			wantRules: nil,
+		},
+		{
+			name:      "fmt verb left behind",
+			html:      `<p>Hello %s</p>`,
+			wantRules: []string{RulePlaceholder},
+		},
+		{
+			name:      "fmt missing argument",
+			html:      `<p>Hello %!s(MISSING)</p>`,
+			wantRules: []string{RulePlaceholder},
+		},
+		{
+			name:      "template action left behind",
+			html:      `<p>Hello {{ .Name }}</p>`,
+			wantRules: []string{RulePlaceholder},
+		},
+		{
+			name:      "percent widths are not placeholders",
+			html:      `<table width="100%" style="width: 100%;"></table>`,
+			wantRules: nil,
+		},
+		{
+			name:      "oversized document",
+			html:      "<p>" + strings.Repeat("a", GmailClipSize) + "</p>",
+			wantRules: []string{RuleSize},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, issue := range Lint(tt.html) {
+				got = append(got, issue.Rule)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantRules, ",") {
+				t.Errorf("Lint() rules = %v, want %v", got, tt.wantRules)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package emaillint
+
+import "testing"
+
+// AssertClean fails the test with every issue Lint reports for doc. name
+// identifies the template in failure messages.
+func AssertClean(t testing.TB, name, doc string) {
+	t.Helper()
+	for _, issue := range Lint(doc) {
+		t.Errorf("%s: %s", name, issue)
+	}
+}
@@ -0,0 +1,237 @@
+// Package ical writes iCalendar (RFC 5545) invitations that calendar clients
+// can accept, decline or remove, following the iTIP methods of RFC 5546.
+package ical
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Method is the iTIP method of a calendar
+type Method string
+
+// Supported methods
+const (
+	// MethodRequest invites attendees, or updates an invitation they have
+	// when the UID matches and the sequence is higher
+	MethodRequest Method = "REQUEST"
+	// MethodCancel removes an event from the attendees' calendars
+	MethodCancel Method = "CANCEL"
+)
+
+// Person is an organizer or attendee
+type Person struct {
+	Name  string
+	Email string
+}
+
+// Event is a single meeting
+type Event struct {
+	// UID identifies the event across updates and cancellations
+	UID string
+	// Sequence is increased every time the event changes
+	Sequence int
+	// Stamp is when this version of the event was created
+	Stamp time.Time
+	// Start and End are written in the time zone of Start
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+	Organizer   Person
+	Attendees   []Person
+}
+
+// Calendar is an iCalendar object with one event
+type Calendar struct {
+	// ProductID names the software that wrote the calendar, e.g.
+	// "-//Sponsoration//Calendar//EN"
+	ProductID string
+	Method    Method
+	Event     Event
+}
+
+// Bytes writes the calendar with CRLF line endings and long lines folded
+func (c Calendar) Bytes() []byte {
+	e := c.Event
+	loc := e.Start.Location()
+	zoned := loc != time.UTC && loc != time.Local && loc.String() != "UTC"
+
+	w := &writer{}
+	w.line("BEGIN:VCALENDAR")
+	w.line("VERSION:2.0")
+	w.line("PRODID:" + c.ProductID)
+	w.line("CALSCALE:GREGORIAN")
+	w.line("METHOD:" + string(c.Method))
+	if zoned {
+		writeTimezone(w, loc, e.Start, e.End)
+	}
+
+	w.line("BEGIN:VEVENT")
+	w.line("UID:" + e.UID)
+	w.line(fmt.Sprintf("SEQUENCE:%d", e.Sequence))
+	w.line("DTSTAMP:" + utcTime(e.Stamp))
+	if zoned {
+		w.line(fmt.Sprintf("DTSTART;TZID=%s:%s", paramValue(loc.String()), localTime(e.Start)))
+		w.line(fmt.Sprintf("DTEND;TZID=%s:%s", paramValue(loc.String()), localTime(e.End.In(loc))))
+	} else {
+		w.line("DTSTART:" + utcTime(e.Start))
+		w.line("DTEND:" + utcTime(e.End))
+	}
+	w.line("SUMMARY:" + text(e.Summary))
+	if e.Description != "" {
+		w.line("DESCRIPTION:" + text(e.Description))
+	}
+	if e.Location != "" {
+		w.line("LOCATION:" + text(e.Location))
+	}
+	if e.URL != "" {
+		w.line("URL:" + e.URL)
+	}
+	w.line("ORGANIZER" + personParams(e.Organizer) + ":mailto:" + e.Organizer.Email)
+	for _, a := range e.Attendees {
+		params := personParams(a) + ";ROLE=REQ-PARTICIPANT;PARTSTAT=NEEDS-ACTION"
+		if c.Method == MethodRequest {
+			params += ";RSVP=TRUE"
+		}
+		w.line("ATTENDEE" + params + ":mailto:" + a.Email)
+	}
+	if c.Method == MethodCancel {
+		w.line("STATUS:CANCELLED")
+	} else {
+		w.line("STATUS:CONFIRMED")
+	}
+	w.line("END:VEVENT")
+	w.line("END:VCALENDAR")
+	return []byte(w.String())
+}
+
+// writer collects content lines
+type writer struct {
+	strings.Builder
+}
+
+// maxLineOctets is the longest a content line may be, without the CRLF
+const maxLineOctets = 75
+
+// line writes one content line, folding it into continuation lines that
+// start with a space. Lines are only broken between characters so UTF-8
+// sequences stay intact.
+func (w *writer) line(s string) {
+	n := 0
+	for _, r := range s {
+		size := len(string(r))
+		if n+size > maxLineOctets {
+			w.WriteString("\r\n ")
+			n = 1
+		}
+		w.WriteRune(r)
+		n += size
+	}
+	w.WriteString("\r\n")
+}
+
+// writeTimezone writes a VTIMEZONE for loc covering the years from start to
+// end: the offset in effect on January 1st of the first year, then every
+// transition until the end of the last year
+func writeTimezone(w *writer, loc *time.Location, start, end time.Time) {
+	w.line("BEGIN:VTIMEZONE")
+	w.line("TZID:" + loc.String())
+
+	from := time.Date(start.In(loc).Year(), time.January, 1, 0, 0, 0, 0, loc)
+	until := time.Date(end.In(loc).Year()+1, time.January, 1, 0, 0, 0, 0, loc)
+	_, offset := from.Zone()
+	writeObservance(w, from, offset)
+
+	for day := from; day.Before(until); day = day.AddDate(0, 0, 1) {
+		next := day.AddDate(0, 0, 1)
+		if _, o := next.Zone(); o == offset {
+			continue
+		}
+		// Narrow the change down to the second it happens
+		lo, hi := day.Unix(), next.Unix()
+		for hi-lo > 1 {
+			mid := lo + (hi-lo)/2
+			if _, o := time.Unix(mid, 0).In(loc).Zone(); o == offset {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		transition := time.Unix(hi, 0).In(loc)
+		writeObservance(w, transition, offset)
+		_, offset = transition.Zone()
+	}
+	w.line("END:VTIMEZONE")
+}
+
+// writeObservance writes the STANDARD or DAYLIGHT rule that starts at t,
+// when the offset changes from fromOffset to the one of t. DTSTART is the
+// local time just before the change.
+func writeObservance(w *writer, t time.Time, fromOffset int) {
+	kind := "STANDARD"
+	if t.IsDST() {
+		kind = "DAYLIGHT"
+	}
+	name, offset := t.Zone()
+	w.line("BEGIN:" + kind)
+	w.line("DTSTART:" + t.UTC().Add(time.Duration(fromOffset)*time.Second).Format("20060102T150405"))
+	w.line("TZOFFSETFROM:" + utcOffset(fromOffset))
+	w.line("TZOFFSETTO:" + utcOffset(offset))
+	w.line("TZNAME:" + text(name))
+	w.line("END:" + kind)
+}
+
+// utcOffset formats seconds east of UTC as +hhmm, with seconds if any
+func utcOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	s := fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds/60%60)
+	if seconds%60 != 0 {
+		s += fmt.Sprintf("%02d", seconds%60)
+	}
+	return s
+}
+
+// utcTime formats t as a UTC date-time
+func utcTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// localTime formats t as a local date-time of its own zone
+func localTime(t time.Time) string {
+	return t.Format("20060102T150405")
+}
+
+// textEscaper escapes TEXT property values
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", "")
+
+// text escapes a TEXT value
+func text(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// paramValue quotes a parameter value when it contains a delimiter. Double
+// quotes can't be escaped in parameters, so they are dropped.
+func paramValue(s string) string {
+	s = strings.NewReplacer(`"`, "", "\r", "", "\n", " ").Replace(s)
+	if strings.ContainsAny(s, ":;,") {
+		return `"` + s + `"`
+	}
+	return s
+}
+
+// personParams writes the common name parameter of an organizer or
+// attendee
+func personParams(p Person) string {
+	if p.Name == "" {
+		return ""
+	}
+	return ";CN=" + paramValue(p.Name)
+}
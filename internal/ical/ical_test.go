@@ -0,0 +1,137 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleEvent(t *testing.T) Event {
+	t.Helper()
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	start := time.Date(2025, time.March, 20, 15, 0, 0, 0, berlin)
+	return Event{
+		UID:         "kickoff-42@app.example.com",
+		Sequence:    1,
+		Stamp:       time.Date(2025, time.March, 14, 9, 30, 0, 0, time.UTC),
+		Start:       start,
+		End:         start.Add(30 * time.Minute),
+		Summary:     "Kickoff call; Spring, Trail",
+		Description: "Agenda:\nBriefing\\timeline",
+		Location:    "Google Meet",
+		URL:         "https://meet.example.com/abc",
+		Organizer:   Person{Name: "Lopez, Maria", Email: "maria@acme.example"},
+		Attendees:   []Person{{Name: "Jane Smith", Email: "jane@example.com"}},
+	}
+}
+
+func TestCalendar_Request(t *testing.T) {
+	out := string(Calendar{ProductID: "-//Test//EN", Method: MethodRequest, Event: sampleEvent(t)}.Bytes())
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Test//EN\r\n",
+		"METHOD:REQUEST\r\n",
+		"TZID:Europe/Berlin\r\n",
+		// CET on January 1st, then the switches to CEST and back
+		"BEGIN:STANDARD\r\nDTSTART:20250101T000000\r\nTZOFFSETFROM:+0100\r\nTZOFFSETTO:+0100\r\nTZNAME:CET\r\n",
+		"BEGIN:DAYLIGHT\r\nDTSTART:20250330T020000\r\nTZOFFSETFROM:+0100\r\nTZOFFSETTO:+0200\r\nTZNAME:CEST\r\n",
+		"BEGIN:STANDARD\r\nDTSTART:20251026T030000\r\nTZOFFSETFROM:+0200\r\nTZOFFSETTO:+0100\r\n",
+		"SEQUENCE:1\r\n",
+		"DTSTAMP:20250314T093000Z\r\n",
+		"DTSTART;TZID=Europe/Berlin:20250320T150000\r\n",
+		"DTEND;TZID=Europe/Berlin:20250320T153000\r\n",
+		`SUMMARY:Kickoff call\; Spring\, Trail` + "\r\n",
+		`DESCRIPTION:Agenda:\nBriefing\\timeline` + "\r\n",
+		`ORGANIZER;CN="Lopez, Maria":mailto:maria@acme.example` + "\r\n",
+		"ATTENDEE;CN=Jane Smith;ROLE=REQ-PARTICIPANT;PARTSTAT=NEEDS-ACTION;RSVP=TRUE\r\n :mailto:jane@example.com\r\n",
+		"STATUS:CONFIRMED\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("calendar does not contain %q\n%s", want, out)
+		}
+	}
+	if !strings.HasSuffix(out, "END:VEVENT\r\nEND:VCALENDAR\r\n") {
+		t.Error("calendar is not terminated")
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Errorf("line longer than %d octets: %q", maxLineOctets, line)
+		}
+	}
+}
+
+func TestCalendar_Cancel(t *testing.T) {
+	out := string(Calendar{ProductID: "-//Test//EN", Method: MethodCancel, Event: sampleEvent(t)}.Bytes())
+	for _, want := range []string{"METHOD:CANCEL\r\n", "STATUS:CANCELLED\r\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("calendar does not contain %q", want)
+		}
+	}
+	if strings.Contains(out, "RSVP") {
+		t.Error("cancellation asks attendees to reply")
+	}
+}
+
+func TestCalendar_UTC(t *testing.T) {
+	e := sampleEvent(t)
+	e.Start = e.Start.UTC()
+	out := string(Calendar{Method: MethodRequest, Event: e}.Bytes())
+	if strings.Contains(out, "VTIMEZONE") {
+		t.Error("UTC event has a VTIMEZONE")
+	}
+	for _, want := range []string{"DTSTART:20250320T140000Z\r\n", "DTEND:20250320T143000Z\r\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("calendar does not contain %q", want)
+		}
+	}
+}
+
+func TestCalendar_ZoneWithoutTransitions(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	e := sampleEvent(t)
+	e.Start = e.Start.In(tokyo)
+	out := string(Calendar{Method: MethodRequest, Event: e}.Bytes())
+	if strings.Count(out, "BEGIN:STANDARD") != 1 || strings.Contains(out, "DAYLIGHT") {
+		t.Errorf("want a single standard observance:\n%s", out)
+	}
+	if !strings.Contains(out, "DTSTART;TZID=Asia/Tokyo:20250320T230000\r\n") {
+		t.Errorf("start is not in Tokyo time:\n%s", out)
+	}
+}
+
+func TestLineFoldingKeepsUTF8(t *testing.T) {
+	w := &writer{}
+	w.line("SUMMARY:" + strings.Repeat("ü", 60))
+	for _, line := range strings.Split(strings.TrimSuffix(w.String(), "\r\n"), "\r\n") {
+		if len(line) > maxLineOctets {
+			t.Errorf("line longer than %d octets: %q", maxLineOctets, line)
+		}
+		if !strings.HasPrefix(line, "SUMMARY") && !strings.HasPrefix(line, " ü") {
+			t.Errorf("continuation line splits a character: %q", line)
+		}
+	}
+}
+
+func TestUTCOffset(t *testing.T) {
+	tests := []struct {
+		seconds int
+		want    string
+	}{
+		{0, "+0000"},
+		{3600, "+0100"},
+		{-18000, "-0500"},
+		{19800, "+0530"},
+		{-2670, "-004430"},
+	}
+	for _, tt := range tests {
+		if got := utcOffset(tt.seconds); got != tt.want {
+			t.Errorf("utcOffset(%d) = %q, want %q", tt.seconds, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package pdf
+
+// Font is one of the standard PDF fonts, which every reader has built in,
+// so nothing needs to be embedded
+type Font int
+
+// Standard fonts
+const (
+	Helvetica Font = iota
+	HelveticaBold
+)
+
+// baseFonts are the PostScript names of the fonts
+var baseFonts = map[Font]string{
+	Helvetica:     "Helvetica",
+	HelveticaBold: "Helvetica-Bold",
+}
+
+// resourceName is how content streams refer to a font
+func (f Font) resourceName() string {
+	if f == HelveticaBold {
+		return "F2"
+	}
+	return "F1"
+}
+
+// asciiWidths are the advance widths of characters 32 to 126 in thousandths
+// of the font size, from the Adobe font metrics
+var asciiWidths = map[Font][95]int{
+	Helvetica: {
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // space to /
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, // 0 to 9
+		278, 278, 584, 584, 584, 556, 1015, // : to @
+		667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, // A to M
+		722, 778, 667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, // N to Z
+		278, 278, 278, 469, 556, 333, // [ to `
+		556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, // a to m
+		556, 556, 556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, // n to z
+		334, 260, 334, 584, // { to ~
+	},
+	HelveticaBold: {
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556,
+		333, 333, 584, 584, 584, 611, 975,
+		722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833,
+		722, 778, 667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611,
+		333, 278, 333, 584, 556, 333,
+		556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889,
+		611, 611, 611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500,
+		389, 280, 389, 584,
+	},
+}
+
+// winAnsi maps the characters of Windows-1252 outside ASCII and Latin-1 to
+// their byte
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+	' ': 0xA0, // narrow no-break space has no glyph of its own
+}
+
+// highWidths are the widths of Windows-1252 bytes 0x80 to 0xFF in
+// Helvetica. Bold uses the same except where boldWidths differs.
+var highWidths = map[byte]int{
+	0x80: 556, 0x82: 222, 0x83: 556, 0x84: 333, 0x85: 1000, 0x86: 556, 0x87: 556,
+	0x88: 333, 0x89: 1000, 0x8A: 667, 0x8B: 333, 0x8C: 1000, 0x8E: 611,
+	0x91: 222, 0x92: 222, 0x93: 333, 0x94: 333, 0x95: 350, 0x96: 556, 0x97: 1000,
+	0x98: 333, 0x99: 1000, 0x9A: 500, 0x9B: 333, 0x9C: 944, 0x9E: 500, 0x9F: 667,
+	0xA0: 278, 0xA1: 333, 0xA2: 556, 0xA3: 556, 0xA4: 556, 0xA5: 556, 0xA6: 260, 0xA7: 556,
+	0xA8: 333, 0xA9: 737, 0xAA: 370, 0xAB: 556, 0xAC: 584, 0xAD: 333, 0xAE: 737, 0xAF: 333,
+	0xB0: 400, 0xB1: 584, 0xB2: 333, 0xB3: 333, 0xB4: 333, 0xB5: 556, 0xB6: 537, 0xB7: 278,
+	0xB8: 333, 0xB9: 333, 0xBA: 365, 0xBB: 556, 0xBC: 834, 0xBD: 834, 0xBE: 834, 0xBF: 611,
+	0xC0: 667, 0xC1: 667, 0xC2: 667, 0xC3: 667, 0xC4: 667, 0xC5: 667, 0xC6: 1000, 0xC7: 722,
+	0xC8: 667, 0xC9: 667, 0xCA: 667, 0xCB: 667, 0xCC: 278, 0xCD: 278, 0xCE: 278, 0xCF: 278,
+	0xD0: 722, 0xD1: 722, 0xD2: 778, 0xD3: 778, 0xD4: 778, 0xD5: 778, 0xD6: 778, 0xD7: 584,
+	0xD8: 778, 0xD9: 722, 0xDA: 722, 0xDB: 722, 0xDC: 722, 0xDD: 667, 0xDE: 667, 0xDF: 611,
+	0xE0: 556, 0xE1: 556, 0xE2: 556, 0xE3: 556, 0xE4: 556, 0xE5: 556, 0xE6: 889, 0xE7: 500,
+	0xE8: 556, 0xE9: 556, 0xEA: 556, 0xEB: 556, 0xEC: 278, 0xED: 278, 0xEE: 278, 0xEF: 278,
+	0xF0: 556, 0xF1: 556, 0xF2: 556, 0xF3: 556, 0xF4: 556, 0xF5: 556, 0xF6: 556, 0xF7: 584,
+	0xF8: 611, 0xF9: 556, 0xFA: 556, 0xFB: 556, 0xFC: 556, 0xFD: 500, 0xFE: 556, 0xFF: 500,
+}
+
+// boldWidths are the Helvetica-Bold widths that differ from highWidths
+var boldWidths = map[byte]int{
+	0x82: 278, 0x84: 500, 0x91: 278, 0x92: 278, 0x93: 500, 0x94: 500, 0x9A: 556, 0x9C: 944,
+	0xA1: 333, 0xA6: 280, 0xAA: 370, 0xB6: 556, 0xBA: 365, 0xE7: 556,
+	0xF0: 611, 0xF1: 611, 0xF2: 611, 0xF3: 611, 0xF4: 611, 0xF5: 611, 0xF6: 611,
+	0xF9: 611, 0xFA: 611, 0xFB: 611, 0xFC: 611, 0xFD: 556, 0xFE: 611, 0xFF: 556,
+	0xEC: 278, 0xED: 278, 0xEE: 278, 0xEF: 278, 0xC6: 1000, 0xE6: 889,
+}
+
+// encode converts s to Windows-1252, the encoding the fonts are declared
+// with. Characters it can't represent become "?".
+func encode(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r >= 0x20 && r <= 0x7E, r >= 0xA0 && r <= 0xFF:
+			out = append(out, byte(r))
+		case winAnsi[r] != 0:
+			out = append(out, winAnsi[r])
+		default:
+			out = append(out, '?')
+		}
+	}
+	return out
+}
+
+// charWidth returns the width of one encoded character in thousandths of
+// the font size
+func charWidth(font Font, c byte) int {
+	if c >= 32 && c <= 126 {
+		return asciiWidths[font][c-32]
+	}
+	if font == HelveticaBold {
+		if w, ok := boldWidths[c]; ok {
+			return w
+		}
+	}
+	if w, ok := highWidths[c]; ok {
+		return w
+	}
+	return 556
+}
+
+// TextWidth returns the width of s in points when set in font at size
+func TextWidth(font Font, size float64, s string) float64 {
+	total := 0
+	for _, c := range encode(s) {
+		total += charWidth(font, c)
+	}
+	return float64(total) * size / 1000
+}
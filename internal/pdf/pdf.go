@@ -0,0 +1,267 @@
+// Package pdf writes simple PDF documents: text in the standard fonts,
+// filled rectangles, lines and images. Output is deterministic, the same
+// calls always produce the same bytes, so documents can be golden-tested.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// A4 page size in points
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// Color is an RGB color
+type Color struct {
+	R, G, B uint8
+}
+
+// Black is the default text color
+var Black = Color{}
+
+// ParseHexColor parses "#RRGGBB" or "#RGB"
+func ParseHexColor(s string) (Color, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return Color{}, fmt.Errorf("invalid color %q", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return Color{}, fmt.Errorf("invalid color %q", s)
+	}
+	return Color{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v)}, nil
+}
+
+// Document is a PDF being built. Pages are A4 and positions are given in
+// points from the top-left corner.
+type Document struct {
+	title  string
+	pages  []*Page
+	images []*Image
+}
+
+// New creates an empty document
+func New() *Document {
+	return &Document{}
+}
+
+// SetTitle sets the title shown by PDF readers
+func (d *Document) SetTitle(title string) {
+	d.title = title
+}
+
+// AddPage appends a blank page
+func (d *Document) AddPage() *Page {
+	p := &Page{}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// Image is a picture that can be drawn on any page of its document
+type Image struct {
+	name          string
+	width, height int
+	pixels        []byte // RGB
+}
+
+// AddImage adds img to the document. Transparent pixels are flattened onto
+// white.
+func (d *Document) AddImage(img image.Image) *Image {
+	bounds := img.Bounds()
+	pixels := make([]byte, 0, bounds.Dx()*bounds.Dy()*3)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			// Colors are alpha-premultiplied, so adding the uncovered
+			// share of white composites the pixel over a white page
+			white := 0xffff - a
+			pixels = append(pixels, uint8((r+white)>>8), uint8((g+white)>>8), uint8((b+white)>>8))
+		}
+	}
+	im := &Image{
+		name:   "Im" + strconv.Itoa(len(d.images)+1),
+		width:  bounds.Dx(),
+		height: bounds.Dy(),
+		pixels: pixels,
+	}
+	d.images = append(d.images, im)
+	return im
+}
+
+// Page is one page of a document
+type Page struct {
+	content bytes.Buffer
+}
+
+// FillRect draws a filled rectangle with its top-left corner at x, y
+func (p *Page) FillRect(x, y, w, h float64, c Color) {
+	fmt.Fprintf(&p.content, "%s rg %s %s %s %s re f\n", rgb(c), num(x), num(PageHeight-y-h), num(w), num(h))
+}
+
+// Line draws a straight line
+func (p *Page) Line(x1, y1, x2, y2, width float64, c Color) {
+	fmt.Fprintf(&p.content, "%s RG %s w %s %s m %s %s l S\n", rgb(c), num(width), num(x1), num(PageHeight-y1), num(x2), num(PageHeight-y2))
+}
+
+// Text writes s with its baseline starting at x, y
+func (p *Page) Text(x, y float64, font Font, size float64, c Color, s string) {
+	fmt.Fprintf(&p.content, "BT %s rg /%s %s Tf %s %s Td %s Tj ET\n",
+		rgb(c), font.resourceName(), num(size), num(x), num(PageHeight-y), literal(encode(s)))
+}
+
+// TextRight writes s so that it ends at x
+func (p *Page) TextRight(x, y float64, font Font, size float64, c Color, s string) {
+	p.Text(x-TextWidth(font, size, s), y, font, size, c, s)
+}
+
+// DrawImage draws img scaled into the w by h box whose top-left corner is
+// at x, y
+func (p *Page) DrawImage(img *Image, x, y, w, h float64) {
+	fmt.Fprintf(&p.content, "q %s 0 0 %s %s %s cm /%s Do Q\n", num(w), num(h), num(x), num(PageHeight-y-h), img.name)
+}
+
+// Bytes renders the document
+func (d *Document) Bytes() []byte {
+	var buf bytes.Buffer
+	_, _ = d.WriteTo(&buf)
+	return buf.Bytes()
+}
+
+// WriteTo renders the document to w
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	var out bytes.Buffer
+	var offsets []int
+
+	// Objects are numbered in the order they're written: catalog, page
+	// tree, the two fonts, info, images, then each page and its content
+	begin := func() int {
+		offsets = append(offsets, out.Len())
+		n := len(offsets)
+		fmt.Fprintf(&out, "%d 0 obj\n", n)
+		return n
+	}
+	end := func() {
+		out.WriteString("endobj\n")
+	}
+
+	const (
+		catalogObj = 1
+		pagesObj   = 2
+		infoObj    = 5
+	)
+	firstImageObj := infoObj + 1
+	firstPageObj := firstImageObj + len(d.images)
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	begin()
+	fmt.Fprintf(&out, "<< /Type /Catalog /Pages %d 0 R >>\n", pagesObj)
+	end()
+
+	begin()
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPageObj+2*i)
+	}
+	fmt.Fprintf(&out, "<< /Type /Pages /Kids [%s] /Count %d >>\n", strings.Join(kids, " "), len(d.pages))
+	end()
+
+	for _, font := range []Font{Helvetica, HelveticaBold} {
+		begin()
+		fmt.Fprintf(&out, "<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>\n", baseFonts[font])
+		end()
+	}
+
+	begin()
+	fmt.Fprintf(&out, "<< /Title %s >>\n", literal(encode(d.title)))
+	end()
+
+	xobjects := make([]string, len(d.images))
+	for i, img := range d.images {
+		n := begin()
+		xobjects[i] = fmt.Sprintf("/%s %d 0 R", img.name, n)
+		fmt.Fprintf(&out, "<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Length %d >>\nstream\n",
+			img.width, img.height, len(img.pixels))
+		out.Write(img.pixels)
+		out.WriteString("\nendstream\n")
+		end()
+	}
+
+	resources := "<< /Font << /F1 3 0 R /F2 4 0 R >>"
+	if len(xobjects) > 0 {
+		resources += " /XObject << " + strings.Join(xobjects, " ") + " >>"
+	}
+	resources += " >>"
+
+	for _, page := range d.pages {
+		n := begin()
+		fmt.Fprintf(&out, "<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources %s /Contents %d 0 R >>\n",
+			pagesObj, num(PageWidth), num(PageHeight), resources, n+1)
+		end()
+
+		begin()
+		fmt.Fprintf(&out, "<< /Length %d >>\nstream\n", page.content.Len())
+		out.Write(page.content.Bytes())
+		out.WriteString("endstream\n")
+		end()
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(offsets)+1, catalogObj, infoObj, xref)
+
+	n, err := w.Write(out.Bytes())
+	return int64(n), err
+}
+
+// num formats a coordinate with at most two decimals
+func num(f float64) string {
+	s := strconv.FormatFloat(f, 'f', 2, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
+
+// rgb formats a color as the operands of the rg and RG operators
+func rgb(c Color) string {
+	channel := func(v uint8) string {
+		return strconv.FormatFloat(float64(v)/255, 'f', 3, 64)
+	}
+	return channel(c.R) + " " + channel(c.G) + " " + channel(c.B)
+}
+
+// literal writes encoded text as a PDF string, escaping delimiters and
+// anything that isn't printable ASCII
+func literal(b []byte) string {
+	var sb strings.Builder
+	sb.WriteByte('(')
+	for _, c := range b {
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case c < 0x20 || c > 0x7E:
+			fmt.Fprintf(&sb, "\\%03o", c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	sb.WriteByte(')')
+	return sb.String()
+}
@@ -0,0 +1,142 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func sampleDocument() *Document {
+	doc := New()
+	doc.SetTitle("Invoice (draft)")
+	logo := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	logo.Set(0, 0, color.NRGBA{R: 255, A: 255})
+	logo.Set(1, 0, color.NRGBA{R: 255, A: 0})
+	img := doc.AddImage(logo)
+
+	page := doc.AddPage()
+	page.FillRect(0, 0, PageWidth, 80, Color{R: 79, G: 70, B: 229})
+	page.DrawImage(img, 40, 20, 40, 20)
+	page.Text(40, 120, HelveticaBold, 18, Black, "Grüße – 100 €")
+	page.TextRight(555, 140, Helvetica, 10, Black, "Total")
+	page.Line(40, 150, 555, 150, 0.5, Color{R: 200, G: 200, B: 200})
+	return doc
+}
+
+func TestDocumentIsDeterministic(t *testing.T) {
+	a := sampleDocument().Bytes()
+	b := sampleDocument().Bytes()
+	if !bytes.Equal(a, b) {
+		t.Error("rendering the same document twice gave different bytes")
+	}
+}
+
+func TestDocumentStructure(t *testing.T) {
+	out := sampleDocument().Bytes()
+	s := string(out)
+
+	if !strings.HasPrefix(s, "%PDF-1.4\n") {
+		t.Errorf("missing header: %q", s[:10])
+	}
+	if !strings.HasSuffix(s, "%%EOF\n") {
+		t.Error("missing EOF marker")
+	}
+
+	// Every xref entry must point at the start of its object
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindStringSubmatch(s)
+	if m == nil {
+		t.Fatal("missing startxref")
+	}
+	xref, _ := strconv.Atoi(m[1])
+	if !strings.HasPrefix(s[xref:], "xref\n") {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(s[xref:], -1)
+	if len(entries) != 8 {
+		t.Fatalf("got %d objects, want 8", len(entries))
+	}
+	for i, e := range entries {
+		off, _ := strconv.Atoi(e[1])
+		want := fmt.Sprintf("%d 0 obj\n", i+1)
+		if !strings.HasPrefix(s[off:], want) {
+			t.Errorf("xref entry %d points at %q, want %q", i+1, s[off:off+len(want)], want)
+		}
+	}
+
+	for _, want := range []string{
+		"/BaseFont /Helvetica /Encoding /WinAnsiEncoding",
+		"/BaseFont /Helvetica-Bold",
+		`/Title (Invoice \(draft\))`,
+		`(Gr\374\337e \226 100 \200) Tj`,
+		"/Im1 Do",
+		"/Width 2 /Height 1",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("output does not contain %q", want)
+		}
+	}
+
+	// The transparent pixel is flattened onto white
+	if !bytes.Contains(out, []byte("stream\n\xff\x00\x00\xff\xff\xff\nendstream")) {
+		t.Error("image pixels are not flattened RGB")
+	}
+}
+
+func TestTextWidth(t *testing.T) {
+	tests := []struct {
+		font Font
+		size float64
+		text string
+		want float64
+	}{
+		{Helvetica, 10, "", 0},
+		{Helvetica, 10, "A", 6.67},
+		{Helvetica, 10, "1,000.00", 38.92},
+		{HelveticaBold, 10, "Total", 23.89},
+		{Helvetica, 10, "€", 5.56},
+		{Helvetica, 12, "Ä", 8.004},
+	}
+	for _, tt := range tests {
+		got := TextWidth(tt.font, tt.size, tt.text)
+		if got < tt.want-0.01 || got > tt.want+0.01 {
+			t.Errorf("TextWidth(%d, %v, %q) = %v, want %v", tt.font, tt.size, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	got := encode("Café • 5 × 3 – 漢")
+	want := []byte("Caf\xe9 \x95 5 \xd7 3 \x96 ?")
+	if !bytes.Equal(got, want) {
+		t.Errorf("encode = %q, want %q", got, want)
+	}
+}
+
+func TestParseHexColor(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Color
+		wantErr bool
+	}{
+		{in: "#4F46E5", want: Color{R: 0x4F, G: 0x46, B: 0xE5}},
+		{in: "#fff", want: Color{R: 255, G: 255, B: 255}},
+		{in: "4f46e5", want: Color{R: 0x4F, G: 0x46, B: 0xE5}},
+		{in: "#12345", wantErr: true},
+		{in: "#zzzzzz", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseHexColor(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseHexColor(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseHexColor(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
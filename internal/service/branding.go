@@ -0,0 +1,98 @@
+package service
+
+import "image"
+
+// Palette is the set of color tokens the email stylesheet is built from
+type Palette struct {
+	Background string // page behind the card
+	Surface    string // card
+	Footer     string // footer band
+	Border     string
+	Heading    string
+	Text       string
+	Muted      string // notes below the main content
+	Subtle     string // footer text
+	Link       string // footer links
+	CodeBox    string // background of the code box
+	OnAccent   string // text on accent colored backgrounds
+
+	// Accents color the header, code and buttons of each email
+	Primary string
+	Danger  string
+	Success string
+
+	// DangerSurface and DangerBorder frame the code box of danger emails
+	DangerSurface string
+	DangerBorder  string
+
+	// Notice colors are used by security tips and warnings
+	NoticeSurface string
+	NoticeBorder  string
+	NoticeText    string
+}
+
+// Theme pairs the palettes used in light and dark mode
+type Theme struct {
+	Light Palette
+	Dark  Palette
+}
+
+// Branding configures how emails look
+type Branding struct {
+	// Name is shown in the footer copyright line
+	Name  string
+	Theme Theme
+	// Logo is drawn in the header of PDF documents instead of the name
+	Logo image.Image
+}
+
+// DefaultBranding returns the Sponsoration brand
+func DefaultBranding() Branding {
+	return Branding{
+		Name: "Sponsoration",
+		Theme: Theme{
+			Light: Palette{
+				Background:    "#f4f4f4",
+				Surface:       "#ffffff",
+				Footer:        "#F9FAFB",
+				Border:        "#E5E7EB",
+				Heading:       "#1F2937",
+				Text:          "#4B5563",
+				Muted:         "#6B7280",
+				Subtle:        "#9CA3AF",
+				Link:          "#6B7280",
+				CodeBox:       "#F3F4F6",
+				OnAccent:      "#ffffff",
+				Primary:       "#4F46E5",
+				Danger:        "#DC2626",
+				Success:       "#10B981",
+				DangerSurface: "#FEF2F2",
+				DangerBorder:  "#FCA5A5",
+				NoticeSurface: "#FFFBEB",
+				NoticeBorder:  "#F59E0B",
+				NoticeText:    "#92400E",
+			},
+			Dark: Palette{
+				Background:    "#111827",
+				Surface:       "#1F2937",
+				Footer:        "#111827",
+				Border:        "#374151",
+				Heading:       "#F9FAFB",
+				Text:          "#D1D5DB",
+				Muted:         "#9CA3AF",
+				Subtle:        "#6B7280",
+				Link:          "#9CA3AF",
+				CodeBox:       "#374151",
+				OnAccent:      "#ffffff",
+				Primary:       "#818CF8",
+				Danger:        "#F87171",
+				Success:       "#34D399",
+				DangerSurface: "#450A0A",
+				DangerBorder:  "#B91C1C",
+				NoticeSurface: "#451A03",
+				NoticeBorder:  "#F59E0B",
+				NoticeText:    "#FDE68A",
+			},
+		},
+	}
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDarkModeMarkup(t *testing.T) {
+	for _, tmpl := range Templates() {
+		t.Run(tmpl.Name, func(t *testing.T) {
+			html := tmpl.Sample(testRenderContext()).HTML
+
+			expected := []string{
+				`<meta name="color-scheme" content="light dark">`,
+				`<meta name="supported-color-schemes" content="light dark">`,
+				"color-scheme: light dark;",
+				"@media (prefers-color-scheme: dark)",
+				"[data-ogsb] .token-box",
+			}
+			for _, part := range expected {
+				if !strings.Contains(html, part) {
+					t.Errorf("template missing dark mode markup %q", part)
+				}
+			}
+		})
+	}
+}
+
+func TestBrandingPalettes(t *testing.T) {
+	branding := DefaultBranding()
+	branding.Name = "Acme"
+	branding.Theme.Light.CodeBox = "#ABCDEF"
+	branding.Theme.Dark.CodeBox = "#012345"
+
+	service := NewEmailService(WithBranding(branding))
+	html := verificationEmail(service.RenderContext(), "ABC123", "", DefaultCodeTTL).HTML
+
+	tests := []struct {
+		name string
+		part string
+	}{
+		{"light token is inlined", `class="token-box" style="background-color: #ABCDEF;`},
+		{"dark token is in the media query", ".token-box { background-color: #012345 !important; }"},
+		{"brand name is in the footer", "Acme. All rights reserved."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(html, tt.part) {
+				t.Errorf("rendered email missing %q", tt.part)
+			}
+		})
+	}
+
+	if strings.Contains(html, DefaultBranding().Theme.Light.CodeBox) {
+		t.Error("default code box color should be replaced by the branding palette")
+	}
+}
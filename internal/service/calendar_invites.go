@@ -0,0 +1,129 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sponsoration/api/internal/ical"
+)
+
+// calendarProductID identifies Sponsoration as the author of invites
+const calendarProductID = "-//Sponsoration//Calendar//EN"
+
+// Participant is the organizer or an attendee of a meeting
+type Participant struct {
+	Name  string
+	Email string
+}
+
+// Meeting is a scheduled call, such as the kickoff of a sponsorship deal.
+// Start and End are shown and written in the time zone of Start.
+type Meeting struct {
+	// ID must stay the same across updates so calendars replace the event
+	// instead of adding another one
+	ID string
+	// Sequence must be increased every time the meeting is changed
+	Sequence    int
+	Title       string
+	Description string
+	Start       time.Time
+	End         time.Time
+	Location    string // e.g. "Google Meet"
+	URL         string // link to join the call
+	Organizer   Participant
+	Attendees   []Participant
+}
+
+// SendMeetingInvite sends an invitation with the meeting attached as an
+// iCalendar REQUEST, so mail clients offer to accept or decline it. Send it
+// again with a higher Sequence to update the meeting.
+func (s *EmailService) SendMeetingInvite(email string, m Meeting) error {
+	msg := meetingInviteEmail(s.RenderContext(), m)
+	msg.To = email
+	msg.Attachments = []Attachment{s.calendarAttachment(m, ical.MethodRequest)}
+	return s.SendEmail(msg)
+}
+
+// SendMeetingCancellation tells an attendee that the meeting is off and
+// removes it from their calendar
+func (s *EmailService) SendMeetingCancellation(email string, m Meeting) error {
+	msg := meetingCanceledEmail(s.RenderContext(), m)
+	msg.To = email
+	msg.Attachments = []Attachment{s.calendarAttachment(m, ical.MethodCancel)}
+	return s.SendEmail(msg)
+}
+
+// calendarAttachment writes the meeting as a text/calendar file. The method
+// parameter of the content type is what makes clients treat it as an
+// invitation rather than a plain file.
+func (s *EmailService) calendarAttachment(m Meeting, method ical.Method) Attachment {
+	attendees := make([]ical.Person, len(m.Attendees))
+	for i, a := range m.Attendees {
+		attendees[i] = ical.Person(a)
+	}
+	cal := ical.Calendar{
+		ProductID: calendarProductID,
+		Method:    method,
+		Event: ical.Event{
+			UID:         s.meetingUID(m.ID),
+			Sequence:    m.Sequence,
+			Stamp:       s.clock.Now(),
+			Start:       m.Start,
+			End:         m.End,
+			Summary:     m.Title,
+			Description: m.Description,
+			Location:    m.Location,
+			URL:         m.URL,
+			Organizer:   ical.Person(m.Organizer),
+			Attendees:   attendees,
+		},
+	}
+
+	filename := "invite.ics"
+	if method == ical.MethodCancel {
+		filename = "cancel.ics"
+	}
+	return Attachment{
+		Filename:    filename,
+		ContentType: fmt.Sprintf("text/calendar; charset=utf-8; method=%s", method),
+		Content:     cal.Bytes(),
+	}
+}
+
+// meetingUID makes a meeting ID globally unique by qualifying it with the
+// APP_URL host, as RFC 5545 recommends
+func (s *EmailService) meetingUID(id string) string {
+	return id + "@" + s.appHost()
+}
+
+// when formats the meeting time in its own time zone, e.g. "Thursday,
+// March 20, 2025 at 15:00 – 15:30 CET"
+func (m Meeting) when() string {
+	start := m.Start
+	end := m.End.In(start.Location())
+	if start.Year() == end.Year() && start.YearDay() == end.YearDay() {
+		return start.Format("Monday, January 2, 2006 at 15:04") + " – " + end.Format("15:04 MST")
+	}
+	return start.Format("Monday, January 2, 2006 at 15:04 MST") + " – " + end.Format("Monday, January 2, 2006 at 15:04 MST")
+}
+
+// details lists the meeting for the email body
+func (m Meeting) details() []detail {
+	details := []detail{{"When", m.when()}}
+	if m.Location != "" {
+		details = append(details, detail{"Where", m.Location})
+	}
+	details = append(details, detail{"Organizer", m.Organizer.Name})
+	if len(m.Attendees) > 0 {
+		names := make([]string, len(m.Attendees))
+		for i, a := range m.Attendees {
+			names[i] = a.Name
+			if names[i] == "" {
+				names[i] = a.Email
+			}
+		}
+		details = append(details, detail{"Attendees", strings.Join(names, ", ")})
+	}
+	return details
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSendMeetingInvite(t *testing.T) {
+	s, _, transport := newTestEmailService(t)
+
+	if err := s.SendMeetingInvite("jane@example.com", sampleMeeting()); err != nil {
+		t.Fatalf("SendMeetingInvite() error = %v", err)
+	}
+	msg := transport.last()
+	if msg.To != "jane@example.com" || msg.Subject != "Invitation: Kickoff: Spring Trail Collection Launch" {
+		t.Errorf("got %q to %s", msg.Subject, msg.To)
+	}
+	if len(msg.Attachments) != 1 {
+		t.Fatalf("got %d attachments, want 1", len(msg.Attachments))
+	}
+	a := msg.Attachments[0]
+	if a.Filename != "invite.ics" || a.ContentType != "text/calendar; charset=utf-8; method=REQUEST" {
+		t.Errorf("attachment = %s (%s)", a.Filename, a.ContentType)
+	}
+	assertGolden(t, "meeting_invite.ics", string(a.Content))
+}
+
+func TestSendMeetingInvite_Changes(t *testing.T) {
+	tests := []struct {
+		name        string
+		send        func(s *EmailService, to string, m Meeting) error
+		sequence    int
+		moveBy      time.Duration
+		wantSubject string
+		wantJoin    bool
+		wantType    string
+		wantICS     []string
+	}{
+		{
+			name:        "update",
+			send:        (*EmailService).SendMeetingInvite,
+			sequence:    2,
+			moveBy:      24 * time.Hour,
+			wantSubject: "Updated invitation: Kickoff: Spring Trail Collection Launch",
+			wantJoin:    true,
+			wantType:    "text/calendar; charset=utf-8; method=REQUEST",
+			wantICS:     []string{"METHOD:REQUEST\r\n", "SEQUENCE:2\r\n", "DTSTART;TZID=Europe/Berlin:20250321T150000\r\n"},
+		},
+		{
+			name:        "cancellation",
+			send:        (*EmailService).SendMeetingCancellation,
+			sequence:    1,
+			wantSubject: "Canceled: Kickoff: Spring Trail Collection Launch",
+			wantType:    "text/calendar; charset=utf-8; method=CANCEL",
+			wantICS:     []string{"METHOD:CANCEL\r\n", "STATUS:CANCELLED\r\n", "SEQUENCE:1\r\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _, transport := newTestEmailService(t)
+			m := sampleMeeting()
+			m.Sequence = tt.sequence
+			m.Start = m.Start.Add(tt.moveBy)
+			m.End = m.End.Add(tt.moveBy)
+			if err := tt.send(s, "jane@example.com", m); err != nil {
+				t.Fatalf("send error = %v", err)
+			}
+
+			msg := transport.last()
+			if msg.Subject != tt.wantSubject {
+				t.Errorf("Subject = %q, want %q", msg.Subject, tt.wantSubject)
+			}
+			if joins := strings.Contains(msg.HTML, "Join Call") || strings.Contains(msg.Text, "Join the call"); joins != tt.wantJoin {
+				t.Errorf("offers to join the call = %v, want %v", joins, tt.wantJoin)
+			}
+			a := msg.Attachments[0]
+			if a.ContentType != tt.wantType {
+				t.Errorf("ContentType = %q, want %q", a.ContentType, tt.wantType)
+			}
+			// The same UID, so calendars update the original event
+			ics := string(a.Content)
+			for _, want := range append(tt.wantICS, "UID:kickoff-deal-42@app.example.com\r\n") {
+				if !strings.Contains(ics, want) {
+					t.Errorf("invite does not contain %q", want)
+				}
+			}
+		})
+	}
+}
+
+func TestMeeting_When(t *testing.T) {
+	m := sampleMeeting()
+	if got, want := m.when(), "Thursday, March 20, 2025 at 15:00 – 15:30 CET"; got != want {
+		t.Errorf("when() = %q, want %q", got, want)
+	}
+
+	// End is shown in the zone of the start, across midnight
+	m.End = time.Date(2025, time.March, 20, 23, 30, 0, 0, time.UTC)
+	if got, want := m.when(), "Thursday, March 20, 2025 at 15:00 CET – Friday, March 21, 2025 at 00:30 CET"; got != want {
+		t.Errorf("when() = %q, want %q", got, want)
+	}
+}
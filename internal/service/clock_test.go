@@ -0,0 +1,49 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2025, time.December, 31, 23, 59, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+
+	if got := clock.Now(); !got.Equal(start) {
+		t.Errorf("Now() = %v, want %v", got, start)
+	}
+
+	clock.Advance(2 * time.Minute)
+	if got, want := clock.Now(), start.Add(2*time.Minute); !got.Equal(want) {
+		t.Errorf("Now() after Advance = %v, want %v", got, want)
+	}
+
+	later := time.Date(2030, time.June, 1, 0, 0, 0, 0, time.UTC)
+	clock.Set(later)
+	if got := clock.Now(); !got.Equal(later) {
+		t.Errorf("Now() after Set = %v, want %v", got, later)
+	}
+}
+
+func TestTemplatesUseServiceClock(t *testing.T) {
+	clock := NewFakeClock(time.Date(2025, time.December, 31, 23, 59, 59, 0, time.UTC))
+	transport := &recordingTransport{}
+	service := NewEmailService(WithClock(clock), WithTransport(transport))
+
+	if err := service.SendVerificationEmail("user@example.com", "ABC123"); err != nil {
+		t.Fatalf("SendVerificationEmail() error = %v", err)
+	}
+	if !strings.Contains(transport.last().HTML, "© 2025 Sponsoration") {
+		t.Error("template footer should use the injected clock's year")
+	}
+
+	// Crossing the year boundary must change the footer year
+	clock.Advance(time.Second)
+	if err := service.SendVerificationEmail("user@example.com", "ABC123"); err != nil {
+		t.Fatalf("SendVerificationEmail() error = %v", err)
+	}
+	if !strings.Contains(transport.last().HTML, "© 2026 Sponsoration") {
+		t.Error("template footer should follow the injected clock across the year boundary")
+	}
+}
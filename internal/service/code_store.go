@@ -0,0 +1,88 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCodeNotFound is returned when no code is stored for a key
+var ErrCodeNotFound = errors.New("verification code not found")
+
+// CodeRecord is a stored one-time code. Only a salted hash of the code is
+// kept.
+type CodeRecord struct {
+	Hash      []byte
+	Salt      []byte
+	ExpiresAt time.Time
+	Attempts  int
+	// TokenID and TokenExpiresAt identify the verification link token sent
+	// with the code, if any, so it can be revoked when the code is replaced
+	TokenID        string
+	TokenExpiresAt time.Time
+}
+
+// CodeStore persists one-time codes by key
+type CodeStore interface {
+	// Save stores rec under key, replacing any previous code
+	Save(key string, rec CodeRecord) error
+	// Get returns the record stored under key, or ErrCodeNotFound
+	Get(key string) (CodeRecord, error)
+	// IncrementAttempts atomically records a verification attempt and
+	// returns the record with the new count, or ErrCodeNotFound
+	IncrementAttempts(key string) (CodeRecord, error)
+	// Delete removes the code stored under key. Deleting a missing key is
+	// not an error.
+	Delete(key string) error
+}
+
+// MemoryCodeStore is an in-process CodeStore. Codes are lost on restart.
+type MemoryCodeStore struct {
+	mu      sync.Mutex
+	records map[string]CodeRecord
+}
+
+// NewMemoryCodeStore creates an empty in-memory code store
+func NewMemoryCodeStore() *MemoryCodeStore {
+	return &MemoryCodeStore{records: map[string]CodeRecord{}}
+}
+
+// Save stores rec under key
+func (m *MemoryCodeStore) Save(key string, rec CodeRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records[key] = rec
+	return nil
+}
+
+// Get returns the record stored under key
+func (m *MemoryCodeStore) Get(key string) (CodeRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec, ok := m.records[key]
+	if !ok {
+		return CodeRecord{}, ErrCodeNotFound
+	}
+	return rec, nil
+}
+
+// IncrementAttempts records a verification attempt for key
+func (m *MemoryCodeStore) IncrementAttempts(key string) (CodeRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec, ok := m.records[key]
+	if !ok {
+		return CodeRecord{}, ErrCodeNotFound
+	}
+	rec.Attempts++
+	m.records[key] = rec
+	return rec, nil
+}
+
+// Delete removes the code stored under key
+func (m *MemoryCodeStore) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.records, key)
+	return nil
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Deliverable is content a creator owes under a deal by a deadline
+type Deliverable struct {
+	ID        string
+	DealID    string
+	DealTitle string
+	// Title names the deliverable, e.g. "Instagram Reel"
+	Title        string
+	CreatorEmail string
+	CreatorName  string
+	Deadline     time.Time
+	// TimeZone is the IANA zone the deadline is shown in, e.g.
+	// "Europe/Berlin". Defaults to UTC.
+	TimeZone string
+}
+
+// ScheduledReminder is a pending reminder about a deliverable
+type ScheduledReminder struct {
+	Deliverable Deliverable
+	// Offset is how long before the deadline the reminder goes out
+	Offset time.Duration
+	SendAt time.Time
+}
+
+// ReminderStore persists scheduled reminders so they survive restarts
+type ReminderStore interface {
+	// Replace swaps the pending reminders of a deliverable for reminders. No
+	// reminders cancels them all.
+	Replace(deliverableID string, reminders []ScheduledReminder) error
+	// Due returns the reminders to send at or before now, ordered by SendAt
+	Due(now time.Time) ([]ScheduledReminder, error)
+	// Delete removes one reminder once it's sent or no longer needed. A
+	// reminder that was rescheduled to another time since is kept.
+	Delete(deliverableID string, offset time.Duration, sendAt time.Time) error
+}
+
+// DeadlineReminderConfig configures deadline reminders
+type DeadlineReminderConfig struct {
+	// Offsets are how long before the deadline to remind. Defaults to 72, 24
+	// and 1 hour.
+	Offsets []time.Duration
+	// Store defaults to an in-memory store
+	Store ReminderStore
+}
+
+// withDefaults fills in zero values
+func (c DeadlineReminderConfig) withDefaults() DeadlineReminderConfig {
+	if len(c.Offsets) == 0 {
+		c.Offsets = []time.Duration{72 * time.Hour, 24 * time.Hour, time.Hour}
+	}
+	if c.Store == nil {
+		c.Store = NewMemoryReminderStore()
+	}
+	return c
+}
+
+// DeadlineReminders emails creators before their deliverables are due.
+// SendDue is meant to be called periodically, e.g. every minute from a
+// background job.
+type DeadlineReminders struct {
+	email  *EmailService
+	config DeadlineReminderConfig
+}
+
+// NewDeadlineReminders creates a reminder engine that sends through email
+func NewDeadlineReminders(email *EmailService, config DeadlineReminderConfig) *DeadlineReminders {
+	return &DeadlineReminders{
+		email:  email,
+		config: config.withDefaults(),
+	}
+}
+
+// Schedule plans the reminders of a deliverable, one per offset. Calling it
+// again, e.g. when the deadline moves, replaces the previous schedule.
+// Offsets that have already passed are skipped.
+func (r *DeadlineReminders) Schedule(d Deliverable) error {
+	now := r.email.clock.Now()
+	var reminders []ScheduledReminder
+	for _, offset := range r.config.Offsets {
+		sendAt := d.Deadline.Add(-offset)
+		if sendAt.Before(now) {
+			continue
+		}
+		reminders = append(reminders, ScheduledReminder{Deliverable: d, Offset: offset, SendAt: sendAt})
+	}
+	if err := r.config.Store.Replace(d.ID, reminders); err != nil {
+		return fmt.Errorf("failed to schedule reminders: %w", err)
+	}
+	return nil
+}
+
+// Cancel drops the pending reminders of a deliverable, e.g. once it's
+// submitted
+func (r *DeadlineReminders) Cancel(deliverableID string) error {
+	if err := r.config.Store.Replace(deliverableID, nil); err != nil {
+		return fmt.Errorf("failed to cancel reminders: %w", err)
+	}
+	return nil
+}
+
+// SendDue sends the reminders that are due and returns how many emails were
+// sent. When several reminders of a deliverable are due at once, e.g. after
+// downtime, only the one closest to the deadline is sent, and nothing is
+// sent once the deadline has passed. A reminder that fails to send is
+// retried by the next SendDue.
+func (r *DeadlineReminders) SendDue() (int, error) {
+	now := r.email.clock.Now()
+	due, err := r.config.Store.Due(now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list due reminders: %w", err)
+	}
+
+	// The closest reminder of each deliverable, the others are stale
+	latest := map[string]ScheduledReminder{}
+	var stale []ScheduledReminder
+	var ids []string
+	for _, rem := range due {
+		id := rem.Deliverable.ID
+		prev, ok := latest[id]
+		switch {
+		case !ok:
+			ids = append(ids, id)
+			latest[id] = rem
+		case rem.Offset < prev.Offset:
+			stale = append(stale, prev)
+			latest[id] = rem
+		default:
+			stale = append(stale, rem)
+		}
+	}
+
+	var errs []error
+	for _, rem := range stale {
+		errs = append(errs, r.delete(rem))
+	}
+
+	sent := 0
+	for _, id := range ids {
+		rem := latest[id]
+		if now.Before(rem.Deliverable.Deadline) {
+			if err := r.send(rem); err != nil {
+				errs = append(errs, fmt.Errorf("reminder for %s: %w", id, err))
+				continue
+			}
+			sent++
+		}
+		errs = append(errs, r.delete(rem))
+	}
+	return sent, errors.Join(errs...)
+}
+
+// send emails one reminder
+func (r *DeadlineReminders) send(rem ScheduledReminder) error {
+	d := rem.Deliverable
+	if loc, err := time.LoadLocation(d.TimeZone); err == nil {
+		d.Deadline = d.Deadline.In(loc)
+	}
+	link := r.email.appURL + "/deals/" + url.PathEscape(d.DealID)
+
+	// After downtime the reminder goes out late, so say how long is really left
+	left := rem.Deliverable.Deadline.Sub(r.email.clock.Now())
+	msg := deadlineReminderEmail(r.email.RenderContext(), d, left, link)
+	msg.To = d.CreatorEmail
+	return r.email.SendEmail(msg)
+}
+
+// delete removes a handled reminder from the store, unless Schedule moved
+// it in the meantime
+func (r *DeadlineReminders) delete(rem ScheduledReminder) error {
+	if err := r.config.Store.Delete(rem.Deliverable.ID, rem.Offset, rem.SendAt); err != nil {
+		return fmt.Errorf("failed to delete reminder for %s: %w", rem.Deliverable.ID, err)
+	}
+	return nil
+}
+
+// MemoryReminderStore is an in-process ReminderStore. Reminders are lost on
+// restart.
+type MemoryReminderStore struct {
+	mu        sync.Mutex
+	reminders map[string][]ScheduledReminder // by deliverable ID
+}
+
+// NewMemoryReminderStore creates an empty in-memory reminder store
+func NewMemoryReminderStore() *MemoryReminderStore {
+	return &MemoryReminderStore{reminders: map[string][]ScheduledReminder{}}
+}
+
+// Replace swaps the reminders of a deliverable
+func (m *MemoryReminderStore) Replace(deliverableID string, reminders []ScheduledReminder) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(reminders) == 0 {
+		delete(m.reminders, deliverableID)
+		return nil
+	}
+	m.reminders[deliverableID] = append([]ScheduledReminder(nil), reminders...)
+	return nil
+}
+
+// Due returns the reminders due at or before now, ordered by SendAt
+func (m *MemoryReminderStore) Due(now time.Time) ([]ScheduledReminder, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var due []ScheduledReminder
+	for _, reminders := range m.reminders {
+		for _, rem := range reminders {
+			if !rem.SendAt.After(now) {
+				due = append(due, rem)
+			}
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].SendAt.Equal(due[j].SendAt) {
+			return due[i].SendAt.Before(due[j].SendAt)
+		}
+		return due[i].Deliverable.ID < due[j].Deliverable.ID
+	})
+	return due, nil
+}
+
+// Delete removes one reminder while it's still due at sendAt
+func (m *MemoryReminderStore) Delete(deliverableID string, offset time.Duration, sendAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var kept []ScheduledReminder
+	for _, rem := range m.reminders[deliverableID] {
+		if rem.Offset != offset || !rem.SendAt.Equal(sendAt) {
+			kept = append(kept, rem)
+		}
+	}
+	if len(kept) == 0 {
+		delete(m.reminders, deliverableID)
+	} else {
+		m.reminders[deliverableID] = kept
+	}
+	return nil
+}
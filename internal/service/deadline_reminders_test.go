@@ -0,0 +1,177 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func testDeliverable(deadline time.Time) Deliverable {
+	return Deliverable{
+		ID: "d1", DealID: "deal-1", DealTitle: "Spring Trail Collection Launch", Title: "Instagram Reel",
+		CreatorEmail: "jane@example.com", CreatorName: "Jane", Deadline: deadline,
+	}
+}
+
+// sendAll polls every quarter hour until until and returns the subjects sent
+func sendAll(t *testing.T, r *DeadlineReminders, clock *FakeClock, transport *recordingTransport, until time.Time) []string {
+	t.Helper()
+	for clock.Now().Before(until) {
+		clock.Advance(15 * time.Minute)
+		if _, err := r.SendDue(); err != nil {
+			t.Fatalf("SendDue() error = %v", err)
+		}
+	}
+	var subjects []string
+	for _, msg := range transport.messages() {
+		subjects = append(subjects, msg.Subject)
+	}
+	return subjects
+}
+
+func TestDeadlineReminders_Schedule(t *testing.T) {
+	tests := []struct {
+		name     string
+		deadline time.Duration
+		timeZone string
+		// change runs after the first Schedule
+		change       func(r *DeadlineReminders, d Deliverable) error
+		wantSubjects []string
+		// wantText is in every reminder sent
+		wantText string
+	}{
+		{
+			name:     "at each offset",
+			deadline: 5 * 24 * time.Hour,
+			wantSubjects: []string{
+				"Reminder: Instagram Reel is due in 3 days",
+				"Reminder: Instagram Reel is due in 24 hours",
+				"Reminder: Instagram Reel is due in 1 hour",
+			},
+			wantText: "Due: Wednesday, March 19, 2025 at 09:30 UTC",
+		},
+		{
+			// The deadline moves out by a week, so the 72 hour reminder is
+			// back on
+			name:     "rescheduled",
+			deadline: 48 * time.Hour,
+			change: func(r *DeadlineReminders, d Deliverable) error {
+				d.Deadline = d.Deadline.Add(7 * 24 * time.Hour)
+				return r.Schedule(d)
+			},
+			wantSubjects: []string{
+				"Reminder: Instagram Reel is due in 3 days",
+				"Reminder: Instagram Reel is due in 24 hours",
+				"Reminder: Instagram Reel is due in 1 hour",
+			},
+			wantText: "Due: Sunday, March 23, 2025 at 09:30 UTC",
+		},
+		{
+			name:     "canceled once submitted",
+			deadline: 48 * time.Hour,
+			change: func(r *DeadlineReminders, d Deliverable) error {
+				return r.Cancel(d.ID)
+			},
+		},
+		{
+			name:     "passed offsets skipped, in the creator's time zone",
+			deadline: 30 * time.Hour,
+			timeZone: "Europe/Berlin",
+			wantSubjects: []string{
+				"Reminder: Instagram Reel is due in 24 hours",
+				"Reminder: Instagram Reel is due in 1 hour",
+			},
+			wantText: "Due: Saturday, March 15, 2025 at 16:30 CET",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email, clock, transport := newTestEmailService(t)
+			store := NewMemoryReminderStore()
+			r := NewDeadlineReminders(email, DeadlineReminderConfig{Store: store})
+			d := testDeliverable(goldenTime.Add(tt.deadline))
+			d.TimeZone = tt.timeZone
+			if err := r.Schedule(d); err != nil {
+				t.Fatalf("Schedule() error = %v", err)
+			}
+			if tt.change != nil {
+				if err := tt.change(r, d); err != nil {
+					t.Fatalf("change error = %v", err)
+				}
+			}
+
+			until := goldenTime.Add(10 * 24 * time.Hour)
+			got := sendAll(t, r, clock, transport, until)
+			if strings.Join(got, "|") != strings.Join(tt.wantSubjects, "|") {
+				t.Errorf("sent %q, want %q", got, tt.wantSubjects)
+			}
+			for _, msg := range transport.messages() {
+				if !strings.Contains(msg.Text, tt.wantText) {
+					t.Errorf("reminder should contain %q:\n%s", tt.wantText, msg.Text)
+				}
+			}
+			if due, _ := store.Due(until); len(due) != 0 {
+				t.Errorf("store still holds %d reminders", len(due))
+			}
+		})
+	}
+}
+
+// dueHookReminderStore runs hook after listing due reminders, to interleave
+// a reschedule with SendDue
+type dueHookReminderStore struct {
+	ReminderStore
+	hook func()
+}
+
+func (s dueHookReminderStore) Due(now time.Time) ([]ScheduledReminder, error) {
+	due, err := s.ReminderStore.Due(now)
+	s.hook()
+	return due, err
+}
+
+func TestDeadlineReminders_RescheduleDuringSendDue(t *testing.T) {
+	email, clock, transport := newTestEmailService(t)
+	store := NewMemoryReminderStore()
+	r := NewDeadlineReminders(email, DeadlineReminderConfig{Store: store})
+	d := testDeliverable(goldenTime.Add(72 * time.Hour))
+	_ = r.Schedule(d)
+
+	// The deadline moves out by a day while the 72 hour reminder is sent
+	moved := d
+	moved.Deadline = d.Deadline.Add(24 * time.Hour)
+	r.config.Store = dueHookReminderStore{store, func() { _ = r.Schedule(moved) }}
+	if sent, err := r.SendDue(); sent != 1 || err != nil {
+		t.Fatalf("SendDue() = %d, %v, want 1", sent, err)
+	}
+	r.config.Store = store
+
+	// The rescheduled reminder is still sent at the new time
+	got := sendAll(t, r, clock, transport, moved.Deadline)
+	if len(got) != 4 || got[1] != "Reminder: Instagram Reel is due in 3 days" {
+		t.Errorf("sent %q, want the rescheduled 72 hour reminder", got)
+	}
+}
+
+func TestDeadlineReminders_Downtime(t *testing.T) {
+	email, clock, transport := newTestEmailService(t)
+	r := NewDeadlineReminders(email, DeadlineReminderConfig{})
+	deadline := goldenTime.Add(5 * 24 * time.Hour)
+	_ = r.Schedule(testDeliverable(deadline))
+
+	// Down through the 72 and 24 hour reminders: only the 24 hour one goes
+	// out, with the time that is actually left
+	clock.Set(deadline.Add(-12 * time.Hour))
+	if sent, err := r.SendDue(); sent != 1 || err != nil {
+		t.Fatalf("SendDue() = %d, %v, want 1", sent, err)
+	}
+	if got := transport.last().Subject; got != "Reminder: Instagram Reel is due in 12 hours" {
+		t.Errorf("Subject = %q", got)
+	}
+
+	// Down past the deadline: the 1 hour reminder is dropped
+	clock.Set(deadline.Add(time.Minute))
+	if sent, err := r.SendDue(); sent != 0 || err != nil {
+		t.Errorf("SendDue() after the deadline = %d, %v, want nothing sent", sent, err)
+	}
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"time"
+)
+
+// ErrUnknownDealEvent is returned for a deal notification whose event has no
+// email
+var ErrUnknownDealEvent = errors.New("unknown deal event")
+
+// DealEvent is a step in the life of a sponsorship deal
+type DealEvent string
+
+// Deal events
+const (
+	DealProposed         DealEvent = "deal_proposed"
+	DealCountered        DealEvent = "deal_countered"
+	DealAccepted         DealEvent = "deal_accepted"
+	DealDeclined         DealEvent = "deal_declined"
+	DeliverableSubmitted DealEvent = "deliverable_submitted"
+	DeliverableApproved  DealEvent = "deliverable_approved"
+	DealCompleted        DealEvent = "deal_completed"
+)
+
+// Deal is the state of a sponsorship deal shown in notifications
+type Deal struct {
+	ID          string
+	Title       string
+	BrandName   string
+	CreatorName string
+	Amount      Money
+	// Deadline of the deliverables. Optional.
+	Deadline time.Time
+}
+
+// DealNotification tells one party of a deal that something happened
+type DealNotification struct {
+	Event DealEvent
+	Deal  Deal
+	// ActorName is who caused the event, e.g. the brand that proposed
+	ActorName string
+	// PreviousAmount is the amount before a counter offer. Optional.
+	PreviousAmount *Money
+	// Deliverable names the submitted or approved deliverable. Optional.
+	Deliverable string
+	// Message is a note from the actor, e.g. why they declined. Optional.
+	Message string
+}
+
+// dealCopy is the wording of one deal event. %s in subject is the deal title
+// and %s in summary the actor's name.
+type dealCopy struct {
+	subject  string
+	headline string
+	summary  string
+	button   string
+	tone     string
+}
+
+// dealCopies holds the wording of every deal event
+var dealCopies = map[DealEvent]dealCopy{
+	DealProposed: {
+		subject:  "New sponsorship proposal: %s",
+		headline: "You Have a New Proposal",
+		summary:  "%s proposed a sponsorship deal.",
+		button:   "Review Proposal",
+		tone:     tonePrimary,
+	},
+	DealCountered: {
+		subject:  "Counter offer on %s",
+		headline: "You Received a Counter Offer",
+		summary:  "%s made a counter offer.",
+		button:   "Review Counter Offer",
+		tone:     tonePrimary,
+	},
+	DealAccepted: {
+		subject:  "Deal accepted: %s",
+		headline: "Your Deal Was Accepted",
+		summary:  "%s accepted the deal. Time to get started!",
+		button:   "View Deal",
+		tone:     toneSuccess,
+	},
+	DealDeclined: {
+		subject:  "Deal declined: %s",
+		headline: "Your Deal Was Declined",
+		summary:  "%s declined the deal.",
+		button:   "View Deal",
+		tone:     toneDanger,
+	},
+	DeliverableSubmitted: {
+		subject:  "Deliverable ready for review: %s",
+		headline: "A Deliverable Is Ready for Review",
+		summary:  "%s submitted a deliverable for your review.",
+		button:   "Review Deliverable",
+		tone:     tonePrimary,
+	},
+	DeliverableApproved: {
+		subject:  "Deliverable approved: %s",
+		headline: "Your Deliverable Was Approved",
+		summary:  "%s approved your deliverable.",
+		button:   "View Deal",
+		tone:     toneSuccess,
+	},
+	DealCompleted: {
+		subject:  "Deal completed: %s",
+		headline: "Deal Completed 🎉",
+		summary:  "The deal with %s is complete. Thanks for working together!",
+		button:   "View Deal",
+		tone:     toneSuccess,
+	},
+}
+
+// SendDealNotification tells email about a deal event, with a link to the
+// deal page
+func (s *EmailService) SendDealNotification(email string, n DealNotification) error {
+	msg, err := dealNotificationEmail(s.RenderContext(), n, s.dealLink(n.Deal.ID))
+	if err != nil {
+		return err
+	}
+	msg.To = email
+	return s.SendEmail(msg)
+}
+
+// dealLink is the APP_URL page of a deal
+func (s *EmailService) dealLink(dealID string) string {
+	return s.appURL + "/deals/" + url.PathEscape(dealID)
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSendDealNotification(t *testing.T) {
+	deal := Deal{
+		ID:          "deal/42",
+		Title:       "Spring Launch",
+		BrandName:   "Acme Outdoor",
+		CreatorName: "Jane Smith",
+		Amount:      Money{Amount: 500000, Currency: "USD"},
+		Deadline:    time.Date(2025, time.April, 30, 0, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		event       DealEvent
+		wantSubject string
+		wantTone    string
+	}{
+		{DealProposed, "New sponsorship proposal: Spring Launch", tonePrimary},
+		{DealCountered, "Counter offer on Spring Launch", tonePrimary},
+		{DealAccepted, "Deal accepted: Spring Launch", toneSuccess},
+		{DealDeclined, "Deal declined: Spring Launch", toneDanger},
+		{DeliverableSubmitted, "Deliverable ready for review: Spring Launch", tonePrimary},
+		{DeliverableApproved, "Deliverable approved: Spring Launch", toneSuccess},
+		{DealCompleted, "Deal completed: Spring Launch", toneSuccess},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.event), func(t *testing.T) {
+			s, _, transport := newTestEmailService(t)
+			if err := s.SendDealNotification("jane@example.com", DealNotification{Event: tt.event, Deal: deal, ActorName: "Acme Outdoor"}); err != nil {
+				t.Fatalf("SendDealNotification() error = %v", err)
+			}
+			msg := transport.last()
+
+			if msg.To != "jane@example.com" || msg.Subject != tt.wantSubject {
+				t.Errorf("sent %q to %q, want %q", msg.Subject, msg.To, tt.wantSubject)
+			}
+			if !strings.Contains(msg.HTML, `class="tone-`+tt.wantTone+`"`) {
+				t.Errorf("HTML should use the %s tone", tt.wantTone)
+			}
+			for _, want := range []string{
+				"https://app.example.com/deals/deal%2F42",
+				"Brand: Acme Outdoor",
+				"Creator: Jane Smith",
+				"Amount: $5,000.00",
+				"Deadline: April 30, 2025",
+			} {
+				if !strings.Contains(msg.Text, want) {
+					t.Errorf("text should contain %q, got %q", want, msg.Text)
+				}
+			}
+		})
+	}
+}
+
+func TestDealNotificationEmail_Optional(t *testing.T) {
+	msg, err := dealNotificationEmail(testRenderContext(), DealNotification{
+		Event:          DealCountered,
+		Deal:           Deal{Title: "Launch", Amount: Money{Amount: 600000, Currency: "USD"}},
+		ActorName:      "Jane <Smith>",
+		PreviousAmount: &Money{Amount: 500000, Currency: "USD"},
+		Message:        "Two Stories & a Reel",
+	}, "https://app.example.com/deals/1")
+	if err != nil {
+		t.Fatalf("dealNotificationEmail() error = %v", err)
+	}
+
+	if !strings.Contains(msg.Text, "Amount: $6,000.00 (was $5,000.00)") {
+		t.Errorf("text should show the previous amount, got %q", msg.Text)
+	}
+	if strings.Contains(msg.Text, "Deadline") {
+		t.Error("a deal without deadline should not show one")
+	}
+	if !strings.Contains(msg.HTML, "Jane &lt;Smith&gt; wrote:") || !strings.Contains(msg.HTML, "Two Stories &amp; a Reel") {
+		t.Error("HTML should show the escaped message")
+	}
+}
+
+func TestSendDealNotification_UnknownEvent(t *testing.T) {
+	s, _, transport := newTestEmailService(t)
+
+	err := s.SendDealNotification("jane@example.com", DealNotification{Event: "deal_archived", Deal: Deal{ID: "d1"}})
+	if !errors.Is(err, ErrUnknownDealEvent) {
+		t.Errorf("SendDealNotification() error = %v, want ErrUnknownDealEvent", err)
+	}
+	if len(transport.messages()) != 0 {
+		t.Error("no email should be sent for an unknown event")
+	}
+}
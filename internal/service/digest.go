@@ -0,0 +1,472 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Digest errors
+var (
+	ErrDigestSubscriberNotFound = errors.New("digest subscriber not found")
+	ErrInvalidCadence           = errors.New("invalid digest cadence")
+	// ErrInvalidSendHour is returned for a DigestConfig.SendHour outside
+	// 0-23, or set together with SendAtMidnight
+	ErrInvalidSendHour = errors.New("invalid digest send hour")
+)
+
+// DigestCadence is how often a user gets their digest
+type DigestCadence string
+
+// Digest cadences
+const (
+	// DigestOff sends every notification in real time and nothing is
+	// collected
+	DigestOff    DigestCadence = "off"
+	DigestDaily  DigestCadence = "daily"
+	DigestWeekly DigestCadence = "weekly"
+)
+
+// DigestKind groups digest events into sections
+type DigestKind string
+
+// Digest kinds, in the order their sections appear
+const (
+	DigestOffers   DigestKind = "offer"
+	DigestMessages DigestKind = "message"
+	DigestDeals    DigestKind = "deal"
+	DigestPayments DigestKind = "payment"
+)
+
+// digestSections are the section titles in display order. Events of other
+// kinds are listed last under "Other Updates".
+var digestSections = []struct {
+	kind  DigestKind
+	title string
+	noun  durationUnit
+}{
+	{DigestOffers, "New Offers", durationUnit{"new offer", "new offers"}},
+	{DigestMessages, "Messages", durationUnit{"message", "messages"}},
+	{DigestDeals, "Deal Updates", durationUnit{"deal update", "deal updates"}},
+	{DigestPayments, "Payments", durationUnit{"payment", "payments"}},
+}
+
+// DigestEvent is one notification waiting for the next digest
+type DigestEvent struct {
+	// ID identifies the event so a real-time send can be recorded later
+	ID         string
+	UserID     string
+	Kind       DigestKind
+	Title      string // e.g. "Acme Outdoor sent you an offer"
+	Summary    string // optional second line
+	Link       string // absolute URL of the offer, conversation, ...
+	OccurredAt time.Time
+	// SentRealtime is set for events the user was already emailed about.
+	// They are kept so the store has a full history but never repeated in
+	// a digest.
+	SentRealtime bool
+}
+
+// DigestSubscriber is a user's digest preference together with the address
+// and time zone to send it with
+type DigestSubscriber struct {
+	UserID   string
+	Email    string
+	Name     string
+	Cadence  DigestCadence
+	TimeZone string // IANA name, defaults to UTC
+	// SubscribedAt and LastSentAt determine when the next digest is due.
+	// They are maintained by DigestService.
+	SubscribedAt time.Time
+	LastSentAt   time.Time
+}
+
+// DigestStore persists subscribers and their pending events
+type DigestStore interface {
+	// SaveSubscriber creates or replaces a subscriber
+	SaveSubscriber(sub DigestSubscriber) error
+	// Subscriber returns a subscriber or ErrDigestSubscriberNotFound
+	Subscriber(userID string) (DigestSubscriber, error)
+	// Subscribers returns every subscriber
+	Subscribers() ([]DigestSubscriber, error)
+	// AddEvent stores an event
+	AddEvent(e DigestEvent) error
+	// MarkSentRealtime flags an event as already emailed. Unknown events
+	// are ignored.
+	MarkSentRealtime(userID, eventID string) error
+	// Events returns the user's events that occurred up to until, oldest
+	// first
+	Events(userID string, until time.Time) ([]DigestEvent, error)
+	// DeleteEvents removes the user's events that occurred up to until
+	DeleteEvents(userID string, until time.Time) error
+}
+
+// DigestConfig configures when digests are sent
+type DigestConfig struct {
+	// SendHour is the local hour (1-23) digests go out at. Defaults to 8.
+	SendHour int
+	// SendAtMidnight sends digests at hour 0, which SendHour can't express
+	// since zero means the default
+	SendAtMidnight bool
+	// WeeklyDay is the day weekly digests go out on. The zero value is
+	// Sunday.
+	WeeklyDay time.Weekday
+	// MaxPerSection caps the events listed per section, the rest are
+	// counted. Defaults to 5.
+	MaxPerSection int
+}
+
+// withDefaults fills in zero values
+func (c DigestConfig) withDefaults() DigestConfig {
+	if c.SendHour == 0 && !c.SendAtMidnight {
+		c.SendHour = 8
+	}
+	if c.MaxPerSection <= 0 {
+		c.MaxPerSection = 5
+	}
+	return c
+}
+
+// DigestService collects notification events per user and emails them as a
+// daily or weekly summary. SendDue is meant to be called periodically, e.g.
+// every few minutes from a background job.
+type DigestService struct {
+	email  *EmailService
+	store  DigestStore
+	config DigestConfig
+}
+
+// NewDigestService creates a digest service that keeps events in store and
+// sends through email. It fails with ErrInvalidSendHour if config.SendHour
+// isn't an hour of the day.
+func NewDigestService(email *EmailService, store DigestStore, config DigestConfig) (*DigestService, error) {
+	if config.SendHour < 0 || config.SendHour > 23 || (config.SendAtMidnight && config.SendHour != 0) {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidSendHour, config.SendHour)
+	}
+	return &DigestService{
+		email:  email,
+		store:  store,
+		config: config.withDefaults(),
+	}, nil
+}
+
+// Subscribe saves a user's digest preference. The first digest covers the
+// events recorded from now on.
+func (d *DigestService) Subscribe(sub DigestSubscriber) error {
+	if !validCadence(sub.Cadence) {
+		return ErrInvalidCadence
+	}
+	existing, err := d.store.Subscriber(sub.UserID)
+	switch {
+	case err == nil:
+		sub.SubscribedAt, sub.LastSentAt = existing.SubscribedAt, existing.LastSentAt
+	case errors.Is(err, ErrDigestSubscriberNotFound):
+		sub.SubscribedAt = d.email.clock.Now()
+	default:
+		return fmt.Errorf("failed to load digest subscriber: %w", err)
+	}
+	if err := d.store.SaveSubscriber(sub); err != nil {
+		return fmt.Errorf("failed to save digest subscriber: %w", err)
+	}
+	return nil
+}
+
+// SetCadence changes how often a subscriber gets their digest
+func (d *DigestService) SetCadence(userID string, cadence DigestCadence) error {
+	if !validCadence(cadence) {
+		return ErrInvalidCadence
+	}
+	sub, err := d.store.Subscriber(userID)
+	if err != nil {
+		return err
+	}
+	sub.Cadence = cadence
+	if err := d.store.SaveSubscriber(sub); err != nil {
+		return fmt.Errorf("failed to save digest subscriber: %w", err)
+	}
+	return nil
+}
+
+// Record collects an event for the user's next digest. It reports whether
+// the event was collected: users without a subscription or with digests
+// turned off should be notified in real time instead.
+func (d *DigestService) Record(e DigestEvent) (bool, error) {
+	sub, err := d.store.Subscriber(e.UserID)
+	if errors.Is(err, ErrDigestSubscriberNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to load digest subscriber: %w", err)
+	}
+	if sub.Cadence == DigestOff {
+		return false, nil
+	}
+	if e.OccurredAt.IsZero() {
+		e.OccurredAt = d.email.clock.Now()
+	}
+	if err := d.store.AddEvent(e); err != nil {
+		return false, fmt.Errorf("failed to store digest event: %w", err)
+	}
+	return true, nil
+}
+
+// MarkSentRealtime excludes an event from the digest because the user was
+// already emailed about it
+func (d *DigestService) MarkSentRealtime(userID, eventID string) error {
+	if err := d.store.MarkSentRealtime(userID, eventID); err != nil {
+		return fmt.Errorf("failed to mark digest event: %w", err)
+	}
+	return nil
+}
+
+// SendDue sends every digest that is due and returns how many were sent.
+// A due digest with nothing new is skipped and the next one covers the
+// following period. A failure for one subscriber doesn't stop the others.
+func (d *DigestService) SendDue() (int, error) {
+	subs, err := d.store.Subscribers()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list digest subscribers: %w", err)
+	}
+
+	now := d.email.clock.Now()
+	sent := 0
+	var errs []error
+	for _, sub := range subs {
+		if sub.Cadence == DigestOff || now.Before(d.NextDigest(sub)) {
+			continue
+		}
+		ok, err := d.send(sub, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("digest for %s: %w", sub.UserID, err))
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// send emails one subscriber's digest covering everything up to now and
+// clears it. It reports false when there was nothing to send.
+func (d *DigestService) send(sub DigestSubscriber, now time.Time) (bool, error) {
+	events, err := d.store.Events(sub.UserID, now)
+	if err != nil {
+		return false, fmt.Errorf("failed to load digest events: %w", err)
+	}
+	var pending []DigestEvent
+	for _, e := range events {
+		if !e.SentRealtime {
+			pending = append(pending, e)
+		}
+	}
+
+	if len(pending) > 0 {
+		msg := digestEmail(d.email.RenderContext(), digest{
+			Name:     sub.Name,
+			Cadence:  sub.Cadence,
+			Sections: groupDigestEvents(pending, d.config.MaxPerSection),
+			Total:    len(pending),
+			Settings: d.email.appURL + "/settings/notifications",
+		})
+		msg.To = sub.Email
+		if err := d.email.SendEmail(msg); err != nil {
+			return false, err
+		}
+	}
+
+	if err := d.store.DeleteEvents(sub.UserID, now); err != nil {
+		return false, fmt.Errorf("failed to clear digest events: %w", err)
+	}
+	sub.LastSentAt = now
+	if err := d.store.SaveSubscriber(sub); err != nil {
+		return false, fmt.Errorf("failed to save digest subscriber: %w", err)
+	}
+	return len(pending) > 0, nil
+}
+
+// NextDigest returns when the subscriber's next digest is due: the first
+// send hour after the previous digest (or the subscription) in their time
+// zone, on the weekly day for weekly digests
+func (d *DigestService) NextDigest(sub DigestSubscriber) time.Time {
+	loc := time.UTC
+	if sub.TimeZone != "" {
+		if l, err := time.LoadLocation(sub.TimeZone); err == nil {
+			loc = l
+		}
+	}
+	after := sub.LastSentAt
+	if after.IsZero() {
+		after = sub.SubscribedAt
+	}
+	after = after.In(loc)
+
+	// AddDate keeps the wall clock hour across daylight saving changes
+	next := time.Date(after.Year(), after.Month(), after.Day(), d.config.SendHour, 0, 0, 0, loc)
+	for !next.After(after) || (sub.Cadence == DigestWeekly && next.Weekday() != d.config.WeeklyDay) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// validCadence reports whether c is a known cadence
+func validCadence(c DigestCadence) bool {
+	return c == DigestOff || c == DigestDaily || c == DigestWeekly
+}
+
+// digest is the content of one digest email
+type digest struct {
+	Name     string
+	Cadence  DigestCadence
+	Sections []digestSection
+	Total    int
+	Settings string // link to change the cadence
+}
+
+// digestSection is a group of events of one kind
+type digestSection struct {
+	Title  string
+	Noun   durationUnit // counts the events in the summary line
+	Events []DigestEvent
+	// More counts the events left out to keep the email short
+	More int
+}
+
+// groupDigestEvents sorts events into sections, newest first, listing at
+// most max events per section
+func groupDigestEvents(events []DigestEvent, max int) []digestSection {
+	byKind := map[DigestKind][]DigestEvent{}
+	for _, e := range events {
+		byKind[e.Kind] = append(byKind[e.Kind], e)
+	}
+
+	var sections []digestSection
+	add := func(title string, noun durationUnit, events []DigestEvent) {
+		if len(events) == 0 {
+			return
+		}
+		sort.SliceStable(events, func(i, j int) bool { return events[i].OccurredAt.After(events[j].OccurredAt) })
+		section := digestSection{Title: title, Noun: noun, Events: events}
+		if len(events) > max {
+			section.Events, section.More = events[:max], len(events)-max
+		}
+		sections = append(sections, section)
+	}
+
+	var other []DigestEvent
+	known := map[DigestKind]bool{}
+	for _, s := range digestSections {
+		known[s.kind] = true
+	}
+	for _, e := range events {
+		if !known[e.Kind] {
+			other = append(other, e)
+		}
+	}
+	for _, s := range digestSections {
+		add(s.title, s.noun, byKind[s.kind])
+	}
+	add("Other Updates", durationUnit{"other update", "other updates"}, other)
+	return sections
+}
+
+// MemoryDigestStore is an in-process DigestStore. Everything is lost on
+// restart.
+type MemoryDigestStore struct {
+	mu          sync.Mutex
+	subscribers map[string]DigestSubscriber
+	events      map[string][]DigestEvent
+}
+
+// NewMemoryDigestStore creates an empty in-memory digest store
+func NewMemoryDigestStore() *MemoryDigestStore {
+	return &MemoryDigestStore{
+		subscribers: map[string]DigestSubscriber{},
+		events:      map[string][]DigestEvent{},
+	}
+}
+
+// SaveSubscriber creates or replaces a subscriber
+func (m *MemoryDigestStore) SaveSubscriber(sub DigestSubscriber) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subscribers[sub.UserID] = sub
+	return nil
+}
+
+// Subscriber returns a subscriber
+func (m *MemoryDigestStore) Subscriber(userID string) (DigestSubscriber, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sub, ok := m.subscribers[userID]
+	if !ok {
+		return DigestSubscriber{}, ErrDigestSubscriberNotFound
+	}
+	return sub, nil
+}
+
+// Subscribers returns every subscriber ordered by user ID
+func (m *MemoryDigestStore) Subscribers() ([]DigestSubscriber, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	subs := make([]DigestSubscriber, 0, len(m.subscribers))
+	for _, sub := range m.subscribers {
+		subs = append(subs, sub)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].UserID < subs[j].UserID })
+	return subs, nil
+}
+
+// AddEvent stores an event
+func (m *MemoryDigestStore) AddEvent(e DigestEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events[e.UserID] = append(m.events[e.UserID], e)
+	return nil
+}
+
+// MarkSentRealtime flags an event as already emailed
+func (m *MemoryDigestStore) MarkSentRealtime(userID, eventID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, e := range m.events[userID] {
+		if e.ID == eventID {
+			m.events[userID][i].SentRealtime = true
+		}
+	}
+	return nil
+}
+
+// Events returns the user's events up to until, oldest first
+func (m *MemoryDigestStore) Events(userID string, until time.Time) ([]DigestEvent, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var events []DigestEvent
+	for _, e := range m.events[userID] {
+		if !e.OccurredAt.After(until) {
+			events = append(events, e)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].OccurredAt.Before(events[j].OccurredAt) })
+	return events, nil
+}
+
+// DeleteEvents removes the user's events up to until
+func (m *MemoryDigestStore) DeleteEvents(userID string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var kept []DigestEvent
+	for _, e := range m.events[userID] {
+		if e.OccurredAt.After(until) {
+			kept = append(kept, e)
+		}
+	}
+	if len(kept) == 0 {
+		delete(m.events, userID)
+	} else {
+		m.events[userID] = kept
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDigestService_Daily(t *testing.T) {
+	email, clock, transport := newTestEmailService(t)
+	d, _ := NewDigestService(email, NewMemoryDigestStore(), DigestConfig{WeeklyDay: time.Monday})
+	if err := d.Subscribe(DigestSubscriber{UserID: "u1", Email: "jane@example.com", Name: "Jane", Cadence: DigestDaily}); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	events := []DigestEvent{
+		{ID: "e1", UserID: "u1", Kind: DigestOffers, Title: "Acme sent you an offer", Link: "https://app.example.com/deals/1"},
+		{ID: "e2", UserID: "u1", Kind: DigestMessages, Title: "Maria sent you a message", Link: "https://app.example.com/messages/1"},
+		{ID: "e3", UserID: "u1", Kind: DigestDeals, Title: "Deal accepted", Link: "https://app.example.com/deals/2"},
+	}
+	for _, e := range events {
+		clock.Advance(time.Minute)
+		if ok, err := d.Record(e); !ok || err != nil {
+			t.Fatalf("Record(%s) = %v, %v", e.ID, ok, err)
+		}
+	}
+	// e3 went out in real time, so it's left out of the digest
+	if err := d.MarkSentRealtime("u1", "e3"); err != nil {
+		t.Fatalf("MarkSentRealtime() error = %v", err)
+	}
+
+	if sent, err := d.SendDue(); sent != 0 || err != nil {
+		t.Fatalf("SendDue() before 8:00 = %d, %v, want nothing sent", sent, err)
+	}
+
+	clock.Set(time.Date(2025, time.March, 15, 8, 0, 0, 0, time.UTC))
+	if sent, err := d.SendDue(); sent != 1 || err != nil {
+		t.Fatalf("SendDue() = %d, %v, want 1", sent, err)
+	}
+	msg := transport.last()
+	if msg.To != "jane@example.com" || msg.Subject != "Your daily Sponsoration digest: 2 updates" {
+		t.Errorf("got %q to %s", msg.Subject, msg.To)
+	}
+	if !strings.Contains(msg.Text, "1 new offer and 1 message") {
+		t.Errorf("Text does not count the sections:\n%s", msg.Text)
+	}
+	if strings.Contains(msg.Text, "Deal accepted") {
+		t.Error("digest repeats an event sent in real time")
+	}
+	if strings.Index(msg.Text, "New Offers") > strings.Index(msg.Text, "Messages") {
+		t.Error("sections are out of order")
+	}
+
+	// Sent events are cleared and the next digest is a day later
+	clock.Advance(time.Hour)
+	if sent, err := d.SendDue(); sent != 0 || err != nil {
+		t.Errorf("SendDue() again = %d, %v, want nothing sent", sent, err)
+	}
+	clock.Set(time.Date(2025, time.March, 16, 8, 0, 0, 0, time.UTC))
+	if sent, _ := d.SendDue(); sent != 0 || len(transport.messages()) != 1 {
+		t.Errorf("empty digest was sent")
+	}
+}
+
+func TestDigestService_NextDigest(t *testing.T) {
+	email, _, _ := newTestEmailService(t)
+	d, _ := NewDigestService(email, NewMemoryDigestStore(), DigestConfig{WeeklyDay: time.Monday})
+	tests := []struct {
+		name string
+		sub  DigestSubscriber
+		want time.Time
+	}{
+		{
+			name: "daily after subscribing before the send hour",
+			sub:  DigestSubscriber{Cadence: DigestDaily, SubscribedAt: time.Date(2025, time.March, 14, 6, 0, 0, 0, time.UTC)},
+			want: time.Date(2025, time.March, 14, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "daily after the last digest",
+			sub:  DigestSubscriber{Cadence: DigestDaily, LastSentAt: time.Date(2025, time.March, 14, 8, 0, 0, 0, time.UTC)},
+			want: time.Date(2025, time.March, 15, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "weekly goes out on Monday",
+			sub:  DigestSubscriber{Cadence: DigestWeekly, SubscribedAt: goldenTime},
+			want: time.Date(2025, time.March, 17, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "local send hour in the subscriber's time zone",
+			sub:  DigestSubscriber{Cadence: DigestDaily, TimeZone: "America/New_York", SubscribedAt: goldenTime},
+			want: time.Date(2025, time.March, 14, 12, 0, 0, 0, time.UTC), // 8:00 EDT, 5:30 there now
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := d.NextDigest(tt.sub); !got.Equal(tt.want) {
+				t.Errorf("NextDigest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDigestService_SendHour(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  DigestConfig
+		want    time.Time
+		wantErr bool
+	}{
+		{"default", DigestConfig{}, time.Date(2025, time.March, 15, 8, 0, 0, 0, time.UTC), false},
+		{"evening", DigestConfig{SendHour: 18}, time.Date(2025, time.March, 14, 18, 0, 0, 0, time.UTC), false},
+		{"midnight", DigestConfig{SendAtMidnight: true}, time.Date(2025, time.March, 15, 0, 0, 0, 0, time.UTC), false},
+		{"hour 24", DigestConfig{SendHour: 24}, time.Time{}, true},
+		{"negative", DigestConfig{SendHour: -1}, time.Time{}, true},
+		{"midnight and an hour", DigestConfig{SendHour: 6, SendAtMidnight: true}, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := NewDigestService(NewEmailService(WithTransport(&recordingTransport{})), NewMemoryDigestStore(), tt.config)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSendHour) {
+					t.Errorf("NewDigestService() error = %v, want ErrInvalidSendHour", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewDigestService() error = %v", err)
+			}
+			sub := DigestSubscriber{Cadence: DigestDaily, SubscribedAt: goldenTime}
+			if got := d.NextDigest(sub); !got.Equal(tt.want) {
+				t.Errorf("NextDigest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDigestService_Cadence(t *testing.T) {
+	email, _, _ := newTestEmailService(t)
+	d, _ := NewDigestService(email, NewMemoryDigestStore(), DigestConfig{WeeklyDay: time.Monday})
+
+	if ok, err := d.Record(DigestEvent{UserID: "nobody"}); ok || err != nil {
+		t.Errorf("Record() for unknown user = %v, %v, want not collected", ok, err)
+	}
+	if err := d.Subscribe(DigestSubscriber{UserID: "u1", Cadence: "hourly"}); !errors.Is(err, ErrInvalidCadence) {
+		t.Errorf("Subscribe(hourly) error = %v, want ErrInvalidCadence", err)
+	}
+	if err := d.SetCadence("nobody", DigestDaily); !errors.Is(err, ErrDigestSubscriberNotFound) {
+		t.Errorf("SetCadence() error = %v, want ErrDigestSubscriberNotFound", err)
+	}
+
+	if err := d.Subscribe(DigestSubscriber{UserID: "u1", Email: "jane@example.com", Cadence: DigestDaily}); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+	if err := d.SetCadence("u1", DigestOff); err != nil {
+		t.Fatalf("SetCadence() error = %v", err)
+	}
+	if ok, err := d.Record(DigestEvent{UserID: "u1", Kind: DigestOffers}); ok || err != nil {
+		t.Errorf("Record() with digests off = %v, %v, want not collected", ok, err)
+	}
+}
+
+func TestGroupDigestEvents(t *testing.T) {
+	var events []DigestEvent
+	for i := 0; i < 7; i++ {
+		events = append(events, DigestEvent{Kind: DigestMessages, Title: fmt.Sprintf("m%d", i), OccurredAt: goldenTime.Add(time.Duration(i) * time.Minute)})
+	}
+	events = append(events, DigestEvent{Kind: "review", Title: "r"}, DigestEvent{Kind: DigestPayments, Title: "p"})
+
+	sections := groupDigestEvents(events, 5)
+	var titles []string
+	for _, s := range sections {
+		titles = append(titles, s.Title)
+	}
+	if got := strings.Join(titles, "|"); got != "Messages|Payments|Other Updates" {
+		t.Fatalf("sections = %s", got)
+	}
+	messages := sections[0]
+	if len(messages.Events) != 5 || messages.More != 2 || messages.Events[0].Title != "m6" {
+		t.Errorf("Messages = %d events, %d more, newest %q; want 5, 2, m6", len(messages.Events), messages.More, messages.Events[0].Title)
+	}
+	if got := digestSummary(sections); got != "7 messages, 1 payment and 1 other update" {
+		t.Errorf("digestSummary() = %q", got)
+	}
+}
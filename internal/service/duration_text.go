@@ -0,0 +1,64 @@
+package service
+
+import (
+	"fmt"
+	"time"
+)
+
+// durationUnit is the singular and plural name of a unit of time, or of
+// anything else that is counted
+type durationUnit struct {
+	one   string
+	other string
+}
+
+// formatDuration writes d the way a person would, e.g. "15 minutes",
+// "24 hours" or "1 hour and 30 minutes". Like the rest of the email
+// copy it is English. At most the two largest units are used and the
+// rest is rounded off.
+func formatDuration(d time.Duration) string {
+	units := []struct {
+		size time.Duration
+		name durationUnit
+	}{
+		{24 * time.Hour, durationUnit{"day", "days"}},
+		{time.Hour, durationUnit{"hour", "hours"}},
+		{time.Minute, durationUnit{"minute", "minutes"}},
+		{time.Second, durationUnit{"second", "seconds"}},
+	}
+
+	if d < time.Second {
+		d = time.Second
+	}
+	// "24 hours" reads better than "1 day", so days start at two
+	i := 1
+	if d >= 48*time.Hour {
+		i = 0
+	}
+	for i < len(units)-1 && d < units[i].size {
+		i++
+	}
+	if i == len(units)-1 {
+		return plural(int(d.Round(time.Second)/time.Second), units[i].name)
+	}
+
+	// The largest unit, then the remainder rounded to the next smaller one
+	big, small := units[i], units[i+1]
+	n := int(d / big.size)
+	m := int((d - time.Duration(n)*big.size).Round(small.size) / small.size)
+	if time.Duration(m)*small.size == big.size {
+		n, m = n+1, 0
+	}
+	if m == 0 {
+		return plural(n, big.name)
+	}
+	return plural(n, big.name) + " and " + plural(m, small.name)
+}
+
+// plural writes n with the matching form of unit
+func plural(n int, unit durationUnit) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, unit.one)
+	}
+	return fmt.Sprintf("%d %s", n, unit.other)
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{15 * time.Minute, "15 minutes"},
+		{time.Minute, "1 minute"},
+		{2 * time.Hour, "2 hours"},
+		{24 * time.Hour, "24 hours"},
+		{48 * time.Hour, "2 days"},
+		{78 * time.Hour, "3 days and 6 hours"},
+		{90 * time.Minute, "1 hour and 30 minutes"},
+		{26 * time.Hour, "26 hours"},
+		{24*time.Hour + 10*time.Second, "24 hours"},
+		{24*time.Hour + 10*time.Minute, "24 hours and 10 minutes"},
+		{time.Hour + 59*time.Minute + 45*time.Second, "2 hours"},
+		{45 * time.Second, "45 seconds"},
+		{0, "1 second"},
+	}
+
+	for _, tt := range tests {
+		if got := formatDuration(tt.d); got != tt.want {
+			t.Errorf("formatDuration(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrEmailChangeInvalid is returned for a token whose content doesn't
+// describe an email change
+var ErrEmailChangeInvalid = errors.New("email change token invalid")
+
+// EmailChangeConfig configures the email change flow
+type EmailChangeConfig struct {
+	// ConfirmTTL is how long the new address has to confirm. Defaults to 24
+	// hours.
+	ConfirmTTL time.Duration
+	// RevertTTL is how long the old address can undo the change. Defaults
+	// to 7 days.
+	RevertTTL time.Duration
+}
+
+// withDefaults fills in zero values
+func (c EmailChangeConfig) withDefaults() EmailChangeConfig {
+	if c.ConfirmTTL <= 0 {
+		c.ConfirmTTL = 24 * time.Hour
+	}
+	if c.RevertTTL <= 0 {
+		c.RevertTTL = 7 * 24 * time.Hour
+	}
+	return c
+}
+
+// EmailChange is a requested change of a user's email address
+type EmailChange struct {
+	UserID     string
+	OldAddress string
+	NewAddress string
+}
+
+// emailChangeSubject is the token subject of both links of a change. The
+// revert link also names the confirmation token so reverting can cancel a
+// change that wasn't confirmed yet.
+type emailChangeSubject struct {
+	UserID     string `json:"uid"`
+	OldAddress string `json:"old"`
+	NewAddress string `json:"new"`
+	ConfirmID  string `json:"cid,omitempty"`
+	ConfirmExp int64  `json:"cexp,omitempty"`
+}
+
+// EmailChangeService confirms a new email address with its owner and alerts
+// the old address with a link to undo the change, so an account takeover
+// that swaps the email can be caught
+type EmailChangeService struct {
+	email  *EmailService
+	tokens *TokenService
+	config EmailChangeConfig
+}
+
+// NewEmailChangeService creates an email change service that signs links
+// with tokens and sends them through email
+func NewEmailChangeService(email *EmailService, tokens *TokenService, config EmailChangeConfig) *EmailChangeService {
+	return &EmailChangeService{
+		email:  email,
+		tokens: tokens,
+		config: config.withDefaults(),
+	}
+}
+
+// Request starts changing the address of userID from oldAddress to
+// newAddress. The new address gets a confirmation link and the old one a
+// security notice with a revert link. The address only changes once the
+// caller handles Confirm.
+func (e *EmailChangeService) Request(userID, oldAddress, newAddress string) error {
+	subject := emailChangeSubject{
+		UserID:     userID,
+		OldAddress: normalizeAddress(oldAddress),
+		NewAddress: normalizeAddress(newAddress),
+	}
+
+	confirmToken, err := e.issue(PurposeEmailChange, subject, e.config.ConfirmTTL)
+	if err != nil {
+		return err
+	}
+	confirm, err := e.tokens.Parse(confirmToken, PurposeEmailChange)
+	if err != nil {
+		return fmt.Errorf("failed to issue email change token: %w", err)
+	}
+
+	subject.ConfirmID = confirm.ID
+	subject.ConfirmExp = confirm.ExpiresAt.Unix()
+	revertToken, err := e.issue(PurposeEmailChangeRevert, subject, e.config.RevertTTL)
+	if err != nil {
+		return err
+	}
+
+	if err := e.email.SendEmailChangeConfirmation(newAddress, confirmToken, e.config.ConfirmTTL); err != nil {
+		return err
+	}
+	return e.email.SendEmailChangeNotice(oldAddress, newAddress, revertToken, e.config.RevertTTL)
+}
+
+// Confirm consumes a confirmation link token and returns the change to
+// apply. Errors are the token errors of TokenService; a change that was
+// reverted first fails with ErrTokenUsed.
+func (e *EmailChangeService) Confirm(token string) (EmailChange, error) {
+	claims, err := e.tokens.Consume(token, PurposeEmailChange)
+	if err != nil {
+		return EmailChange{}, err
+	}
+	subject, err := decodeEmailChange(claims.Subject)
+	if err != nil {
+		return EmailChange{}, err
+	}
+	return subject.change(), nil
+}
+
+// Revert consumes a revert link token and returns the change to undo. The
+// caller should restore OldAddress and end the user's sessions. A change
+// that wasn't confirmed yet can no longer be.
+func (e *EmailChangeService) Revert(token string) (EmailChange, error) {
+	claims, err := e.tokens.Consume(token, PurposeEmailChangeRevert)
+	if err != nil {
+		return EmailChange{}, err
+	}
+	subject, err := decodeEmailChange(claims.Subject)
+	if err != nil {
+		return EmailChange{}, err
+	}
+	if err := e.tokens.Revoke(subject.ConfirmID, time.Unix(subject.ConfirmExp, 0)); err != nil {
+		return EmailChange{}, err
+	}
+	return subject.change(), nil
+}
+
+// issue signs a token carrying subject
+func (e *EmailChangeService) issue(purpose TokenPurpose, subject emailChangeSubject, ttl time.Duration) (string, error) {
+	raw, err := json.Marshal(subject)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode email change: %w", err)
+	}
+	token, err := e.tokens.Issue(purpose, string(raw), ttl)
+	if err != nil {
+		return "", fmt.Errorf("failed to issue email change token: %w", err)
+	}
+	return token, nil
+}
+
+// decodeEmailChange parses a token subject written by issue
+func decodeEmailChange(raw string) (emailChangeSubject, error) {
+	var subject emailChangeSubject
+	if err := json.Unmarshal([]byte(raw), &subject); err != nil || subject.UserID == "" {
+		return emailChangeSubject{}, ErrEmailChangeInvalid
+	}
+	return subject, nil
+}
+
+// change returns the public form of s
+func (s emailChangeSubject) change() EmailChange {
+	return EmailChange{UserID: s.UserID, OldAddress: s.OldAddress, NewAddress: s.NewAddress}
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// requestEmailChange starts a change and returns the confirm and revert tokens
+func requestEmailChange(t *testing.T, e *EmailChangeService, transport *recordingTransport) (confirm, revert string) {
+	t.Helper()
+	if err := e.Request("user-1", "old@example.com", "New@Example.com"); err != nil {
+		t.Fatalf("Request() error = %v", err)
+	}
+	sent := transport.messages()
+	if len(sent) != 2 {
+		t.Fatalf("sent %d emails, want 2", len(sent))
+	}
+	if sent[0].To != "New@Example.com" || sent[1].To != "old@example.com" {
+		t.Fatalf("sent to %q and %q, want the new then the old address", sent[0].To, sent[1].To)
+	}
+	return sentLinkToken(t, sent[0], "/confirm-email-change"), sentLinkToken(t, sent[1], "/revert-email-change")
+}
+
+func TestEmailChangeService_Confirm(t *testing.T) {
+	email, clock, transport := newTestEmailService(t)
+	e := NewEmailChangeService(email, newTestTokens(clock), EmailChangeConfig{})
+	confirm, _ := requestEmailChange(t, e, transport)
+
+	notice := transport.messages()[1]
+	if !strings.Contains(notice.HTML, "New@Example.com") || !strings.Contains(notice.HTML, "Undo Email Change") {
+		t.Error("notice should name the new address and offer the revert link")
+	}
+
+	change, err := e.Confirm(confirm)
+	if err != nil {
+		t.Fatalf("Confirm() error = %v", err)
+	}
+	want := EmailChange{UserID: "user-1", OldAddress: "old@example.com", NewAddress: "new@example.com"}
+	if change != want {
+		t.Errorf("Confirm() = %+v, want %+v", change, want)
+	}
+	if _, err := e.Confirm(confirm); !errors.Is(err, ErrTokenUsed) {
+		t.Errorf("second Confirm() error = %v, want ErrTokenUsed", err)
+	}
+}
+
+func TestEmailChangeService_Revert(t *testing.T) {
+	tests := []struct {
+		name           string
+		confirmFirst   bool
+		advance        time.Duration
+		wantRevertErr  error
+		wantConfirmErr error
+	}{
+		{name: "before confirmation cancels the change", wantConfirmErr: ErrTokenUsed},
+		{name: "after confirmation", confirmFirst: true},
+		{name: "after the confirm link expired", advance: 3 * 24 * time.Hour, wantConfirmErr: ErrTokenExpired},
+		{name: "too late", advance: 8 * 24 * time.Hour, wantRevertErr: ErrTokenExpired, wantConfirmErr: ErrTokenExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email, clock, transport := newTestEmailService(t)
+			e := NewEmailChangeService(email, newTestTokens(clock), EmailChangeConfig{})
+			confirm, revert := requestEmailChange(t, e, transport)
+
+			if tt.confirmFirst {
+				if _, err := e.Confirm(confirm); err != nil {
+					t.Fatalf("Confirm() error = %v", err)
+				}
+			}
+			clock.Advance(tt.advance)
+
+			change, err := e.Revert(revert)
+			if !errors.Is(err, tt.wantRevertErr) {
+				t.Fatalf("Revert() error = %v, want %v", err, tt.wantRevertErr)
+			}
+			if err == nil && change.OldAddress != "old@example.com" {
+				t.Errorf("Revert() = %+v, want the old address back", change)
+			}
+
+			if !tt.confirmFirst {
+				if _, err := e.Confirm(confirm); !errors.Is(err, tt.wantConfirmErr) {
+					t.Errorf("Confirm() after revert error = %v, want %v", err, tt.wantConfirmErr)
+				}
+			}
+		})
+	}
+}
+
+func TestEmailChangeService_TokensArePurposeBound(t *testing.T) {
+	email, clock, transport := newTestEmailService(t)
+	e := NewEmailChangeService(email, newTestTokens(clock), EmailChangeConfig{})
+	confirm, revert := requestEmailChange(t, e, transport)
+
+	if _, err := e.Confirm(revert); !errors.Is(err, ErrTokenPurpose) {
+		t.Errorf("Confirm(revert token) error = %v, want ErrTokenPurpose", err)
+	}
+	if _, err := e.Revert(confirm); !errors.Is(err, ErrTokenPurpose) {
+		t.Errorf("Revert(confirm token) error = %v, want ErrTokenPurpose", err)
+	}
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/sponsoration/api/internal/cssinline"
+)
+
+// RenderContext carries the service-wide settings every template is
+// rendered with
+type RenderContext struct {
+	Now      time.Time
+	Branding Branding
+	// Locale selects how amounts are written
+	Locale string
+	// OTPAutofill selects the templates that use the autofill code format
+	OTPAutofill OTPAutofill
+}
+
+// Tones select the accent color of an email
+const (
+	tonePrimary = "primary"
+	toneDanger  = "danger"
+	toneSuccess = "success"
+)
+
+// emailStylesheet is shared by every template and filled in from the light
+// palette. Rules are inlined into style attributes at render time; the media
+// queries stay in the head for clients that support them.
+var emailStylesheet = template.Must(template.New("stylesheet").Parse(`
+    :root { color-scheme: light dark; supported-color-schemes: light dark; }
+    body { margin: 0; padding: 0; font-family: Arial, sans-serif; background-color: {{.Background}}; }
+    .wrapper { background-color: {{.Background}}; padding: 20px; }
+    .container { background-color: {{.Surface}}; border-radius: 8px; overflow: hidden; }
+    .header { padding: 30px 40px; text-align: center; }
+    .header h1 { margin: 0; color: {{.OnAccent}}; font-size: 28px; }
+    .content { padding: 40px; }
+    h2 { margin: 0 0 20px 0; color: {{.Heading}}; font-size: 24px; }
+    p { margin: 0 0 20px 0; color: {{.Text}}; font-size: 16px; line-height: 1.5; }
+    p.note { margin: 10px 0 0 0; color: {{.Muted}}; font-size: 14px; }
+    .token-box { background-color: {{.CodeBox}}; border-radius: 8px; padding: 30px; text-align: center; margin: 30px 0; }
+    .token { font-size: 32px; font-weight: bold; letter-spacing: 8px; font-family: 'Courier New', monospace; }
+    .actions { text-align: center; margin: 30px 0; }
+    .button { display: inline-block; color: {{.OnAccent}}; text-decoration: none; padding: 15px 30px; border-radius: 6px; font-weight: bold; font-size: 16px; }
+    .items { width: 100%; border-collapse: collapse; margin: 0 0 20px 0; }
+    .items th { text-align: left; padding: 8px 0; border-bottom: 2px solid {{.Border}}; color: {{.Muted}}; font-size: 13px; }
+    .items td { padding: 8px 0; border-bottom: 1px solid {{.Border}}; color: {{.Text}}; font-size: 14px; }
+    .items .amount { text-align: right; white-space: nowrap; }
+    .items tr.total td { border-bottom: none; color: {{.Heading}}; font-size: 16px; font-weight: bold; }
+    .notice { background-color: {{.NoticeSurface}}; border-left: 4px solid {{.NoticeBorder}}; padding: 15px; margin-top: 30px; }
+    .notice p { margin: 0; color: {{.NoticeText}}; font-size: 13px; }
+    .footer { background-color: {{.Footer}}; padding: 30px 40px; text-align: center; border-top: 1px solid {{.Border}}; }
+    .footer p { margin: 0; color: {{.Subtle}}; font-size: 12px; }
+    .footer p.links { margin-top: 10px; }
+    .footer a { color: {{.Link}}; text-decoration: none; }
+    .tone-primary .header, .tone-primary .button { background-color: {{.Primary}}; }
+    .tone-primary .token { color: {{.Primary}}; }
+    .tone-danger .header, .tone-danger .button { background-color: {{.Danger}}; }
+    .tone-danger .token { color: {{.Danger}}; }
+    .tone-danger .token-box { background-color: {{.DangerSurface}}; border: 2px solid {{.DangerBorder}}; }
+    .tone-success .header, .tone-success .button { background-color: {{.Success}}; }
+    .tone-success .token { color: {{.Success}}; }
+    @media only screen and (max-width: 620px) {
+      .container { width: 100% !important; }
+      .header, .footer { padding: 24px !important; }
+      .content { padding: 24px !important; }
+      .token { font-size: 26px !important; letter-spacing: 4px !important; }
+    }`))
+
+// darkRule overrides one property with a dark palette token
+type darkRule struct {
+	selector string
+	property string
+	token    func(Palette) string
+}
+
+// darkRules are applied when the client prefers a dark color scheme
+var darkRules = []darkRule{
+	{"body", "background-color", func(p Palette) string { return p.Background }},
+	{".wrapper", "background-color", func(p Palette) string { return p.Background }},
+	{".container", "background-color", func(p Palette) string { return p.Surface }},
+	{"h2", "color", func(p Palette) string { return p.Heading }},
+	{"p", "color", func(p Palette) string { return p.Text }},
+	{"p.note", "color", func(p Palette) string { return p.Muted }},
+	{".token-box", "background-color", func(p Palette) string { return p.CodeBox }},
+	{".items th", "color", func(p Palette) string { return p.Muted }},
+	{".items th", "border-bottom-color", func(p Palette) string { return p.Border }},
+	{".items td", "color", func(p Palette) string { return p.Text }},
+	{".items td", "border-bottom-color", func(p Palette) string { return p.Border }},
+	{".items tr.total td", "color", func(p Palette) string { return p.Heading }},
+	{".notice", "background-color", func(p Palette) string { return p.NoticeSurface }},
+	{".notice p", "color", func(p Palette) string { return p.NoticeText }},
+	{".footer", "background-color", func(p Palette) string { return p.Footer }},
+	{".footer", "border-top-color", func(p Palette) string { return p.Border }},
+	{".footer p", "color", func(p Palette) string { return p.Subtle }},
+	{".footer a", "color", func(p Palette) string { return p.Link }},
+	{".tone-primary .header", "background-color", func(p Palette) string { return p.Primary }},
+	{".tone-primary .button", "background-color", func(p Palette) string { return p.Primary }},
+	{".tone-primary .token", "color", func(p Palette) string { return p.Primary }},
+	{".tone-danger .header", "background-color", func(p Palette) string { return p.Danger }},
+	{".tone-danger .button", "background-color", func(p Palette) string { return p.Danger }},
+	{".tone-danger .token", "color", func(p Palette) string { return p.Danger }},
+	{".tone-danger .token-box", "background-color", func(p Palette) string { return p.DangerSurface }},
+	{".tone-danger .token-box", "border-color", func(p Palette) string { return p.DangerBorder }},
+	{".tone-success .header", "background-color", func(p Palette) string { return p.Success }},
+	{".tone-success .button", "background-color", func(p Palette) string { return p.Success }},
+	{".tone-success .token", "color", func(p Palette) string { return p.Success }},
+}
+
+// darkStylesheet renders the dark palette as a prefers-color-scheme media
+// query, plus the [data-ogsc]/[data-ogsb] selectors Outlook.com adds in
+// its own dark mode. The Outlook rules sit in a screen media query so the
+// inliner keeps them in the head.
+func darkStylesheet(p Palette) string {
+	var standard, outlook strings.Builder
+	for _, r := range darkRules {
+		fmt.Fprintf(&standard, "\n      %s { %s: %s !important; }", r.selector, r.property, r.token(p))
+
+		prefix := "[data-ogsc]"
+		if strings.HasPrefix(r.property, "background") {
+			prefix = "[data-ogsb]"
+		}
+		fmt.Fprintf(&outlook, "\n      %s %s { %s: %s !important; }", prefix, r.selector, r.property, r.token(p))
+	}
+
+	return fmt.Sprintf(`
+    @media (prefers-color-scheme: dark) {%s
+    }
+    @media screen {%s
+    }`, standard.String(), outlook.String())
+}
+
+// emailLayout is the content of one email placed into the shared layout
+type emailLayout struct {
+	Title   string
+	Heading string
+	// Tone selects the accent color of the header, code and buttons
+	Tone string
+	// Preheader is the inbox preview text shown after the subject
+	Preheader string
+	Content   string
+	// Footer is extra footer markup below the copyright line
+	Footer string
+}
+
+// renderEmail renders a complete HTML email and inlines its stylesheet
+func renderEmail(rc RenderContext, l emailLayout) string {
+	var css strings.Builder
+	if err := emailStylesheet.Execute(&css, rc.Branding.Theme.Light); err != nil {
+		log.Printf("❌ Failed to render email stylesheet: %v", err)
+	}
+	css.WriteString(darkStylesheet(rc.Branding.Theme.Dark))
+
+	doc := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1.0">
+  <meta name="color-scheme" content="light dark">
+  <meta name="supported-color-schemes" content="light dark">
+  <title>%s</title>
+  <style>%s
+  </style>
+</head>
+<body class="tone-%s">%s
+  <table class="wrapper" width="100%%" cellpadding="0" cellspacing="0">
+    <tr>
+      <td align="center">
+        <table class="container" width="600" cellpadding="0" cellspacing="0">
+          <!-- Header -->
+          <tr>
+            <td class="header">
+              <h1>%s</h1>
+            </td>
+          </tr>
+
+          <!-- Content -->
+          <tr>
+            <td class="content">%s
+            </td>
+          </tr>
+
+          <!-- Footer -->
+          <tr>
+            <td class="footer">
+              <p>© %d %s. All rights reserved.</p>%s
+            </td>
+          </tr>
+        </table>
+      </td>
+    </tr>
+  </table>
+</body>
+</html>
+    `, html.EscapeString(l.Title), css.String(), l.Tone, preheaderBlock(l.Preheader), html.EscapeString(l.Heading), l.Content, rc.Now.Year(), html.EscapeString(rc.Branding.Name), l.Footer)
+
+	return inlineCSS(doc)
+}
+
+// preheaderPadding follows the preview text so clients don't fill the rest
+// of the preview with the start of the body ("Sponsoration Verify Your...")
+var preheaderPadding = strings.Repeat("&nbsp;&zwnj;", 90)
+
+// preheaderBlock renders the hidden inbox preview text. It carries its own
+// inline styles so it can be inserted into any HTML body.
+func preheaderBlock(text string) string {
+	if text == "" {
+		return ""
+	}
+	return fmt.Sprintf(`
+  <!-- Preheader -->
+  <div class="preheader" style="display: none; max-height: 0; max-width: 0; overflow: hidden; mso-hide: all; font-size: 1px; line-height: 1px; color: transparent; opacity: 0;">
+    %s%s
+  </div>`, html.EscapeString(text), preheaderPadding)
+}
+
+// setPreheader replaces the preheader of doc with text, or inserts one at the
+// start of the body when doc has none
+func setPreheader(doc, text string) string {
+	block := preheaderBlock(text)
+
+	if start := strings.Index(doc, "\n  <!-- Preheader -->"); start >= 0 {
+		if end := strings.Index(doc[start:], "</div>"); end >= 0 {
+			return doc[:start] + block + doc[start+end+len("</div>"):]
+		}
+	}
+
+	if body := strings.Index(doc, "<body"); body >= 0 {
+		if end := strings.IndexByte(doc[body:], '>'); end >= 0 {
+			at := body + end + 1
+			return doc[:at] + block + doc[at:]
+		}
+	}
+	return strings.TrimPrefix(block, "\n") + doc
+}
+
+// inlineCSS applies the document's stylesheet as inline styles. Templates are
+// static, so a failure is a bug; the un-inlined document is still sendable.
+func inlineCSS(doc string) string {
+	inlined, err := cssinline.Inline(doc)
+	if err != nil {
+		log.Printf("❌ Failed to inline email CSS: %v", err)
+		return doc
+	}
+	return inlined
+}
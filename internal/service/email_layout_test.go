@@ -0,0 +1,116 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplatePreheaders(t *testing.T) {
+	for _, tmpl := range Templates() {
+		t.Run(tmpl.Name, func(t *testing.T) {
+			html := tmpl.Sample(testRenderContext()).HTML
+
+			if strings.Count(html, `class="preheader"`) != 1 {
+				t.Fatal("template should render exactly one preheader block")
+			}
+			if !strings.Contains(html, "display: none;") || !strings.Contains(html, "&nbsp;&zwnj;") {
+				t.Error("preheader should be hidden and padded")
+			}
+
+			// The preheader must come before any visible content
+			if strings.Index(html, `class="preheader"`) > strings.Index(html, `class="wrapper"`) {
+				t.Error("preheader should be the first element of the body")
+			}
+		})
+	}
+}
+
+func TestSetPreheader(t *testing.T) {
+	tests := []struct {
+		name    string
+		html    string
+		text    string
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "replaces the template preheader",
+			html:    verificationEmail(testRenderContext(), "ABC123", "", DefaultCodeTTL).HTML,
+			text:    "Custom preview",
+			want:    []string{"Custom preview&nbsp;&zwnj;"},
+			notWant: []string{"Use this code to verify"},
+		},
+		{
+			name: "inserts into a body without one",
+			html: `<html><body style="margin: 0;"><p>Hi</p></body></html>`,
+			text: "Preview",
+			want: []string{`<body style="margin: 0;">` + "\n  <!-- Preheader -->", "Preview&nbsp;"},
+		},
+		{
+			name: "prepends to a fragment",
+			html: `<p>Hi</p>`,
+			text: "Preview",
+			want: []string{"<!-- Preheader -->"},
+		},
+		{
+			name:    "escapes the text",
+			html:    `<body></body>`,
+			text:    `Tom & <Jerry>`,
+			want:    []string{"Tom &amp; &lt;Jerry&gt;"},
+			notWant: []string{"<Jerry>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := setPreheader(tt.html, tt.text)
+			if strings.Count(got, `class="preheader"`) != 1 {
+				t.Errorf("want exactly one preheader block, got:\n%s", got)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("output missing %q", w)
+				}
+			}
+			for _, nw := range tt.notWant {
+				if strings.Contains(got, nw) {
+					t.Errorf("output should not contain %q", nw)
+				}
+			}
+		})
+	}
+}
+
+func TestTemplatesEscapeNames(t *testing.T) {
+	const name = "Tom & <Jerry>"
+	rc := testRenderContext()
+	deadline := rc.Now.Add(48 * time.Hour)
+	branded := rc
+	branded.Branding.Name = name
+
+	tests := []struct {
+		name string
+		msg  EmailOptions
+	}{
+		{"brand name", welcomeEmail(branded, "Jane", "https://app.example.com")},
+		{"layout heading", EmailOptions{HTML: renderEmail(rc, emailLayout{Title: "Hello", Heading: name})}},
+		{"verification code", verificationEmail(rc, name, "", DefaultCodeTTL)},
+		{"password reset", passwordResetEmail(rc, "RESET123", "Hi "+name+",", "", DefaultCodeTTL)},
+		{"password reset code", passwordResetEmail(rc, name, "Hi,", "", DefaultCodeTTL)},
+		{"welcome", welcomeEmail(rc, name, "https://app.example.com")},
+		{"message", messageNotificationEmail(rc, []ChatMessage{{SenderName: name, Body: "Hi", SentAt: rc.Now}}, "https://app.example.com/messages/1", 5, 200)},
+		{"onboarding", onboardingEmail(rc, OnboardingStep{Subject: "Tips", Headline: "Tips", Body: "Read on", Button: "Go"}, name, "https://app.example.com", "https://app.example.com/unsubscribe")},
+		{"deadline reminder", deadlineReminderEmail(rc, Deliverable{Title: "Reel", CreatorName: name, Deadline: deadline}, 48*time.Hour, "https://app.example.com")},
+		{"deletion scheduled", deletionScheduledEmail(rc, name, deadline, "https://app.example.com/undo")},
+		{"account deleted", accountDeletedEmail(rc, name)},
+		{"data export", dataExportReadyEmail(rc, name, "https://app.example.com/export", time.Hour, rc.Now.Add(time.Hour))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if strings.Contains(tt.msg.HTML, "<Jerry>") || !strings.Contains(tt.msg.HTML, "Tom &amp; &lt;Jerry&gt;") {
+				t.Error("name should be HTML-escaped")
+			}
+		})
+	}
+}
@@ -1,9 +1,15 @@
 package service
 
 import (
+	"encoding/base64"
 	"fmt"
 	"log"
+	"maps"
+	"net/url"
 	"os"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/sendgrid/sendgrid-go"
 	"github.com/sendgrid/sendgrid-go/helpers/mail"
@@ -14,22 +20,110 @@ type EmailService struct {
 	apiKey    string
 	fromEmail string
 	fromName  string
+	appURL    string
 	isDev     bool
+	clock     Clock
+	branding  Branding
+	locale    string
+	autofill  OTPAutofill
+	transport Transport
+	throttle  *Throttle
 }
 
+// DefaultCodeTTL is the lifetime of verification and reset codes shown by
+// SendVerificationEmail and SendPasswordResetEmail, and the default TTL of
+// VerificationService
+const DefaultCodeTTL = 24 * time.Hour
+
 // EmailOptions contains email parameters
 type EmailOptions struct {
 	To      string
 	Subject string
 	Text    string
 	HTML    string
+	// Preheader overrides the inbox preview text of the HTML body
+	Preheader string
+	// Attachments are sent as files alongside the message
+	Attachments []Attachment
+	// Headers are extra message headers, e.g. Message-ID and In-Reply-To
+	// for threading
+	Headers map[string]string
+}
+
+// Attachment is a file attached to an email
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+// Transport delivers a rendered email in place of the development log and
+// SendGrid, e.g. to capture emails in tests
+type Transport interface {
+	Send(opts EmailOptions) error
 }
 
-// NewEmailService creates a new email service instance
-func NewEmailService() *EmailService {
+// EmailServiceOption customizes an EmailService created by NewEmailService
+type EmailServiceOption func(*EmailService)
+
+// WithClock sets the clock used for template dates and any time-dependent
+// logic. Defaults to the system clock.
+func WithClock(clock Clock) EmailServiceOption {
+	return func(s *EmailService) {
+		s.clock = clock
+	}
+}
+
+// WithBranding sets the name and light/dark color themes used by templates.
+// Defaults to DefaultBranding().
+func WithBranding(branding Branding) EmailServiceOption {
+	return func(s *EmailService) {
+		s.branding = branding
+	}
+}
+
+// WithLocale sets the locale ("en", "de", "fr-CA", ...) amounts are
+// written in. Defaults to "en".
+func WithLocale(locale string) EmailServiceOption {
+	return func(s *EmailService) {
+		s.locale = locale
+	}
+}
+
+// WithOTPAutofill formats the codes of the named templates ("verification",
+// "password_reset") for one-time code autofill, bound to domain. An empty
+// domain means the APP_URL host.
+func WithOTPAutofill(domain string, templates ...string) EmailServiceOption {
+	return func(s *EmailService) {
+		s.autofill = OTPAutofill{Domain: domain, Templates: templates}
+	}
+}
+
+// WithTransport delivers emails through t instead of logging (development)
+// or SendGrid (production)
+func WithTransport(t Transport) EmailServiceOption {
+	return func(s *EmailService) {
+		s.transport = t
+	}
+}
+
+// WithThrottle limits verification and password reset emails with t (see
+// ThrottleVerification and ThrottlePasswordReset). Refused sends return a
+// *RateLimitError.
+func WithThrottle(t *Throttle) EmailServiceOption {
+	return func(s *EmailService) {
+		s.throttle = t
+	}
+}
+
+// NewEmailService creates a new email service instance. The SENDGRID_*,
+// APP_URL and ENV variables are read once here, so changing them later
+// doesn't affect an existing service.
+func NewEmailService(opts ...EmailServiceOption) *EmailService {
 	apiKey := os.Getenv("SENDGRID_API_KEY")
 	fromEmail := os.Getenv("SENDGRID_FROM_EMAIL")
 	fromName := os.Getenv("SENDGRID_FROM_NAME")
+	appURL := os.Getenv("APP_URL")
 	env := os.Getenv("ENV")
 
 	if fromEmail == "" {
@@ -38,6 +132,9 @@ func NewEmailService() *EmailService {
 	if fromName == "" {
 		fromName = "Sponsoration"
 	}
+	if appURL == "" {
+		appURL = "http://localhost:8082"
+	}
 
 	isDev := env == "development" || env == ""
 
@@ -45,22 +142,63 @@ func NewEmailService() *EmailService {
 		log.Println("⚠️  SENDGRID_API_KEY not set in production!")
 	}
 
-	return &EmailService{
+	s := &EmailService{
 		apiKey:    apiKey,
 		fromEmail: fromEmail,
 		fromName:  fromName,
+		appURL:    strings.TrimRight(appURL, "/"),
 		isDev:     isDev,
+		clock:     systemClock{},
+		branding:  DefaultBranding(),
+		locale:    "en",
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.autofill.Domain == "" && len(s.autofill.Templates) > 0 {
+		if u, err := url.Parse(s.appURL); err == nil {
+			s.autofill.Domain = u.Hostname()
+		}
+	}
+
+	return s
+}
+
+// RenderContext returns the settings templates are rendered with
+func (s *EmailService) RenderContext() RenderContext {
+	return RenderContext{
+		Now:         s.clock.Now(),
+		Branding:    s.branding,
+		Locale:      s.locale,
+		OTPAutofill: s.autofill,
 	}
 }
 
 // SendEmail sends an email via SendGrid
 func (s *EmailService) SendEmail(opts EmailOptions) error {
+	if opts.Preheader != "" && opts.HTML != "" {
+		opts.HTML = setPreheader(opts.HTML, opts.Preheader)
+	}
+
+	if s.transport != nil {
+		return s.transport.Send(opts)
+	}
+
 	// Development mode: log instead of sending
 	if s.isDev {
 		log.Println("📧 Email (DEV MODE - Not actually sent):")
 		log.Printf("From: %s <%s>", s.fromName, s.fromEmail)
 		log.Printf("To: %s", opts.To)
 		log.Printf("Subject: %s", opts.Subject)
+		if opts.Preheader != "" {
+			log.Printf("Preheader: %s", opts.Preheader)
+		}
+		for _, name := range slices.Sorted(maps.Keys(opts.Headers)) {
+			log.Printf("%s: %s", name, opts.Headers[name])
+		}
+		for _, a := range opts.Attachments {
+			log.Printf("Attachment: %s (%s, %d bytes)", a.Filename, a.ContentType, len(a.Content))
+		}
 		if len(opts.HTML) > 200 {
 			log.Printf("Content: %s...", opts.HTML[:200])
 		} else {
@@ -74,6 +212,17 @@ func (s *EmailService) SendEmail(opts EmailOptions) error {
 	to := mail.NewEmail("", opts.To)
 
 	message := mail.NewSingleEmail(from, opts.Subject, to, opts.Text, opts.HTML)
+	for _, a := range opts.Attachments {
+		attachment := mail.NewAttachment()
+		attachment.SetFilename(a.Filename)
+		attachment.SetType(a.ContentType)
+		attachment.SetContent(base64.StdEncoding.EncodeToString(a.Content))
+		attachment.SetDisposition("attachment")
+		message.AddAttachment(attachment)
+	}
+	for _, name := range slices.Sorted(maps.Keys(opts.Headers)) {
+		message.SetHeader(name, opts.Headers[name])
+	}
 	client := sendgrid.NewSendClient(s.apiKey)
 
 	response, err := client.Send(message)
@@ -91,42 +240,129 @@ func (s *EmailService) SendEmail(opts EmailOptions) error {
 	return nil
 }
 
-// SendVerificationEmail sends an email verification code
+// SendVerificationEmail sends an email verification code that expires after
+// DefaultCodeTTL
 func (s *EmailService) SendVerificationEmail(email, code string) error {
-	return s.SendEmail(EmailOptions{
-		To:      email,
-		Subject: "Verify Your Email Address",
-		Text:    fmt.Sprintf("Your verification code is: %s", code),
-		HTML:    getVerificationEmailTemplate(code),
+	return s.SendVerificationEmailWithToken(email, code, "", DefaultCodeTTL)
+}
+
+// SendVerificationEmailWithToken sends an email verification code together
+// with a one-click link carrying a signed token (see TokenService). The link
+// is left out when token is empty. ttl is the expiry shown in the email and
+// should be the one the code and token are stored with.
+func (s *EmailService) SendVerificationEmailWithToken(email, code, token string, ttl time.Duration) error {
+	return s.SendVerificationEmailFrom(email, "", code, token, ttl)
+}
+
+// SendVerificationEmailFrom is SendVerificationEmailWithToken on behalf of
+// client (an IP or account ID), which is rate limited alongside the
+// recipient when a throttle is set with WithThrottle
+func (s *EmailService) SendVerificationEmailFrom(email, client, code, token string, ttl time.Duration) error {
+	return s.throttled(ThrottleVerification, email, client, func() error {
+		return s.sendVerificationEmail(email, code, token, ttl)
 	})
 }
 
-// SendPasswordResetEmail sends a password reset code
+// sendVerificationEmail sends a verification email the caller has
+// throttled already
+func (s *EmailService) sendVerificationEmail(email, code, token string, ttl time.Duration) error {
+	msg := verificationEmail(s.RenderContext(), code, s.tokenLink("/verify-email", token), ttl)
+	msg.To = email
+	return s.SendEmail(msg)
+}
+
+// SendPasswordResetEmail sends a password reset code that expires after
+// DefaultCodeTTL
 func (s *EmailService) SendPasswordResetEmail(email, code string, userName ...string) error {
+	return s.SendPasswordResetEmailWithToken(email, code, "", DefaultCodeTTL, userName...)
+}
+
+// SendPasswordResetEmailWithToken sends a password reset code together with
+// a one-click reset link carrying a signed token. The link is left out when
+// token is empty. ttl is the expiry shown in the email.
+func (s *EmailService) SendPasswordResetEmailWithToken(email, code, token string, ttl time.Duration, userName ...string) error {
+	return s.SendPasswordResetEmailFrom(email, "", code, token, ttl, userName...)
+}
+
+// SendPasswordResetEmailFrom is SendPasswordResetEmailWithToken on behalf of
+// client (an IP or account ID), which is rate limited alongside the
+// recipient when a throttle is set with WithThrottle
+func (s *EmailService) SendPasswordResetEmailFrom(email, client, code, token string, ttl time.Duration, userName ...string) error {
 	greeting := "Hello,"
 	if len(userName) > 0 && userName[0] != "" {
 		greeting = fmt.Sprintf("Hi %s,", userName[0])
 	}
 
-	return s.SendEmail(EmailOptions{
-		To:      email,
-		Subject: "Reset Your Password",
-		Text:    fmt.Sprintf("Your password reset code is: %s", code),
-		HTML:    getPasswordResetEmailTemplate(code, greeting),
+	return s.throttled(ThrottlePasswordReset, email, client, func() error {
+		msg := passwordResetEmail(s.RenderContext(), code, greeting, s.tokenLink("/reset-password", token), ttl)
+		msg.To = email
+		return s.SendEmail(msg)
 	})
 }
 
+// throttled runs send through the throttle set with WithThrottle, if any
+func (s *EmailService) throttled(action ThrottleAction, recipient, client string, send func() error) error {
+	if s.throttle == nil {
+		return send()
+	}
+	return s.throttle.Do(action, recipient, client, send)
+}
+
 // SendWelcomeEmail sends a welcome email to a new user
 func (s *EmailService) SendWelcomeEmail(email, name string) error {
-	appURL := os.Getenv("APP_URL")
-	if appURL == "" {
-		appURL = "http://localhost:8082"
+	msg := welcomeEmail(s.RenderContext(), name, s.appURL)
+	msg.To = email
+	return s.SendEmail(msg)
+}
+
+// SendMagicLoginEmail sends a passwordless login link carrying token (see
+// MagicLoginService) that expires after ttl
+func (s *EmailService) SendMagicLoginEmail(email, token string, ttl time.Duration) error {
+	msg := magicLoginEmail(s.RenderContext(), s.tokenLink("/magic-login", token), ttl)
+	msg.To = email
+	return s.SendEmail(msg)
+}
+
+// SendEmailChangeConfirmation asks newAddress to confirm it should become the
+// account's address, with a link carrying token (see EmailChangeService)
+func (s *EmailService) SendEmailChangeConfirmation(newAddress, token string, ttl time.Duration) error {
+	msg := emailChangeConfirmEmail(s.RenderContext(), newAddress, s.tokenLink("/confirm-email-change", token), ttl)
+	msg.To = newAddress
+	return s.SendEmail(msg)
+}
+
+// SendEmailChangeNotice tells oldAddress that the account's address is being
+// changed to newAddress, with a link carrying token to undo it
+func (s *EmailService) SendEmailChangeNotice(oldAddress, newAddress, token string, ttl time.Duration) error {
+	msg := emailChangeNoticeEmail(s.RenderContext(), newAddress, s.tokenLink("/revert-email-change", token), ttl)
+	msg.To = oldAddress
+	return s.SendEmail(msg)
+}
+
+// SendInvitationEmail invites inv.Email to join inv.Organization as
+// inv.Role, with accept and decline links carrying acceptToken and
+// declineToken (see InvitationService) that expire after ttl
+func (s *EmailService) SendInvitationEmail(inv Invitation, acceptToken, declineToken string, ttl time.Duration) error {
+	msg := invitationEmail(s.RenderContext(), inv,
+		s.tokenLink("/invitations/accept", acceptToken), s.tokenLink("/invitations/decline", declineToken), ttl)
+	msg.To = inv.Email
+	return s.SendEmail(msg)
+}
+
+// tokenLink builds an APP_URL link to path carrying token, or "" without a
+// token
+func (s *EmailService) tokenLink(path, token string) string {
+	if token == "" {
+		return ""
 	}
+	return s.appURL + path + "?" + url.Values{"token": {token}}.Encode()
+}
 
-	return s.SendEmail(EmailOptions{
-		To:      email,
-		Subject: "Welcome to Sponsoration!",
-		Text:    fmt.Sprintf("Welcome %s! Thank you for joining Sponsoration.", name),
-		HTML:    getWelcomeEmailTemplate(name, appURL),
-	})
+// appHost is the APP_URL host, used to make IDs such as calendar UIDs and
+// Message-IDs globally unique
+func (s *EmailService) appHost() string {
+	if u, err := url.Parse(s.appURL); err == nil && u.Hostname() != "" {
+		return u.Hostname()
+	}
+	return "sponsoration"
 }
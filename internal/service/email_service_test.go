@@ -271,7 +271,7 @@ func TestEmailTemplateContent(t *testing.T) {
 		{
 			name: "verification email template",
 			templateFunc: func() string {
-				return getVerificationEmailTemplate("TEST123")
+				return getVerificationEmailTemplate(testRenderContext(), "TEST123", "", "24 hours")
 			},
 			expectedParts: []string{
 				"TEST123",
@@ -288,7 +288,7 @@ func TestEmailTemplateContent(t *testing.T) {
 		{
 			name: "password reset email template",
 			templateFunc: func() string {
-				return getPasswordResetEmailTemplate("RESET456", "Hi John,")
+				return getPasswordResetEmailTemplate(testRenderContext(), "RESET456", "Hi John,", "", "24 hours")
 			},
 			expectedParts: []string{
 				"RESET456",
@@ -306,7 +306,7 @@ func TestEmailTemplateContent(t *testing.T) {
 		{
 			name: "welcome email template",
 			templateFunc: func() string {
-				return getWelcomeEmailTemplate("Jane Smith", "https://app.example.com")
+				return getWelcomeEmailTemplate(testRenderContext(), "Jane Smith", "https://app.example.com")
 			},
 			expectedParts: []string{
 				"Jane Smith",
@@ -355,7 +355,7 @@ func TestEmailTemplateContent(t *testing.T) {
 func TestEmailTemplateVariableSubstitution(t *testing.T) {
 	t.Run("verification code is properly substituted", func(t *testing.T) {
 		code := "XYZ789"
-		template := getVerificationEmailTemplate(code)
+		template := getVerificationEmailTemplate(testRenderContext(), code, "", "24 hours")
 
 		// Should appear in the code box
 		if !strings.Contains(template, code) {
@@ -371,7 +371,7 @@ func TestEmailTemplateVariableSubstitution(t *testing.T) {
 	t.Run("password reset greeting is properly substituted", func(t *testing.T) {
 		greeting := "Hi Test User,"
 		code := "RESET999"
-		template := getPasswordResetEmailTemplate(code, greeting)
+		template := getPasswordResetEmailTemplate(testRenderContext(), code, greeting, "", "24 hours")
 
 		if !strings.Contains(template, greeting) {
 			t.Errorf("Template should contain greeting %q", greeting)
@@ -384,7 +384,7 @@ func TestEmailTemplateVariableSubstitution(t *testing.T) {
 	t.Run("welcome email personalization", func(t *testing.T) {
 		name := "Alice Johnson"
 		appURL := "https://test.example.com"
-		template := getWelcomeEmailTemplate(name, appURL)
+		template := getWelcomeEmailTemplate(testRenderContext(), name, appURL)
 
 		if !strings.Contains(template, name) {
 			t.Errorf("Template should contain name %q", name)
@@ -403,19 +403,19 @@ func TestEmailTemplateHTML(t *testing.T) {
 		{
 			name: "verification email",
 			templateFunc: func() string {
-				return getVerificationEmailTemplate("TEST")
+				return getVerificationEmailTemplate(testRenderContext(), "TEST", "", "24 hours")
 			},
 		},
 		{
 			name: "password reset email",
 			templateFunc: func() string {
-				return getPasswordResetEmailTemplate("RESET", "Hello,")
+				return getPasswordResetEmailTemplate(testRenderContext(), "RESET", "Hello,", "", "24 hours")
 			},
 		},
 		{
 			name: "welcome email",
 			templateFunc: func() string {
-				return getWelcomeEmailTemplate("User", "http://localhost:8082")
+				return getWelcomeEmailTemplate(testRenderContext(), "User", "http://localhost:8082")
 			},
 		},
 	}
@@ -471,7 +471,7 @@ func BenchmarkSendVerificationEmail(b *testing.B) {
 func BenchmarkGetVerificationEmailTemplate(b *testing.B) {
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_ = getVerificationEmailTemplate("TEST123")
+		_ = getVerificationEmailTemplate(testRenderContext(), "TEST123", "", "24 hours")
 	}
 }
 
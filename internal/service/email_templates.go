@@ -2,213 +2,1120 @@ package service
 
 import (
 	"fmt"
+	"html"
+	"strings"
 	"time"
 )
 
+// verificationEmail builds the subject and bodies of the verification email
+func verificationEmail(rc RenderContext, code, link string, ttl time.Duration) EmailOptions {
+	expiry := formatDuration(ttl)
+	text := fmt.Sprintf("Your verification code is: %s\n\nThis code expires in %s.", code, expiry)
+	if link != "" {
+		text += fmt.Sprintf("\n\nOr verify with one click: %s", link)
+	}
+	msg := EmailOptions{
+		Subject: "Verify Your Email Address",
+		Text:    text,
+		HTML:    getVerificationEmailTemplate(rc, code, link, expiry),
+	}
+	return withOTPAutofill(rc, "verification", msg, code, fmt.Sprintf("%s is your verification code", code))
+}
+
+// passwordResetEmail builds the subject and bodies of the password reset email
+func passwordResetEmail(rc RenderContext, code, greeting, link string, ttl time.Duration) EmailOptions {
+	expiry := formatDuration(ttl)
+	text := fmt.Sprintf("Your password reset code is: %s\n\nThis code expires in %s.", code, expiry)
+	if link != "" {
+		text += fmt.Sprintf("\n\nOr reset your password with one click: %s", link)
+	}
+	msg := EmailOptions{
+		Subject: "Reset Your Password",
+		Text:    text,
+		HTML:    getPasswordResetEmailTemplate(rc, code, greeting, link, expiry),
+	}
+	return withOTPAutofill(rc, "password_reset", msg, code, fmt.Sprintf("%s is your password reset code", code))
+}
+
+// magicLoginEmail builds the subject and bodies of the passwordless login
+// email
+func magicLoginEmail(rc RenderContext, link string, ttl time.Duration) EmailOptions {
+	expiry := formatDuration(ttl)
+	return EmailOptions{
+		Subject: "Your Sponsoration Login Link",
+		Text: fmt.Sprintf("Log in to Sponsoration with this link: %s\n\n"+
+			"The link expires in %s, works once and only on the device that requested it. "+
+			"If you didn't try to log in, you can ignore this email.", link, expiry),
+		HTML: getMagicLoginEmailTemplate(rc, link, expiry),
+	}
+}
+
+// emailChangeConfirmEmail builds the subject and bodies of the email asking
+// a new address to confirm an email change
+func emailChangeConfirmEmail(rc RenderContext, newAddress, link string, ttl time.Duration) EmailOptions {
+	expiry := formatDuration(ttl)
+	return EmailOptions{
+		Subject: "Confirm Your New Email Address",
+		Text: fmt.Sprintf("Confirm that %s should become the email address of your Sponsoration account: %s\n\n"+
+			"The link expires in %s. If you didn't ask for this change, you can ignore this email.", newAddress, link, expiry),
+		HTML: getEmailChangeConfirmEmailTemplate(rc, newAddress, link, expiry),
+	}
+}
+
+// emailChangeNoticeEmail builds the subject and bodies of the security notice
+// sent to the old address of an email change
+func emailChangeNoticeEmail(rc RenderContext, newAddress, link string, ttl time.Duration) EmailOptions {
+	expiry := formatDuration(ttl)
+	return EmailOptions{
+		Subject: "Your Sponsoration Email Address Is Being Changed",
+		Text: fmt.Sprintf("A request was made on %s to change the email address of your Sponsoration account to %s.\n\n"+
+			"If this wasn't you, undo the change and secure your account: %s\n\n"+
+			"This link works for %s.", rc.Now.UTC().Format("January 2, 2006 at 15:04 MST"), newAddress, link, expiry),
+		HTML: getEmailChangeNoticeEmailTemplate(rc, newAddress, link, expiry),
+	}
+}
+
+// securityAlertEmail builds the subject and bodies of a security alert. link
+// is the "this wasn't me" link.
+func securityAlertEmail(rc RenderContext, alert securityAlert, link string) EmailOptions {
+	wording := securityCopies[alert.Event]
+
+	details := []detail{
+		{"When", alert.localTime()},
+		{"Where", orUnknown(alert.Location)},
+		{"IP address", orUnknown(alert.IPAddress)},
+		{"Device", orUnknown(alert.UserAgent)},
+	}
+	if wording.detailLabel != "" && alert.Detail != "" {
+		details = append([]detail{{wording.detailLabel, alert.Detail}}, details...)
+	}
+	text, rows := renderDetails(details)
+
+	return EmailOptions{
+		Subject: wording.subject,
+		Text: fmt.Sprintf("%s\n\n%s\nIf this was you, you can ignore this email. If it wasn't, secure your account now: %s",
+			wording.summary, text, link),
+		HTML: getSecurityAlertEmailTemplate(rc, wording, rows, link),
+	}
+}
+
+// dealNotificationEmail builds the subject and bodies of a deal notification.
+// link is the deal page. Events without wording fail with
+// ErrUnknownDealEvent.
+func dealNotificationEmail(rc RenderContext, n DealNotification, link string) (EmailOptions, error) {
+	wording, ok := dealCopies[n.Event]
+	if !ok {
+		return EmailOptions{}, fmt.Errorf("%w: %q", ErrUnknownDealEvent, n.Event)
+	}
+	deal := n.Deal
+
+	amount := formatMoney(deal.Amount, rc.Locale)
+	if n.PreviousAmount != nil {
+		amount = fmt.Sprintf("%s (was %s)", amount, formatMoney(*n.PreviousAmount, rc.Locale))
+	}
+	details := []detail{
+		{"Deal", deal.Title},
+		{"Brand", deal.BrandName},
+		{"Creator", deal.CreatorName},
+		{"Amount", amount},
+	}
+	if !deal.Deadline.IsZero() {
+		details = append(details, detail{"Deadline", deal.Deadline.Format("January 2, 2006")})
+	}
+	if n.Deliverable != "" {
+		details = append(details, detail{"Deliverable", n.Deliverable})
+	}
+	text, rows := renderDetails(details)
+
+	summary := fmt.Sprintf(wording.summary, n.ActorName)
+	message := ""
+	if n.Message != "" {
+		text += fmt.Sprintf("\nMessage from %s:\n%s\n", n.ActorName, n.Message)
+		message = fmt.Sprintf(`
+
+              <!-- Message -->
+              <div class="notice">
+                <p>
+                  <strong>%s wrote:</strong> %s
+                </p>
+              </div>`, html.EscapeString(n.ActorName), html.EscapeString(n.Message))
+	}
+
+	return EmailOptions{
+		Subject: fmt.Sprintf(wording.subject, deal.Title),
+		Text:    fmt.Sprintf("%s\n\n%s\n%s: %s", summary, text, wording.button, link),
+		HTML:    getDealNotificationEmailTemplate(rc, wording, summary, rows, message, link),
+	}, nil
+}
+
+// receiptEmail builds the subject and bodies of a payment receipt
+func receiptEmail(rc RenderContext, r Receipt) EmailOptions {
+	totals := r.Totals()
+	money := func(amount int64) string {
+		return formatMoney(Money{Amount: amount, Currency: r.Currency}, rc.Locale)
+	}
+
+	var text, items strings.Builder
+	fmt.Fprintf(&text, "Thank you for your payment%s.\n\n", receiptName(r.CustomerName))
+	fmt.Fprintf(&text, "Invoice: %s\nDate: %s\n\n", r.InvoiceNumber, r.IssuedAt.Format("January 2, 2006"))
+	for _, item := range r.Items {
+		fmt.Fprintf(&text, "%d × %s: %s\n", item.Quantity, item.Description, money(item.Amount()))
+		fmt.Fprintf(&items, `
+                <tr>
+                  <td>%d × %s</td>
+                  <td class="amount">%s</td>
+                </tr>`, item.Quantity, html.EscapeString(item.Description), money(item.Amount()))
+	}
+
+	totalRows := []detail{{"Subtotal", formatMoney(totals.Subtotal, rc.Locale)}}
+	for _, tax := range totals.Taxes {
+		totalRows = append(totalRows, detail{fmt.Sprintf("%s (%s)", tax.Name, formatRate(tax.Rate)), formatMoney(tax.Amount, rc.Locale)})
+	}
+	for _, row := range totalRows {
+		fmt.Fprintf(&text, "%s: %s\n", row.label, row.value)
+		fmt.Fprintf(&items, `
+                <tr>
+                  <td>%s</td>
+                  <td class="amount">%s</td>
+                </tr>`, html.EscapeString(row.label), row.value)
+	}
+	total := formatMoney(totals.Total, rc.Locale)
+	fmt.Fprintf(&text, "Total paid: %s\n", total)
+	fmt.Fprintf(&items, `
+                <tr class="total">
+                  <td>Total paid</td>
+                  <td class="amount">%s</td>
+                </tr>`, total)
+
+	paidWith, _ := renderDetails([]detail{{"Paid with", r.PaymentMethod.String()}})
+	address := r.BillingAddress.Lines()
+	fmt.Fprintf(&text, "\n%s\nBilling address:\n%s\n", paidWith, strings.Join(address, "\n"))
+	escaped := make([]string, len(address))
+	for i, line := range address {
+		escaped[i] = html.EscapeString(line)
+	}
+
+	return EmailOptions{
+		Subject: fmt.Sprintf("Your Sponsoration receipt %s", r.InvoiceNumber),
+		Text:    text.String(),
+		HTML:    getReceiptEmailTemplate(rc, r, items.String(), strings.Join(escaped, "<br>"), total),
+	}
+}
+
+// receiptName addresses the customer in the receipt intro, if known
+func receiptName(name string) string {
+	if name == "" {
+		return ""
+	}
+	return ", " + name
+}
+
+// detail is one labelled value in the details block of an email
+type detail struct {
+	label string
+	value string
+}
+
+// renderDetails writes details as "Label: value" lines for the text part and
+// as escaped rows for the HTML part
+func renderDetails(details []detail) (text, rows string) {
+	var t, h strings.Builder
+	for _, d := range details {
+		fmt.Fprintf(&t, "%s: %s\n", d.label, d.value)
+		fmt.Fprintf(&h, `
+                <strong>%s:</strong> %s<br>`, d.label, html.EscapeString(d.value))
+	}
+	return t.String(), h.String()
+}
+
+// invitationEmail builds the subject and bodies of an invitation to join an
+// organization
+func invitationEmail(rc RenderContext, inv Invitation, acceptLink, declineLink string, ttl time.Duration) EmailOptions {
+	expiry := formatDuration(ttl)
+	return EmailOptions{
+		Subject: fmt.Sprintf("%s invited you to join %s on Sponsoration", inv.InviterName, inv.Organization),
+		Text: fmt.Sprintf("%s invited you to join %s on Sponsoration as %s.\n\n"+
+			"Accept the invitation: %s\n\n"+
+			"The invitation expires in %s. Not interested? Decline it: %s",
+			inv.InviterName, inv.Organization, inv.Role, acceptLink, expiry, declineLink),
+		HTML: getInvitationEmailTemplate(rc, inv, acceptLink, declineLink, expiry),
+	}
+}
+
+// meetingInviteEmail builds the subject and bodies of a meeting invitation.
+// A meeting with a sequence above zero is an update of an earlier invite.
+func meetingInviteEmail(rc RenderContext, m Meeting) EmailOptions {
+	subject := "Invitation: " + m.Title
+	headline := "You're Invited to a Call"
+	summary := fmt.Sprintf("%s invited you to %s.", m.Organizer.Name, m.Title)
+	if m.Sequence > 0 {
+		subject = "Updated invitation: " + m.Title
+		headline = "Your Call Was Updated"
+		summary = fmt.Sprintf("%s changed the details of %s.", m.Organizer.Name, m.Title)
+	}
+	return meetingEmail(rc, m, subject, headline, summary, tonePrimary,
+		"Accept or decline with the buttons in your mail client, or open the attached invite to add it to your calendar.")
+}
+
+// meetingCanceledEmail builds the subject and bodies of a meeting
+// cancellation
+func meetingCanceledEmail(rc RenderContext, m Meeting) EmailOptions {
+	return meetingEmail(rc, m, "Canceled: "+m.Title, "Your Call Was Canceled",
+		fmt.Sprintf("%s canceled %s.", m.Organizer.Name, m.Title), toneDanger,
+		"Your calendar removes the call when you open the attached cancellation.")
+}
+
+// meetingEmail renders the meeting details with the given wording. The
+// join button is only shown for invitations with a call link.
+func meetingEmail(rc RenderContext, m Meeting, subject, headline, summary string, tone string, note string) EmailOptions {
+	text, rows := renderDetails(m.details())
+	link := m.URL
+	if tone == toneDanger {
+		link = ""
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "%s\n\n%s", summary, text)
+	if m.Description != "" {
+		fmt.Fprintf(&body, "\n%s\n", m.Description)
+	}
+	if link != "" {
+		fmt.Fprintf(&body, "\nJoin the call: %s\n", link)
+	}
+	fmt.Fprintf(&body, "\n%s", note)
+
+	return EmailOptions{
+		Subject: subject,
+		Text:    body.String(),
+		HTML:    getMeetingEmailTemplate(rc, headline, summary, rows, m.Description, link, tone, note),
+	}
+}
+
+// digestEmail builds the subject and bodies of a daily or weekly digest
+func digestEmail(rc RenderContext, d digest) EmailOptions {
+	period := "daily"
+	if d.Cadence == DigestWeekly {
+		period = "weekly"
+	}
+	greeting := "Hi there,"
+	if d.Name != "" {
+		greeting = fmt.Sprintf("Hi %s,", d.Name)
+	}
+	summary := digestSummary(d.Sections)
+
+	var text, sections strings.Builder
+	fmt.Fprintf(&text, "%s\n\nHere's what you missed: %s.\n", greeting, summary)
+	for _, section := range d.Sections {
+		fmt.Fprintf(&text, "\n%s\n", section.Title)
+		fmt.Fprintf(&sections, `
+              <table class="items" role="presentation" width="100%%" cellpadding="0" cellspacing="0">
+                <tr>
+                  <th>%s</th>
+                </tr>`, html.EscapeString(section.Title))
+		for _, e := range section.Events {
+			fmt.Fprintf(&text, "- %s\n", e.Title)
+			if e.Summary != "" {
+				fmt.Fprintf(&text, "  %s\n", e.Summary)
+			}
+			fmt.Fprintf(&text, "  %s\n", e.Link)
+
+			summary := ""
+			if e.Summary != "" {
+				summary = "<br>" + html.EscapeString(e.Summary)
+			}
+			fmt.Fprintf(&sections, `
+                <tr>
+                  <td><a href="%s"><strong>%s</strong></a>%s</td>
+                </tr>`, html.EscapeString(e.Link), html.EscapeString(e.Title), summary)
+		}
+		if section.More > 0 {
+			fmt.Fprintf(&text, "- and %d more\n", section.More)
+			fmt.Fprintf(&sections, `
+                <tr>
+                  <td>and %d more</td>
+                </tr>`, section.More)
+		}
+		sections.WriteString(`
+              </table>`)
+	}
+	fmt.Fprintf(&text, "\nYou get this summary %s. Change how often: %s", period, d.Settings)
+
+	return EmailOptions{
+		Subject: fmt.Sprintf("Your %s Sponsoration digest: %s", period, plural(d.Total, durationUnit{"update", "updates"})),
+		Text:    text.String(),
+		HTML:    getDigestEmailTemplate(rc, greeting, period, summary, sections.String(), d.Settings),
+	}
+}
+
+// digestSummary counts the events of each section, e.g. "2 new offers and
+// 5 messages"
+func digestSummary(sections []digestSection) string {
+	parts := make([]string, len(sections))
+	for i, s := range sections {
+		parts[i] = plural(len(s.Events)+s.More, s.Noun)
+	}
+	if len(parts) <= 1 {
+		return strings.Join(parts, "")
+	}
+	return strings.Join(parts[:len(parts)-1], ", ") + " and " + parts[len(parts)-1]
+}
+
+// messageNotificationEmail builds the subject and bodies of a notification
+// about new chat messages in one conversation. The subject stays the same
+// for a conversation so mail clients thread the notifications.
+func messageNotificationEmail(rc RenderContext, messages []ChatMessage, link string, max, preview int) EmailOptions {
+	first := messages[0]
+	title := first.ConversationTitle
+	if title == "" {
+		title = first.SenderName
+	}
+	headline := "New Message from " + first.SenderName
+	summary := fmt.Sprintf("%s sent you a message.", first.SenderName)
+	if len(messages) > 1 {
+		headline = fmt.Sprintf("You Have %d New Messages", len(messages))
+		summary = fmt.Sprintf("You have %s in %s.", plural(len(messages), durationUnit{"new message", "new messages"}), title)
+	}
+
+	shown, more := messages, 0
+	if len(messages) > max {
+		// The latest messages are the most relevant
+		shown, more = messages[len(messages)-max:], len(messages)-max
+	}
+
+	var text, rows strings.Builder
+	fmt.Fprintf(&text, "%s\n\n", summary)
+	if more > 0 {
+		fmt.Fprintf(&text, "(%d earlier)\n", more)
+		fmt.Fprintf(&rows, `
+                <tr>
+                  <td>%d earlier</td>
+                </tr>`, more)
+	}
+	for _, m := range shown {
+		body := truncateText(m.Body, preview)
+		at := m.SentAt.UTC().Format("15:04 MST")
+		fmt.Fprintf(&text, "%s (%s):\n%s\n\n", m.SenderName, at, body)
+		fmt.Fprintf(&rows, `
+                <tr>
+                  <td><strong>%s</strong> · %s<br>%s</td>
+                </tr>`, html.EscapeString(m.SenderName), at, strings.ReplaceAll(html.EscapeString(body), "\n", "<br>"))
+	}
+	fmt.Fprintf(&text, "Reply on Sponsoration: %s", link)
+
+	return EmailOptions{
+		Subject: "New messages: " + title,
+		Text:    text.String(),
+		HTML:    getMessageNotificationEmailTemplate(rc, headline, summary, rows.String(), link),
+	}
+}
+
+// truncateText cuts s to at most max characters, ending with an ellipsis
+// when shortened
+func truncateText(s string, max int) string {
+	runes := []rune(strings.TrimSpace(s))
+	if len(runes) <= max {
+		return string(runes)
+	}
+	return strings.TrimSpace(string(runes[:max-1])) + "…"
+}
+
+// onboardingEmail builds the subject and bodies of one onboarding step.
+// link is the step's call to action.
+func onboardingEmail(rc RenderContext, step OnboardingStep, name, link, unsubscribe string) EmailOptions {
+	greeting := "Hi there,"
+	if name != "" {
+		greeting = fmt.Sprintf("Hi %s,", name)
+	}
+	return EmailOptions{
+		Subject: step.Subject,
+		Text: fmt.Sprintf("%s\n\n%s\n\n%s: %s\n\nDon't want these tips? Unsubscribe: %s",
+			greeting, step.Body, step.Button, link, unsubscribe),
+		HTML: getOnboardingEmailTemplate(rc, step, greeting, link, unsubscribe),
+	}
+}
+
+// deadlineReminderEmail builds the subject and bodies of a reminder that a
+// deliverable is due. left is the time until the deadline and link is the
+// deal page.
+func deadlineReminderEmail(rc RenderContext, d Deliverable, left time.Duration, link string) EmailOptions {
+	greeting := "Hi there,"
+	if d.CreatorName != "" {
+		greeting = fmt.Sprintf("Hi %s,", d.CreatorName)
+	}
+	due := formatDuration(left)
+	summary := fmt.Sprintf("Your %s for %s is due in %s.", d.Title, d.DealTitle, due)
+	text, rows := renderDetails([]detail{
+		{"Deal", d.DealTitle},
+		{"Deliverable", d.Title},
+		{"Due", d.Deadline.Format("Monday, January 2, 2006 at 15:04 MST")},
+	})
+	return EmailOptions{
+		Subject: fmt.Sprintf("Reminder: %s is due in %s", d.Title, due),
+		Text:    fmt.Sprintf("%s\n\n%s\n\n%s\nSubmit it here: %s", greeting, summary, text, link),
+		HTML:    getDeadlineReminderEmailTemplate(rc, greeting, summary, rows, link),
+	}
+}
+
+// deletionScheduledEmail builds the subject and bodies of the notice that an
+// account will be deleted. undo is the link that keeps the account.
+func deletionScheduledEmail(rc RenderContext, name string, deleteAt time.Time, undo string) EmailOptions {
+	greeting := "Hi there,"
+	if name != "" {
+		greeting = fmt.Sprintf("Hi %s,", name)
+	}
+	when := deleteAt.UTC().Format("January 2, 2006 at 15:04 MST")
+	return EmailOptions{
+		Subject: "Your Sponsoration account will be deleted",
+		Text: fmt.Sprintf("%s\n\nWe received a request to delete your Sponsoration account. "+
+			"Your account and all of its data will be permanently deleted on %s.\n\n"+
+			"Changed your mind? Keep your account: %s\n\n"+
+			"If you didn't ask for this, keep your account and reset your password.", greeting, when, undo),
+		HTML: getDeletionScheduledEmailTemplate(rc, greeting, when, undo),
+	}
+}
+
+// accountDeletedEmail builds the subject and bodies of the confirmation that
+// an account is gone. It is sent to the address captured when the deletion
+// was requested.
+func accountDeletedEmail(rc RenderContext, name string) EmailOptions {
+	greeting := "Hi there,"
+	if name != "" {
+		greeting = fmt.Sprintf("Hi %s,", name)
+	}
+	return EmailOptions{
+		Subject: "Your Sponsoration account has been deleted",
+		Text: fmt.Sprintf("%s\n\nYour Sponsoration account and all of its data have been permanently deleted. "+
+			"This is the last email you'll get from us.\n\nThank you for being part of Sponsoration.", greeting),
+		HTML: getAccountDeletedEmailTemplate(rc, greeting),
+	}
+}
+
+// dataExportReadyEmail builds the subject and bodies of the email with the
+// download link of a data export
+func dataExportReadyEmail(rc RenderContext, name, link string, ttl time.Duration, expiresAt time.Time) EmailOptions {
+	greeting := "Hi there,"
+	if name != "" {
+		greeting = fmt.Sprintf("Hi %s,", name)
+	}
+	expiry := formatDuration(ttl)
+	until := expiresAt.UTC().Format("January 2, 2006 at 15:04 MST")
+	return EmailOptions{
+		Subject: "Your Sponsoration data export is ready",
+		Text: fmt.Sprintf("%s\n\nThe copy of your Sponsoration data you asked for is ready. Download it here: %s\n\n"+
+			"The link expires in %s (%s). If you didn't ask for an export, reset your password.", greeting, link, expiry, until),
+		HTML: getDataExportReadyEmailTemplate(rc, greeting, link, expiry, until),
+	}
+}
+
+// welcomeEmail builds the subject and bodies of the welcome email
+func welcomeEmail(rc RenderContext, name, appURL string) EmailOptions {
+	return EmailOptions{
+		Subject: "Welcome to Sponsoration!",
+		Text:    fmt.Sprintf("Welcome %s! Thank you for joining Sponsoration.", name),
+		HTML:    getWelcomeEmailTemplate(rc, name, appURL),
+	}
+}
+
 // getVerificationEmailTemplate returns the HTML template for email verification
-func getVerificationEmailTemplate(code string) string {
-	year := time.Now().Year()
-	return fmt.Sprintf(`
-<!DOCTYPE html>
-<html>
-<head>
-  <meta charset="utf-8">
-  <meta name="viewport" content="width=device-width, initial-scale=1.0">
-  <title>Verify Your Email</title>
-</head>
-<body style="margin: 0; padding: 0; font-family: Arial, sans-serif; background-color: #f4f4f4;">
-  <table width="100%%" cellpadding="0" cellspacing="0" style="background-color: #f4f4f4; padding: 20px;">
-    <tr>
-      <td align="center">
-        <table width="600" cellpadding="0" cellspacing="0" style="background-color: #ffffff; border-radius: 8px; overflow: hidden;">
-          <!-- Header -->
-          <tr>
-            <td style="background-color: #4F46E5; padding: 30px 40px; text-align: center;">
-              <h1 style="margin: 0; color: #ffffff; font-size: 28px;">Sponsoration</h1>
-            </td>
-          </tr>
-
-          <!-- Content -->
-          <tr>
-            <td style="padding: 40px;">
-              <h2 style="margin: 0 0 20px 0; color: #1F2937; font-size: 24px;">Verify Your Email Address</h2>
-              <p style="margin: 0 0 20px 0; color: #4B5563; font-size: 16px; line-height: 1.5;">
+func getVerificationEmailTemplate(rc RenderContext, code, link, expiry string) string {
+	return renderEmail(rc, emailLayout{
+		Title:     "Verify Your Email",
+		Heading:   "Sponsoration",
+		Tone:      tonePrimary,
+		Preheader: fmt.Sprintf("Use this code to verify your email address. It expires in %s.", expiry),
+		Content: fmt.Sprintf(`
+              <h2>Verify Your Email Address</h2>
+              <p>
                 Thank you for registering! Please use the following code to verify your email address:
               </p>
 
               <!-- Code Box -->
-              <div style="background-color: #F3F4F6; border-radius: 8px; padding: 30px; text-align: center; margin: 30px 0;">
-                <div style="font-size: 32px; font-weight: bold; letter-spacing: 8px; color: #4F46E5; font-family: 'Courier New', monospace;">
+              <div class="token-box">
+                <div class="token">
                   %s
                 </div>
-              </div>
+              </div>%s
 
-              <p style="margin: 20px 0 0 0; color: #6B7280; font-size: 14px; line-height: 1.5;">
-                This code will expire in <strong>24 hours</strong>.
+              <p class="note">
+                This code will expire in <strong>%s</strong>.
               </p>
-              <p style="margin: 10px 0 0 0; color: #6B7280; font-size: 14px; line-height: 1.5;">
+              <p class="note">
                 If you didn't request this verification, please ignore this email.
-              </p>
-            </td>
-          </tr>
-
-          <!-- Footer -->
-          <tr>
-            <td style="background-color: #F9FAFB; padding: 30px 40px; text-align: center; border-top: 1px solid #E5E7EB;">
-              <p style="margin: 0; color: #9CA3AF; font-size: 12px;">
-                © %d Sponsoration. All rights reserved.
-              </p>
-            </td>
-          </tr>
-        </table>
-      </td>
-    </tr>
-  </table>
-</body>
-</html>
-    `, code, year)
+              </p>`, html.EscapeString(code), linkButton(link, "Verify Email Address"), expiry),
+	})
 }
 
 // getPasswordResetEmailTemplate returns the HTML template for password reset
-func getPasswordResetEmailTemplate(code, greeting string) string {
-	year := time.Now().Year()
-	return fmt.Sprintf(`
-<!DOCTYPE html>
-<html>
-<head>
-  <meta charset="utf-8">
-  <meta name="viewport" content="width=device-width, initial-scale=1.0">
-  <title>Reset Your Password</title>
-</head>
-<body style="margin: 0; padding: 0; font-family: Arial, sans-serif; background-color: #f4f4f4;">
-  <table width="100%%" cellpadding="0" cellspacing="0" style="background-color: #f4f4f4; padding: 20px;">
-    <tr>
-      <td align="center">
-        <table width="600" cellpadding="0" cellspacing="0" style="background-color: #ffffff; border-radius: 8px; overflow: hidden;">
-          <!-- Header -->
-          <tr>
-            <td style="background-color: #DC2626; padding: 30px 40px; text-align: center;">
-              <h1 style="margin: 0; color: #ffffff; font-size: 28px;">🔒 Password Reset</h1>
-            </td>
-          </tr>
-
-          <!-- Content -->
-          <tr>
-            <td style="padding: 40px;">
-              <h2 style="margin: 0 0 20px 0; color: #1F2937; font-size: 24px;">Reset Your Password</h2>
-              <p style="margin: 0 0 20px 0; color: #4B5563; font-size: 16px; line-height: 1.5;">
-                %s
-              </p>
-              <p style="margin: 0 0 20px 0; color: #4B5563; font-size: 16px; line-height: 1.5;">
+func getPasswordResetEmailTemplate(rc RenderContext, code, greeting, link, expiry string) string {
+	return renderEmail(rc, emailLayout{
+		Title:     "Reset Your Password",
+		Heading:   "🔒 Password Reset",
+		Tone:      toneDanger,
+		Preheader: "Use this code to reset your password. If you didn't ask for it, you can ignore this email.",
+		Content: fmt.Sprintf(`
+              <h2>Reset Your Password</h2>
+              <p>
+                %s
+              </p>
+              <p>
                 You requested to reset your password. Please use the following code:
               </p>
 
               <!-- Code Box -->
-              <div style="background-color: #FEF2F2; border: 2px solid #FCA5A5; border-radius: 8px; padding: 30px; text-align: center; margin: 30px 0;">
-                <div style="font-size: 32px; font-weight: bold; letter-spacing: 8px; color: #DC2626; font-family: 'Courier New', monospace;">
+              <div class="token-box">
+                <div class="token">
                   %s
                 </div>
-              </div>
+              </div>%s
 
-              <p style="margin: 20px 0 0 0; color: #6B7280; font-size: 14px; line-height: 1.5;">
-                This code will expire in <strong>24 hours</strong>.
+              <p class="note">
+                This code will expire in <strong>%s</strong>.
               </p>
-              <p style="margin: 10px 0 0 0; color: #6B7280; font-size: 14px; line-height: 1.5;">
+              <p class="note">
                 If you didn't request a password reset, please ignore this email and your password will remain unchanged.
               </p>
 
               <!-- Security Notice -->
-              <div style="background-color: #FFFBEB; border-left: 4px solid #F59E0B; padding: 15px; margin-top: 30px;">
-                <p style="margin: 0; color: #92400E; font-size: 13px; line-height: 1.5;">
+              <div class="notice">
+                <p>
                   <strong>Security Tip:</strong> Never share your password reset code with anyone. Sponsoration staff will never ask for this code.
                 </p>
+              </div>`, html.EscapeString(greeting), html.EscapeString(code), linkButton(link, "Reset Password"), expiry),
+	})
+}
+
+// getMagicLoginEmailTemplate returns the HTML template for passwordless login
+func getMagicLoginEmailTemplate(rc RenderContext, link, expiry string) string {
+	return renderEmail(rc, emailLayout{
+		Title:     "Log In to Sponsoration",
+		Heading:   "Sponsoration",
+		Tone:      tonePrimary,
+		Preheader: fmt.Sprintf("Your login link expires in %s and only works on the device that requested it.", expiry),
+		Content: fmt.Sprintf(`
+              <h2>Log In to Sponsoration</h2>
+              <p>
+                Click the button below to log in. No password needed.
+              </p>
+
+              <!-- CTA Button -->
+              <div class="actions">
+                <a class="button" href="%s">
+                  Log In
+                </a>
               </div>
-            </td>
-          </tr>
-
-          <!-- Footer -->
-          <tr>
-            <td style="background-color: #F9FAFB; padding: 30px 40px; text-align: center; border-top: 1px solid #E5E7EB;">
-              <p style="margin: 0; color: #9CA3AF; font-size: 12px;">
-                © %d Sponsoration. All rights reserved.
-              </p>
-            </td>
-          </tr>
-        </table>
-      </td>
-    </tr>
-  </table>
-</body>
-</html>
-    `, greeting, code, year)
+
+              <p class="note">
+                This link expires in <strong>%s</strong> and can only be used once, on the device you requested it from.
+              </p>
+              <p class="note">
+                If you didn't try to log in, you can ignore this email. Nobody can use this link without access to your inbox.
+              </p>`, html.EscapeString(link), expiry),
+	})
 }
 
-// getWelcomeEmailTemplate returns the HTML template for welcome email
-func getWelcomeEmailTemplate(name, appURL string) string {
-	year := time.Now().Year()
+// getEmailChangeConfirmEmailTemplate returns the HTML template for confirming
+// a new email address
+func getEmailChangeConfirmEmailTemplate(rc RenderContext, newAddress, link, expiry string) string {
+	return renderEmail(rc, emailLayout{
+		Title:     "Confirm Your New Email Address",
+		Heading:   "Sponsoration",
+		Tone:      tonePrimary,
+		Preheader: "Confirm this address to finish changing the email on your Sponsoration account.",
+		Content: fmt.Sprintf(`
+              <h2>Confirm Your New Email Address</h2>
+              <p>
+                You asked to use <strong>%s</strong> for your Sponsoration account. Please confirm it's yours:
+              </p>
+
+              <!-- CTA Button -->
+              <div class="actions">
+                <a class="button" href="%s">
+                  Confirm Email Address
+                </a>
+              </div>
+
+              <p class="note">
+                This link will expire in <strong>%s</strong>.
+              </p>
+              <p class="note">
+                If you didn't ask for this change, please ignore this email. Your account will keep its current address.
+              </p>`, html.EscapeString(newAddress), html.EscapeString(link), expiry),
+	})
+}
+
+// getEmailChangeNoticeEmailTemplate returns the HTML template for the email
+// change security notice
+func getEmailChangeNoticeEmailTemplate(rc RenderContext, newAddress, link, expiry string) string {
+	return renderEmail(rc, emailLayout{
+		Title:     "Your Email Address Is Being Changed",
+		Heading:   "🔒 Security Notice",
+		Tone:      toneDanger,
+		Preheader: "If you didn't change your email address, you can undo it with one click.",
+		Content: fmt.Sprintf(`
+              <h2>Your Email Address Is Being Changed</h2>
+              <p>
+                A request was made on %s to change the email address of your Sponsoration account to <strong>%s</strong>.
+              </p>
+              <p>
+                If this was you, there's nothing to do.
+              </p>
+
+              <!-- Security Notice -->
+              <div class="notice">
+                <p>
+                  <strong>Wasn't you?</strong> Someone may have access to your account. Undo the change to keep this address and sign out everywhere, then reset your password.
+                </p>
+              </div>
+
+              <div class="actions">
+                <a class="button" href="%s">
+                  Undo Email Change
+                </a>
+              </div>
+
+              <p class="note">
+                This link works for <strong>%s</strong>.
+              </p>`, rc.Now.UTC().Format("January 2, 2006 at 15:04 MST"), html.EscapeString(newAddress), html.EscapeString(link), expiry),
+	})
+}
+
+// getSecurityAlertEmailTemplate returns the HTML template for security
+// alerts. rows are the pre-rendered event details.
+func getSecurityAlertEmailTemplate(rc RenderContext, wording securityCopy, rows, link string) string {
+	return renderEmail(rc, emailLayout{
+		Title:     wording.headline,
+		Heading:   "🔒 Security Alert",
+		Tone:      toneDanger,
+		Preheader: wording.summary + " If this wasn't you, secure your account now.",
+		Content: fmt.Sprintf(`
+              <h2>%s</h2>
+              <p>
+                %s
+              </p>
+              <p>%s
+              </p>
+              <p>
+                If this was you, you can ignore this email.
+              </p>
+
+              <!-- Security Notice -->
+              <div class="notice">
+                <p>
+                  <strong>Wasn't you?</strong> Someone else may have access to your account. Lock it now, then reset your password and review your sign-in methods.
+                </p>
+              </div>
+
+              <div class="actions">
+                <a class="button" href="%s">
+                  This Wasn't Me
+                </a>
+              </div>`, wording.headline, wording.summary, rows, html.EscapeString(link)),
+	})
+}
+
+// getInvitationEmailTemplate returns the HTML template for invitations
+func getInvitationEmailTemplate(rc RenderContext, inv Invitation, acceptLink, declineLink, expiry string) string {
+	return renderEmail(rc, emailLayout{
+		Title:     "You're Invited",
+		Heading:   "Sponsoration",
+		Tone:      tonePrimary,
+		Preheader: fmt.Sprintf("%s invited you to join %s as %s.", inv.InviterName, inv.Organization, inv.Role),
+		Content: fmt.Sprintf(`
+              <h2>Join %s on Sponsoration</h2>
+              <p>
+                <strong>%s</strong> invited you to join <strong>%s</strong> as <strong>%s</strong>.
+              </p>
+
+              <!-- CTA Button -->
+              <div class="actions">
+                <a class="button" href="%s">
+                  Accept Invitation
+                </a>
+              </div>
+
+              <p class="note">
+                This invitation will expire in <strong>%s</strong>.
+              </p>
+              <p class="note">
+                Not interested? <a href="%s">Decline the invitation</a>. If you don't know %s, you can ignore this email.
+              </p>`,
+			html.EscapeString(inv.Organization), html.EscapeString(inv.InviterName), html.EscapeString(inv.Organization),
+			html.EscapeString(inv.Role), html.EscapeString(acceptLink), expiry, html.EscapeString(declineLink),
+			html.EscapeString(inv.InviterName)),
+	})
+}
+
+// getDealNotificationEmailTemplate returns the HTML template for deal
+// notifications. rows are the pre-rendered deal details and message the
+// optional note from the actor.
+func getDealNotificationEmailTemplate(rc RenderContext, wording dealCopy, summary, rows, message, link string) string {
+	return renderEmail(rc, emailLayout{
+		Title:     wording.headline,
+		Heading:   "Sponsoration",
+		Tone:      wording.tone,
+		Preheader: summary,
+		Content: fmt.Sprintf(`
+              <h2>%s</h2>
+              <p>
+                %s
+              </p>
+              <p>%s
+              </p>%s
+
+              <!-- CTA Button -->
+              <div class="actions">
+                <a class="button" href="%s">
+                  %s
+                </a>
+              </div>`, wording.headline, html.EscapeString(summary), rows, message, html.EscapeString(link), wording.button),
+	})
+}
+
+// getReceiptEmailTemplate returns the HTML template for payment receipts.
+// items are the pre-rendered line item and total rows, address the escaped
+// billing address lines.
+func getReceiptEmailTemplate(rc RenderContext, r Receipt, items, address, total string) string {
+	return renderEmail(rc, emailLayout{
+		Title:     "Payment Receipt",
+		Heading:   "🧾 Payment Receipt",
+		Tone:      toneSuccess,
+		Preheader: fmt.Sprintf("We received your payment of %s. Invoice %s.", total, r.InvoiceNumber),
+		Content: fmt.Sprintf(`
+              <h2>Thank You for Your Payment</h2>
+              <p>
+                <strong>Invoice:</strong> %s<br>
+                <strong>Date:</strong> %s
+              </p>
+
+              <!-- Line Items -->
+              <table class="items" role="presentation" width="100%%" cellpadding="0" cellspacing="0">
+                <tr>
+                  <th>Description</th>
+                  <th class="amount">Amount</th>
+                </tr>%s
+              </table>
+
+              <p>
+                <strong>Paid with:</strong> %s
+              </p>
+              <p>
+                <strong>Billing address:</strong><br>
+                %s
+              </p>
+
+              <p class="note">
+                Keep this email for your records. Questions about this charge? Reply to this email.
+              </p>`, html.EscapeString(r.InvoiceNumber), r.IssuedAt.Format("January 2, 2006"), items,
+			html.EscapeString(r.PaymentMethod.String()), address),
+	})
+}
+
+// getMeetingEmailTemplate returns the HTML template for meeting invitations,
+// updates and cancellations. rows are the pre-rendered meeting details.
+func getMeetingEmailTemplate(rc RenderContext, headline, summary, rows, description, link string, tone string, note string) string {
+	var agenda, button string
+	if description != "" {
+		agenda = fmt.Sprintf(`
+              <p>
+                %s
+              </p>`, strings.ReplaceAll(html.EscapeString(description), "\n", "<br>"))
+	}
+	if link != "" {
+		button = fmt.Sprintf(`
+
+              <!-- CTA Button -->
+              <div class="actions">
+                <a class="button" href="%s">
+                  Join Call
+                </a>
+              </div>`, html.EscapeString(link))
+	}
+	return renderEmail(rc, emailLayout{
+		Title:     headline,
+		Heading:   "Sponsoration",
+		Tone:      tone,
+		Preheader: summary,
+		Content: fmt.Sprintf(`
+              <h2>%s</h2>
+              <p>
+                %s
+              </p>
+              <p>%s
+              </p>%s%s
+
+              <p class="note">
+                %s
+              </p>`, headline, html.EscapeString(summary), rows, agenda, button, note),
+	})
+}
+
+// getDigestEmailTemplate returns the HTML template for digests. sections
+// are the pre-rendered event tables.
+func getDigestEmailTemplate(rc RenderContext, greeting, period, summary, sections, settings string) string {
+	title := "Your Daily Digest"
+	if period == "weekly" {
+		title = "Your Weekly Digest"
+	}
+	return renderEmail(rc, emailLayout{
+		Title:     title,
+		Heading:   "Sponsoration",
+		Tone:      tonePrimary,
+		Preheader: "Here's what you missed: " + summary + ".",
+		Content: fmt.Sprintf(`
+              <h2>%s</h2>
+              <p>
+                %s
+              </p>
+              <p>
+                Here's what you missed: %s.
+              </p>
+%s
+
+              <p class="note">
+                You get this summary %s. <a href="%s">Change how often</a>.
+              </p>`, title, html.EscapeString(greeting), html.EscapeString(summary), sections, period, html.EscapeString(settings)),
+	})
+}
+
+// getMessageNotificationEmailTemplate returns the HTML template for new
+// message notifications. rows are the pre-rendered quoted messages.
+func getMessageNotificationEmailTemplate(rc RenderContext, headline, summary, rows, link string) string {
+	return renderEmail(rc, emailLayout{
+		Title:     headline,
+		Heading:   "Sponsoration",
+		Tone:      tonePrimary,
+		Preheader: summary,
+		Content: fmt.Sprintf(`
+              <h2>%s</h2>
+              <p>
+                %s
+              </p>
+
+              <!-- Messages -->
+              <table class="items" role="presentation" width="100%%" cellpadding="0" cellspacing="0">%s
+              </table>
+
+              <!-- CTA Button -->
+              <div class="actions">
+                <a class="button" href="%s">
+                  Reply
+                </a>
+              </div>
+
+              <p class="note">
+                We only email you about messages you haven't read yet.
+              </p>`, html.EscapeString(headline), html.EscapeString(summary), rows, html.EscapeString(link)),
+	})
+}
+
+// getOnboardingEmailTemplate returns the HTML template for onboarding
+// steps
+func getOnboardingEmailTemplate(rc RenderContext, step OnboardingStep, greeting, link, unsubscribe string) string {
+	return renderEmail(rc, emailLayout{
+		Title:     step.Headline,
+		Heading:   "Sponsoration",
+		Tone:      toneSuccess,
+		Preheader: step.Body,
+		Content: fmt.Sprintf(`
+              <h2>%s</h2>
+              <p>
+                %s
+              </p>
+              <p>
+                %s
+              </p>
+
+              <!-- CTA Button -->
+              <div class="actions">
+                <a class="button" href="%s">
+                  %s
+                </a>
+              </div>
+
+              <p class="note">
+                Best regards,<br>
+                <strong>The Sponsoration Team</strong>
+              </p>`, html.EscapeString(step.Headline), html.EscapeString(greeting), html.EscapeString(step.Body),
+			html.EscapeString(link), html.EscapeString(step.Button)),
+		Footer: fmt.Sprintf(`
+              <p class="links">
+                You're getting tips for new creators. <a href="%s">Unsubscribe</a>
+              </p>`, html.EscapeString(unsubscribe)),
+	})
+}
+
+// getDeadlineReminderEmailTemplate returns the HTML template for deadline
+// reminders
+func getDeadlineReminderEmailTemplate(rc RenderContext, greeting, summary, rows, link string) string {
+	return renderEmail(rc, emailLayout{
+		Title:     "Deadline Reminder",
+		Heading:   "Sponsoration",
+		Tone:      tonePrimary,
+		Preheader: summary,
+		Content: fmt.Sprintf(`
+              <h2>Deadline Reminder</h2>
+              <p>
+                %s
+              </p>
+              <p>
+                %s
+              </p>
+              <p>%s
+              </p>
+
+              <!-- CTA Button -->
+              <div class="actions">
+                <a class="button" href="%s">
+                  Submit Deliverable
+                </a>
+              </div>
+
+              <p class="note">
+                Already submitted it? You can ignore this email.
+              </p>`, html.EscapeString(greeting), html.EscapeString(summary), rows, html.EscapeString(link)),
+	})
+}
+
+// getDeletionScheduledEmailTemplate returns the HTML template for scheduled
+// account deletions
+func getDeletionScheduledEmailTemplate(rc RenderContext, greeting, when, undo string) string {
+	return renderEmail(rc, emailLayout{
+		Title:     "Your Account Will Be Deleted",
+		Heading:   "Sponsoration",
+		Tone:      toneDanger,
+		Preheader: fmt.Sprintf("Your account will be permanently deleted on %s. You can still undo this.", when),
+		Content: fmt.Sprintf(`
+              <h2>Your Account Will Be Deleted</h2>
+              <p>
+                %s
+              </p>
+              <p>
+                We received a request to delete your Sponsoration account. Your account and all of its data will be permanently deleted on <strong>%s</strong>.
+              </p>
+
+              <!-- CTA Button -->
+              <div class="actions">
+                <a class="button" href="%s">
+                  Keep My Account
+                </a>
+              </div>
+
+              <!-- Security Notice -->
+              <div class="notice">
+                <p>
+                  <strong>Didn't ask for this?</strong> Keep your account, then reset your password.
+                </p>
+              </div>`, html.EscapeString(greeting), when, html.EscapeString(undo)),
+	})
+}
+
+// getAccountDeletedEmailTemplate returns the HTML template for the
+// confirmation that an account was deleted
+func getAccountDeletedEmailTemplate(rc RenderContext, greeting string) string {
+	return renderEmail(rc, emailLayout{
+		Title:     "Your Account Has Been Deleted",
+		Heading:   "Sponsoration",
+		Tone:      tonePrimary,
+		Preheader: "Your Sponsoration account and all of its data have been permanently deleted.",
+		Content: fmt.Sprintf(`
+              <h2>Your Account Has Been Deleted</h2>
+              <p>
+                %s
+              </p>
+              <p>
+                Your Sponsoration account and all of its data have been permanently deleted. This is the last email you'll get from us.
+              </p>
+
+              <p class="note">
+                Thank you for being part of Sponsoration.<br>
+                <strong>The Sponsoration Team</strong>
+              </p>`, html.EscapeString(greeting)),
+	})
+}
+
+// getDataExportReadyEmailTemplate returns the HTML template for finished
+// data exports
+func getDataExportReadyEmailTemplate(rc RenderContext, greeting, link, expiry, until string) string {
+	return renderEmail(rc, emailLayout{
+		Title:     "Your Data Export Is Ready",
+		Heading:   "Sponsoration",
+		Tone:      toneSuccess,
+		Preheader: fmt.Sprintf("Download a copy of your Sponsoration data. The link expires in %s.", expiry),
+		Content: fmt.Sprintf(`
+              <h2>Your Data Export Is Ready</h2>
+              <p>
+                %s
+              </p>
+              <p>
+                The copy of your Sponsoration data you asked for is ready to download.
+              </p>
+
+              <!-- CTA Button -->
+              <div class="actions">
+                <a class="button" href="%s">
+                  Download My Data
+                </a>
+              </div>
+
+              <p class="note">
+                This link expires in <strong>%s</strong> (%s).
+              </p>
+              <p class="note">
+                If you didn't ask for an export, reset your password.
+              </p>`, html.EscapeString(greeting), html.EscapeString(link), expiry, until),
+	})
+}
+
+// linkButton renders the one-click alternative to typing a code, or nothing
+// when there is no link
+func linkButton(link, label string) string {
+	if link == "" {
+		return ""
+	}
 	return fmt.Sprintf(`
-<!DOCTYPE html>
-<html>
-<head>
-  <meta charset="utf-8">
-  <meta name="viewport" content="width=device-width, initial-scale=1.0">
-  <title>Welcome to Sponsoration</title>
-</head>
-<body style="margin: 0; padding: 0; font-family: Arial, sans-serif; background-color: #f4f4f4;">
-  <table width="100%%" cellpadding="0" cellspacing="0" style="background-color: #f4f4f4; padding: 20px;">
-    <tr>
-      <td align="center">
-        <table width="600" cellpadding="0" cellspacing="0" style="background-color: #ffffff; border-radius: 8px; overflow: hidden;">
-          <!-- Header -->
-          <tr>
-            <td style="background-color: #10B981; padding: 30px 40px; text-align: center;">
-              <h1 style="margin: 0; color: #ffffff; font-size: 28px;">🎉 Welcome to Sponsoration!</h1>
-            </td>
-          </tr>
-
-          <!-- Content -->
-          <tr>
-            <td style="padding: 40px;">
-              <h2 style="margin: 0 0 20px 0; color: #1F2937; font-size: 24px;">Hi %s,</h2>
-              <p style="margin: 0 0 20px 0; color: #4B5563; font-size: 16px; line-height: 1.5;">
+
+              <!-- Magic Link -->
+              <p>
+                Or skip typing the code and continue with one click:
+              </p>
+              <div class="actions">
+                <a class="button" href="%s">
+                  %s
+                </a>
+              </div>`, html.EscapeString(link), label)
+}
+
+// getWelcomeEmailTemplate returns the HTML template for welcome email
+func getWelcomeEmailTemplate(rc RenderContext, name, appURL string) string {
+	return renderEmail(rc, emailLayout{
+		Title:     "Welcome to Sponsoration",
+		Heading:   "🎉 Welcome to Sponsoration!",
+		Tone:      toneSuccess,
+		Preheader: "Your account is ready. Complete your profile to start finding sponsorships.",
+		Content: fmt.Sprintf(`
+              <h2>Hi %s,</h2>
+              <p>
                 Thank you for joining our community! We're excited to have you on board.
               </p>
-              <p style="margin: 0 0 30px 0; color: #4B5563; font-size: 16px; line-height: 1.5;">
+              <p>
                 Get started by completing your profile and exploring the platform.
               </p>
 
               <!-- CTA Button -->
-              <div style="text-align: center; margin: 30px 0;">
-                <a href="%s"
-                   style="display: inline-block; background-color: #10B981; color: #ffffff; text-decoration: none; padding: 15px 30px; border-radius: 6px; font-weight: bold; font-size: 16px;">
+              <div class="actions">
+                <a class="button" href="%s">
                   Go to Dashboard
                 </a>
               </div>
 
-              <p style="margin: 30px 0 0 0; color: #6B7280; font-size: 14px; line-height: 1.5;">
+              <p class="note">
                 Best regards,<br>
                 <strong>The Sponsoration Team</strong>
-              </p>
-            </td>
-          </tr>
-
-          <!-- Footer -->
-          <tr>
-            <td style="background-color: #F9FAFB; padding: 30px 40px; text-align: center; border-top: 1px solid #E5E7EB;">
-              <p style="margin: 0 0 10px 0; color: #9CA3AF; font-size: 12px;">
-                © %d Sponsoration. All rights reserved.
-              </p>
-              <p style="margin: 0; color: #9CA3AF; font-size: 12px;">
-                <a href="%s/privacy/policy" style="color: #6B7280; text-decoration: none;">Privacy Policy</a> •
-                <a href="%s/privacy/terms" style="color: #6B7280; text-decoration: none;">Terms of Service</a>
-              </p>
-            </td>
-          </tr>
-        </table>
-      </td>
-    </tr>
-  </table>
-</body>
-</html>
-    `, name, appURL, year, appURL, appURL)
+              </p>`, html.EscapeString(name), html.EscapeString(appURL)),
+		Footer: fmt.Sprintf(`
+              <p class="links">
+                <a href="%s/privacy/policy">Privacy Policy</a> •
+                <a href="%s/privacy/terms">Terms of Service</a>
+              </p>`, html.EscapeString(appURL), html.EscapeString(appURL)),
+	})
 }
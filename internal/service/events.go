@@ -0,0 +1,476 @@
+package service
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrInvalidEmailRule is returned for a rules file with a rule that
+	// can't be applied
+	ErrInvalidEmailRule = errors.New("invalid email rule")
+	// ErrEventPayload is returned when an event doesn't carry the payload
+	// its template needs, or the payload describes a different event
+	ErrEventPayload = errors.New("event payload does not match template")
+	// ErrNoRecipient is returned when an event lacks a recipient role a
+	// rule sends to
+	ErrNoRecipient = errors.New("event has no recipient for role")
+)
+
+// Event types published by the services. The email rules refer to them by
+// these names.
+const (
+	EventUserRegistered       = "UserRegistered"
+	EventDealProposed         = "DealProposed"
+	EventDealCountered        = "DealCountered"
+	EventDealAccepted         = "DealAccepted"
+	EventDealDeclined         = "DealDeclined"
+	EventDeliverableSubmitted = "DeliverableSubmitted"
+	EventDeliverableApproved  = "DeliverableApproved"
+	EventDealCompleted        = "DealCompleted"
+	EventPaymentReceived      = "PaymentReceived"
+)
+
+// dealEventTypes maps the deal event types to the DealEvent of their
+// DealNotification payload
+var dealEventTypes = map[string]DealEvent{
+	EventDealProposed:         DealProposed,
+	EventDealCountered:        DealCountered,
+	EventDealAccepted:         DealAccepted,
+	EventDealDeclined:         DealDeclined,
+	EventDeliverableSubmitted: DeliverableSubmitted,
+	EventDeliverableApproved:  DeliverableApproved,
+	EventDealCompleted:        DealCompleted,
+}
+
+// ChannelEmail is the channel that sends rules' emails through EmailService
+const ChannelEmail = "email"
+
+// Event is something that happened in the domain, e.g. a user registered
+type Event struct {
+	// Type selects the rules that apply, e.g. EventDealAccepted
+	Type string
+	// ID identifies the event. Defaults to a random ID when handled.
+	ID         string
+	OccurredAt time.Time
+	// Recipients are the people involved by role, e.g. "user", "creator",
+	// "brand" or "counterparty". Rules pick recipients by role.
+	Recipients map[string]AccountContact
+	// Payload is what the templates need, e.g. a DealNotification for
+	// deal events or a Receipt for payments
+	Payload any
+}
+
+// EventHandler reacts to a published event
+type EventHandler func(e Event) error
+
+// EventBus delivers published events to the handlers subscribed to their
+// type. MemoryEventBus dispatches in process; a persistent bus would store
+// events before dispatching so none are lost on restart.
+type EventBus interface {
+	// Subscribe adds a handler for events of eventType
+	Subscribe(eventType string, handler EventHandler)
+	// Publish hands e to the subscribed handlers
+	Publish(e Event) error
+}
+
+// MemoryEventBus is an in-process EventBus. Publish calls the handlers
+// synchronously, in the order they subscribed.
+type MemoryEventBus struct {
+	mu       sync.RWMutex
+	handlers map[string][]EventHandler
+}
+
+// NewMemoryEventBus creates an event bus without subscribers
+func NewMemoryEventBus() *MemoryEventBus {
+	return &MemoryEventBus{handlers: map[string][]EventHandler{}}
+}
+
+// Subscribe adds a handler for events of eventType
+func (b *MemoryEventBus) Subscribe(eventType string, handler EventHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+// Publish calls every handler of e.Type. A failing handler doesn't stop the
+// others; their errors are joined.
+func (b *MemoryEventBus) Publish(e Event) error {
+	b.mu.RLock()
+	handlers := b.handlers[e.Type]
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, handle := range handlers {
+		if err := handle(e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// EmailRule maps an event type to the email sent for it
+type EmailRule struct {
+	// Event is the event type, e.g. "DealAccepted"
+	Event string
+	// Template names an EventTemplate, e.g. "deal_notification"
+	Template string
+	// To lists the recipient roles, e.g. ["creator", "brand"]
+	To []string
+	// Delay holds the email back after the event. Zero sends right away.
+	Delay time.Duration
+	// Channel delivers the email. Defaults to ChannelEmail.
+	Channel string
+}
+
+// emailRulesFile is the JSON form of a rules file
+type emailRulesFile struct {
+	Rules []struct {
+		Event    string   `json:"event"`
+		Template string   `json:"template"`
+		To       []string `json:"to"`
+		Delay    string   `json:"delay"`
+		Channel  string   `json:"channel"`
+	} `json:"rules"`
+}
+
+//go:embed email_rules.json
+var defaultEmailRules []byte
+
+// DefaultEmailRules returns the built-in rules, which send the deal, welcome
+// and receipt emails
+func DefaultEmailRules() []EmailRule {
+	rules, err := ParseEmailRules(defaultEmailRules)
+	if err != nil {
+		panic(err)
+	}
+	return rules
+}
+
+// LoadEmailRules reads a rules file, see ParseEmailRules
+func LoadEmailRules(path string) ([]EmailRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read email rules: %w", err)
+	}
+	return ParseEmailRules(data)
+}
+
+// ParseEmailRules parses a JSON rules file such as
+//
+//	{"rules": [
+//	  {"event": "DealAccepted", "template": "deal_notification", "to": ["counterparty"]},
+//	  {"event": "UserRegistered", "template": "welcome", "to": ["user"], "delay": "10m"}
+//	]}
+//
+// Delays use time.ParseDuration syntax and the channel defaults to "email".
+func ParseEmailRules(data []byte) ([]EmailRule, error) {
+	var file emailRulesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse email rules: %w", err)
+	}
+
+	var rules []EmailRule
+	for i, r := range file.Rules {
+		rule := EmailRule{Event: r.Event, Template: r.Template, To: r.To, Channel: r.Channel}
+		if rule.Channel == "" {
+			rule.Channel = ChannelEmail
+		}
+		if r.Delay != "" {
+			delay, err := time.ParseDuration(r.Delay)
+			if err != nil || delay < 0 {
+				return nil, fmt.Errorf("%w: rule %d has invalid delay %q", ErrInvalidEmailRule, i+1, r.Delay)
+			}
+			rule.Delay = delay
+		}
+		if rule.Event == "" || rule.Template == "" || len(rule.To) == 0 {
+			return nil, fmt.Errorf("%w: rule %d needs an event, a template and recipients", ErrInvalidEmailRule, i+1)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// EventTemplate renders the email a rule sends to the recipient with role
+// of an event. To is filled in by the caller.
+type EventTemplate func(s *EmailService, e Event, role string, to AccountContact) (EmailOptions, error)
+
+// ChannelFunc delivers a rendered email whose To is set
+type ChannelFunc func(msg EmailOptions) error
+
+// eventTemplates are the templates rules can name without registering them
+var eventTemplates = map[string]EventTemplate{
+	"welcome": func(s *EmailService, _ Event, _ string, to AccountContact) (EmailOptions, error) {
+		return welcomeEmail(s.RenderContext(), to.Name, s.appURL), nil
+	},
+	"deal_notification": func(s *EmailService, e Event, role string, _ AccountContact) (EmailOptions, error) {
+		n, ok := e.Payload.(DealNotification)
+		if !ok {
+			return EmailOptions{}, fmt.Errorf("%w: deal_notification needs a DealNotification", ErrEventPayload)
+		}
+		if want, ok := dealEventTypes[e.Type]; ok {
+			if n.Event == "" {
+				n.Event = want
+			} else if n.Event != want {
+				return EmailOptions{}, fmt.Errorf("%w: %s event carries a %s notification", ErrEventPayload, e.Type, n.Event)
+			}
+		}
+		// Events sent to both parties, like DealCompleted, name the other
+		// side of the deal rather than the recipient
+		switch {
+		case role == "creator" && n.Deal.BrandName != "":
+			n.ActorName = n.Deal.BrandName
+		case role == "brand" && n.Deal.CreatorName != "":
+			n.ActorName = n.Deal.CreatorName
+		}
+		return dealNotificationEmail(s.RenderContext(), n, s.dealLink(n.Deal.ID))
+	},
+	"receipt": func(s *EmailService, e Event, _ string, _ AccountContact) (EmailOptions, error) {
+		r, ok := e.Payload.(Receipt)
+		if !ok {
+			return EmailOptions{}, fmt.Errorf("%w: receipt needs a Receipt", ErrEventPayload)
+		}
+		return receiptEmail(s.RenderContext(), r), nil
+	},
+}
+
+// QueuedEmail is a rendered email held back by a rule's delay
+type QueuedEmail struct {
+	// ID is unique per event, rule and recipient, so handling an event
+	// twice doesn't queue it twice
+	ID      string
+	Channel string
+	Message EmailOptions
+	DueAt   time.Time
+}
+
+// EmailQueue persists delayed emails
+type EmailQueue interface {
+	// Enqueue adds q, replacing a queued email with the same ID
+	Enqueue(q QueuedEmail) error
+	// Due returns the emails due at or before now, ordered by DueAt
+	Due(now time.Time) ([]QueuedEmail, error)
+	// Delete removes a delivered email
+	Delete(id string) error
+}
+
+// EventEmailerConfig configures an event emailer
+type EventEmailerConfig struct {
+	// Rules default to DefaultEmailRules
+	Rules []EmailRule
+	// Templates are added to the built-in "welcome", "deal_notification"
+	// and "receipt" templates, replacing those of the same name
+	Templates map[string]EventTemplate
+	// Channels are added to ChannelEmail, e.g. to post to a digest or chat
+	Channels map[string]ChannelFunc
+	// Queue holds delayed emails. Defaults to an in-memory queue.
+	Queue EmailQueue
+}
+
+// withDefaults fills in zero values
+func (c EventEmailerConfig) withDefaults() EventEmailerConfig {
+	if c.Rules == nil {
+		c.Rules = DefaultEmailRules()
+	}
+	if c.Queue == nil {
+		c.Queue = NewMemoryEmailQueue()
+	}
+	return c
+}
+
+// EventEmailer turns published events into emails by its rules. Flush is
+// meant to be called periodically, e.g. every minute from a background
+// job, to send delayed emails.
+type EventEmailer struct {
+	email     *EmailService
+	rules     map[string][]EmailRule // by event type
+	templates map[string]EventTemplate
+	channels  map[string]ChannelFunc
+	queue     EmailQueue
+}
+
+// NewEventEmailer creates an event emailer that renders and sends through
+// email. It fails with ErrInvalidEmailRule if a rule names a template or
+// channel that doesn't exist.
+func NewEventEmailer(email *EmailService, config EventEmailerConfig) (*EventEmailer, error) {
+	config = config.withDefaults()
+	e := &EventEmailer{
+		email:     email,
+		rules:     map[string][]EmailRule{},
+		templates: map[string]EventTemplate{},
+		channels:  map[string]ChannelFunc{ChannelEmail: email.SendEmail},
+		queue:     config.Queue,
+	}
+	for name, t := range eventTemplates {
+		e.templates[name] = t
+	}
+	for name, t := range config.Templates {
+		e.templates[name] = t
+	}
+	for name, c := range config.Channels {
+		e.channels[name] = c
+	}
+
+	for i, rule := range config.Rules {
+		if rule.Channel == "" {
+			rule.Channel = ChannelEmail
+		}
+		if _, ok := e.templates[rule.Template]; !ok {
+			return nil, fmt.Errorf("%w: rule %d uses unknown template %q", ErrInvalidEmailRule, i+1, rule.Template)
+		}
+		if _, ok := e.channels[rule.Channel]; !ok {
+			return nil, fmt.Errorf("%w: rule %d uses unknown channel %q", ErrInvalidEmailRule, i+1, rule.Channel)
+		}
+		e.rules[rule.Event] = append(e.rules[rule.Event], rule)
+	}
+	return e, nil
+}
+
+// Subscribe registers the emailer on bus for every event type it has rules
+// for
+func (e *EventEmailer) Subscribe(bus EventBus) {
+	types := make([]string, 0, len(e.rules))
+	for t := range e.rules {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		bus.Subscribe(t, e.Handle)
+	}
+}
+
+// Handle applies the rules of ev.Type: every recipient gets the rule's
+// email, right away or queued for Flush. A failure for one recipient
+// doesn't stop the others.
+func (e *EventEmailer) Handle(ev Event) error {
+	if ev.ID == "" {
+		id := make([]byte, 8)
+		if _, err := rand.Read(id); err != nil {
+			return fmt.Errorf("failed to generate event id: %w", err)
+		}
+		ev.ID = hex.EncodeToString(id)
+	}
+	if ev.OccurredAt.IsZero() {
+		ev.OccurredAt = e.email.clock.Now()
+	}
+
+	var errs []error
+	for i, rule := range e.rules[ev.Type] {
+		for _, role := range rule.To {
+			if err := e.apply(ev, rule, role, fmt.Sprintf("%s/%d/%s", ev.ID, i, role)); err != nil {
+				errs = append(errs, fmt.Errorf("%s to %s: %w", ev.Type, role, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// apply renders one rule for one recipient and sends or queues it
+func (e *EventEmailer) apply(ev Event, rule EmailRule, role, id string) error {
+	to, ok := ev.Recipients[role]
+	if !ok || to.Email == "" {
+		return fmt.Errorf("%w %s", ErrNoRecipient, role)
+	}
+	msg, err := e.templates[rule.Template](e.email, ev, role, to)
+	if err != nil {
+		return err
+	}
+	msg.To = to.Email
+
+	if rule.Delay <= 0 {
+		return e.channels[rule.Channel](msg)
+	}
+	err = e.queue.Enqueue(QueuedEmail{
+		ID:      id,
+		Channel: rule.Channel,
+		Message: msg,
+		DueAt:   ev.OccurredAt.Add(rule.Delay),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to queue email: %w", err)
+	}
+	return nil
+}
+
+// Flush sends the delayed emails that are due and returns how many were
+// sent. An email that fails stays queued and is retried by the next Flush.
+func (e *EventEmailer) Flush() (int, error) {
+	due, err := e.queue.Due(e.email.clock.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to list queued emails: %w", err)
+	}
+
+	sent := 0
+	var errs []error
+	for _, q := range due {
+		channel, ok := e.channels[q.Channel]
+		if !ok {
+			errs = append(errs, fmt.Errorf("queued email %s: unknown channel %q", q.ID, q.Channel))
+			continue
+		}
+		if err := channel(q.Message); err != nil {
+			errs = append(errs, fmt.Errorf("queued email %s: %w", q.ID, err))
+			continue
+		}
+		sent++
+		if err := e.queue.Delete(q.ID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to delete queued email %s: %w", q.ID, err))
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// MemoryEmailQueue is an in-process EmailQueue. Queued emails are lost on
+// restart.
+type MemoryEmailQueue struct {
+	mu     sync.Mutex
+	queued map[string]QueuedEmail
+}
+
+// NewMemoryEmailQueue creates an empty in-memory email queue
+func NewMemoryEmailQueue() *MemoryEmailQueue {
+	return &MemoryEmailQueue{queued: map[string]QueuedEmail{}}
+}
+
+// Enqueue adds or replaces a queued email
+func (m *MemoryEmailQueue) Enqueue(q QueuedEmail) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queued[q.ID] = q
+	return nil
+}
+
+// Due returns the emails due at or before now, ordered by DueAt and ID
+func (m *MemoryEmailQueue) Due(now time.Time) ([]QueuedEmail, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var due []QueuedEmail
+	for _, q := range m.queued {
+		if !q.DueAt.After(now) {
+			due = append(due, q)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].DueAt.Equal(due[j].DueAt) {
+			return due[i].DueAt.Before(due[j].DueAt)
+		}
+		return due[i].ID < due[j].ID
+	})
+	return due, nil
+}
+
+// Delete removes a queued email
+func (m *MemoryEmailQueue) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.queued, id)
+	return nil
+}
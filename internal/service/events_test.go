@@ -0,0 +1,216 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// subscribedEmailer creates an event emailer for config listening on a new bus
+func subscribedEmailer(t *testing.T, email *EmailService, config EventEmailerConfig) (*EventEmailer, *MemoryEventBus) {
+	t.Helper()
+	emailer, err := NewEventEmailer(email, config)
+	if err != nil {
+		t.Fatalf("NewEventEmailer() error = %v", err)
+	}
+	bus := NewMemoryEventBus()
+	emailer.Subscribe(bus)
+	return emailer, bus
+}
+
+func TestEventEmailer_DefaultRules(t *testing.T) {
+	email, _, transport := newTestEmailService(t)
+	_, bus := subscribedEmailer(t, email, EventEmailerConfig{})
+
+	err := bus.Publish(Event{
+		Type:       EventUserRegistered,
+		Recipients: map[string]AccountContact{"user": {UserID: "u1", Email: "jane@example.com", Name: "Jane"}},
+	})
+	if err != nil {
+		t.Fatalf("Publish(UserRegistered) error = %v", err)
+	}
+	if msg := transport.last(); msg.To != "jane@example.com" || msg.Subject != "Welcome to Sponsoration!" {
+		t.Errorf("got %q to %s", msg.Subject, msg.To)
+	}
+
+	err = bus.Publish(Event{
+		Type: EventDealCompleted,
+		Recipients: map[string]AccountContact{
+			"creator": {Email: "jane@example.com"},
+			"brand":   {Email: "maria@acme.example"},
+		},
+		Payload: DealNotification{Event: DealCompleted, Deal: Deal{ID: "d1", Title: "Spring Trail", BrandName: "Acme", CreatorName: "Jane"}},
+	})
+	if err != nil {
+		t.Fatalf("Publish(DealCompleted) error = %v", err)
+	}
+	sent := transport.messages()
+	if len(sent) != 3 || sent[1].To != "jane@example.com" || sent[2].To != "maria@acme.example" {
+		t.Fatalf("sent %d emails, want one to each party", len(sent))
+	}
+	if sent[2].Subject != "Deal completed: Spring Trail" {
+		t.Errorf("Subject = %q", sent[2].Subject)
+	}
+	// Each party is told about the other one
+	if !strings.Contains(sent[1].Text, "The deal with Acme is complete") {
+		t.Errorf("creator email = %q, want it to name the brand", sent[1].Text)
+	}
+	if !strings.Contains(sent[2].Text, "The deal with Jane is complete") {
+		t.Errorf("brand email = %q, want it to name the creator", sent[2].Text)
+	}
+
+	// Events without rules are ignored
+	if err := bus.Publish(Event{Type: "ProfileViewed"}); err != nil {
+		t.Errorf("Publish(ProfileViewed) error = %v", err)
+	}
+}
+
+func TestEventEmailer_Errors(t *testing.T) {
+	email, _, transport := newTestEmailService(t)
+	_, bus := subscribedEmailer(t, email, EventEmailerConfig{})
+
+	err := bus.Publish(Event{
+		Type: EventDealCompleted,
+		Recipients: map[string]AccountContact{
+			"creator": {Email: "jane@example.com"},
+		},
+		Payload: DealNotification{Event: DealCompleted, Deal: Deal{ID: "d1", Title: "Spring Trail"}},
+	})
+	if !errors.Is(err, ErrNoRecipient) {
+		t.Errorf("Publish() without a brand error = %v, want ErrNoRecipient", err)
+	}
+	if len(transport.messages()) != 1 {
+		t.Errorf("the creator should still get the email")
+	}
+
+	err = bus.Publish(Event{
+		Type:       EventPaymentReceived,
+		Recipients: map[string]AccountContact{"payer": {Email: "maria@acme.example"}},
+		Payload:    "not a receipt",
+	})
+	if !errors.Is(err, ErrEventPayload) {
+		t.Errorf("Publish() with a wrong payload error = %v, want ErrEventPayload", err)
+	}
+
+	err = bus.Publish(Event{
+		Type:       EventDeliverableApproved,
+		Recipients: map[string]AccountContact{"creator": {Email: "jane@example.com"}},
+		Payload:    DealNotification{Event: DealDeclined, Deal: Deal{ID: "d1", Title: "Spring Trail"}},
+	})
+	if !errors.Is(err, ErrEventPayload) {
+		t.Errorf("Publish() with a payload for another event error = %v, want ErrEventPayload", err)
+	}
+
+	// A payload without an event follows the event type
+	err = bus.Publish(Event{
+		Type:       EventDeliverableApproved,
+		Recipients: map[string]AccountContact{"creator": {Email: "jane@example.com"}},
+		Payload:    DealNotification{Deal: Deal{ID: "d1", Title: "Spring Trail", BrandName: "Acme"}},
+	})
+	if err != nil {
+		t.Fatalf("Publish() without a payload event error = %v", err)
+	}
+	if msg := transport.last(); msg.Subject != "Deliverable approved: Spring Trail" {
+		t.Errorf("Subject = %q", msg.Subject)
+	}
+}
+
+func TestEventEmailer_DelayAndChannels(t *testing.T) {
+	var posted []string
+	rules, err := ParseEmailRules([]byte(`{"rules": [
+		{"event": "DealAccepted", "template": "deal_notification", "to": ["counterparty"], "delay": "5m"},
+		{"event": "DealAccepted", "template": "deal_notification", "to": ["counterparty"], "channel": "chat"}
+	]}`))
+	if err != nil {
+		t.Fatalf("ParseEmailRules() error = %v", err)
+	}
+	email, clock, transport := newTestEmailService(t)
+	emailer, bus := subscribedEmailer(t, email, EventEmailerConfig{
+		Rules: rules,
+		Channels: map[string]ChannelFunc{"chat": func(msg EmailOptions) error {
+			posted = append(posted, msg.To+": "+msg.Subject)
+			return nil
+		}},
+	})
+
+	ev := Event{
+		Type:       EventDealAccepted,
+		ID:         "evt-1",
+		Recipients: map[string]AccountContact{"counterparty": {Email: "maria@acme.example"}},
+		Payload:    DealNotification{Event: DealAccepted, Deal: Deal{ID: "d1", Title: "Spring Trail"}, ActorName: "Jane"},
+	}
+	// Handling the same event twice queues its email once
+	for i := 0; i < 2; i++ {
+		if err := bus.Publish(ev); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+	if len(posted) != 2 || posted[0] != "maria@acme.example: Deal accepted: Spring Trail" {
+		t.Errorf("chat channel got %q", posted)
+	}
+	if sent, _ := emailer.Flush(); sent != 0 || len(transport.messages()) != 0 {
+		t.Fatal("delayed email was sent early")
+	}
+
+	clock.Advance(5 * time.Minute)
+	if sent, err := emailer.Flush(); sent != 1 || err != nil {
+		t.Fatalf("Flush() = %d, %v, want 1", sent, err)
+	}
+	if sent, _ := emailer.Flush(); sent != 0 {
+		t.Error("delayed email was sent twice")
+	}
+}
+
+func TestParseEmailRules(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"not JSON", `rules:`},
+		{"missing template", `{"rules": [{"event": "UserRegistered", "to": ["user"]}]}`},
+		{"no recipients", `{"rules": [{"event": "UserRegistered", "template": "welcome"}]}`},
+		{"bad delay", `{"rules": [{"event": "UserRegistered", "template": "welcome", "to": ["user"], "delay": "soon"}]}`},
+		{"negative delay", `{"rules": [{"event": "UserRegistered", "template": "welcome", "to": ["user"], "delay": "-1m"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseEmailRules([]byte(tt.data)); err == nil {
+				t.Error("ParseEmailRules() error = nil")
+			}
+		})
+	}
+
+	// Rules are checked against the templates and channels
+	email := NewEmailService(WithTransport(&recordingTransport{}))
+	for _, rule := range []EmailRule{
+		{Event: "X", Template: "missing", To: []string{"user"}},
+		{Event: "X", Template: "welcome", To: []string{"user"}, Channel: "sms"},
+	} {
+		if _, err := NewEventEmailer(email, EventEmailerConfig{Rules: []EmailRule{rule}}); !errors.Is(err, ErrInvalidEmailRule) {
+			t.Errorf("NewEventEmailer(%+v) error = %v, want ErrInvalidEmailRule", rule, err)
+		}
+	}
+}
+
+func TestLoadEmailRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	data := `{"rules": [{"event": "UserRegistered", "template": "welcome", "to": ["user"], "delay": "10m"}]}`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := LoadEmailRules(path)
+	if err != nil {
+		t.Fatalf("LoadEmailRules() error = %v", err)
+	}
+	want := EmailRule{Event: "UserRegistered", Template: "welcome", To: []string{"user"}, Delay: 10 * time.Minute, Channel: ChannelEmail}
+	if len(rules) != 1 || !slices.Equal(rules[0].To, want.To) || rules[0].Event != want.Event || rules[0].Delay != want.Delay || rules[0].Channel != want.Channel {
+		t.Errorf("LoadEmailRules() = %+v, want %+v", rules, want)
+	}
+	if len(DefaultEmailRules()) == 0 {
+		t.Error("DefaultEmailRules() is empty")
+	}
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "regenerate golden files in testdata/golden")
+
+// goldenTime is the frozen instant used when rendering snapshots
+var goldenTime = time.Date(2025, time.March, 14, 9, 30, 0, 0, time.UTC)
+
+// goldenContext renders snapshots with the frozen clock and default branding
+var goldenContext = RenderContext{Now: goldenTime, Branding: DefaultBranding()}
+
+// testRenderContext renders with the wall clock and default branding
+func testRenderContext() RenderContext {
+	return RenderContext{Now: time.Now(), Branding: DefaultBranding()}
+}
+
+// assertGolden compares got with testdata/golden/<name>, rewriting the file
+// instead when the -update flag is set
+func assertGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", "golden", name)
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("create golden dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("write golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file (run with -update to create it): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s does not match rendered output (run with -update to accept):\n--- got ---\n%s\n--- want ---\n%s", path, got, want)
+	}
+}
+
+// assertGoldenEmail snapshots the HTML body as <name>.html and the subject
+// plus text body as <name>.txt
+func assertGoldenEmail(t *testing.T, name string, msg EmailOptions) {
+	t.Helper()
+	assertGolden(t, name+".html", msg.HTML)
+	assertGolden(t, name+".txt", "Subject: "+msg.Subject+"\n\n"+msg.Text+"\n")
+}
+
+func TestEmailGolden(t *testing.T) {
+	for _, tmpl := range Templates() {
+		t.Run(tmpl.Name, func(t *testing.T) {
+			assertGoldenEmail(t, tmpl.Name, tmpl.Sample(goldenContext))
+		})
+	}
+
+	// Variants not covered by the registry samples
+	t.Run("password_reset_no_name", func(t *testing.T) {
+		assertGoldenEmail(t, "password_reset_no_name", passwordResetEmail(goldenContext, "RESET456", "Hello,", "", time.Hour))
+	})
+	t.Run("verification_autofill", func(t *testing.T) {
+		rc := goldenContext
+		rc.OTPAutofill = OTPAutofill{Domain: "app.example.com", Templates: []string{"verification"}}
+		assertGoldenEmail(t, "verification_autofill", verificationEmail(rc, "ABC123", "", 24*time.Hour))
+	})
+	t.Run("meeting_updated", func(t *testing.T) {
+		m := sampleMeeting()
+		m.Sequence = 1
+		m.Start = m.Start.Add(time.Hour)
+		m.End = m.End.Add(time.Hour)
+		assertGoldenEmail(t, "meeting_updated", meetingInviteEmail(goldenContext, m))
+	})
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingTransport captures sent emails instead of delivering them
+type recordingTransport struct {
+	mu   sync.Mutex
+	sent []EmailOptions
+	err  error
+}
+
+// Send records opts, or fails with the configured error
+func (r *recordingTransport) Send(opts EmailOptions) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	r.sent = append(r.sent, opts)
+	return nil
+}
+
+// messages returns a copy of the emails sent so far
+func (r *recordingTransport) messages() []EmailOptions {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]EmailOptions(nil), r.sent...)
+}
+
+// last returns the most recently sent email
+func (r *recordingTransport) last() EmailOptions {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.sent) == 0 {
+		return EmailOptions{}
+	}
+	return r.sent[len(r.sent)-1]
+}
+
+// newTestEmailService returns an email service on a fake clock at goldenTime
+// that records what it sends. APP_URL is https://app.example.com, and opts
+// are applied on top.
+func newTestEmailService(t *testing.T, opts ...EmailServiceOption) (*EmailService, *FakeClock, *recordingTransport) {
+	t.Setenv("APP_URL", "https://app.example.com")
+	clock := NewFakeClock(goldenTime)
+	transport := &recordingTransport{}
+	opts = append([]EmailServiceOption{WithClock(clock), WithTransport(transport)}, opts...)
+	return NewEmailService(opts...), clock, transport
+}
+
+// newTestTokens returns a token service on clock that signs with one test key
+func newTestTokens(clock Clock) *TokenService {
+	return NewTokenService(NewKeyring(testKey("k1")), NewMemoryUsedTokenStore(), clock)
+}
+
+// sentLinkToken extracts the token of the link to path in the plain-text part
+func sentLinkToken(t *testing.T, msg EmailOptions, path string) string {
+	t.Helper()
+	_, token, ok := strings.Cut(msg.Text, path+"?token=")
+	if !ok {
+		t.Fatalf("no %s link in %q", path, msg.Text)
+	}
+	return strings.Fields(token)[0]
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"time"
+)
+
+// ErrInvitationInvalid is returned when sending an invitation without an ID
+// or a valid address, and for a token whose content doesn't describe an
+// invitation
+var ErrInvitationInvalid = errors.New("invitation token invalid")
+
+// Invitation token purposes. The accept and decline links carry separate
+// tokens, so one can't be used for the other.
+const (
+	PurposeInvitationAccept  TokenPurpose = "invitation_accept"
+	PurposeInvitationDecline TokenPurpose = "invitation_decline"
+)
+
+// InvitationConfig configures invitations
+type InvitationConfig struct {
+	// TTL is how long an invitation can be answered. Defaults to 7 days.
+	TTL time.Duration
+}
+
+// withDefaults fills in zero values
+func (c InvitationConfig) withDefaults() InvitationConfig {
+	if c.TTL <= 0 {
+		c.TTL = 7 * 24 * time.Hour
+	}
+	return c
+}
+
+// Invitation asks someone to join an organization, such as a sponsor's brand
+// workspace or a creator's management team
+type Invitation struct {
+	// ID is the caller's identifier of the invitation
+	ID           string
+	Email        string
+	InviterName  string
+	Organization string
+	Role         string
+
+	// TokenID and ExpiresAt identify the pending accept and decline links.
+	// They are set by InvitationService and must be stored to resend or
+	// revoke them.
+	TokenID   string
+	ExpiresAt time.Time
+}
+
+// invitationSubject is the token subject of an invitation
+type invitationSubject struct {
+	ID string `json:"iid"`
+	// Answer is the use ID shared by the accept and decline tokens, so
+	// answering one way burns the other link too
+	Answer       string `json:"ans"`
+	Email        string `json:"email"`
+	Organization string `json:"org"`
+	Role         string `json:"role"`
+}
+
+// InvitationService sends invitations with signed accept and decline links.
+// Only one link per invitation works at a time: resending revokes the
+// previous one.
+type InvitationService struct {
+	email  *EmailService
+	tokens *TokenService
+	config InvitationConfig
+}
+
+// NewInvitationService creates an invitation service that signs links with
+// tokens and sends them through email
+func NewInvitationService(email *EmailService, tokens *TokenService, config InvitationConfig) *InvitationService {
+	return &InvitationService{
+		email:  email,
+		tokens: tokens,
+		config: config.withDefaults(),
+	}
+}
+
+// Send emails inv and returns it with the new links' TokenID and ExpiresAt.
+// It fails with ErrInvitationInvalid when inv has no ID or a bad address.
+func (i *InvitationService) Send(inv Invitation) (Invitation, error) {
+	if inv.ID == "" {
+		return Invitation{}, fmt.Errorf("%w: missing ID", ErrInvitationInvalid)
+	}
+	if _, err := mail.ParseAddress(inv.Email); err != nil {
+		return Invitation{}, fmt.Errorf("%w: bad address %q", ErrInvitationInvalid, inv.Email)
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return Invitation{}, fmt.Errorf("failed to issue invitation token: %w", err)
+	}
+	answerID := base64.RawURLEncoding.EncodeToString(nonce)
+	raw, err := json.Marshal(invitationSubject{
+		ID:           inv.ID,
+		Answer:       answerID,
+		Email:        normalizeAddress(inv.Email),
+		Organization: inv.Organization,
+		Role:         inv.Role,
+	})
+	if err != nil {
+		return Invitation{}, fmt.Errorf("failed to encode invitation: %w", err)
+	}
+	accept, err := i.tokens.Issue(PurposeInvitationAccept, string(raw), i.config.TTL)
+	if err != nil {
+		return Invitation{}, fmt.Errorf("failed to issue invitation token: %w", err)
+	}
+	decline, err := i.tokens.Issue(PurposeInvitationDecline, string(raw), i.config.TTL)
+	if err != nil {
+		return Invitation{}, fmt.Errorf("failed to issue invitation token: %w", err)
+	}
+	claims, err := i.tokens.Parse(accept, PurposeInvitationAccept)
+	if err != nil {
+		return Invitation{}, fmt.Errorf("failed to issue invitation token: %w", err)
+	}
+
+	inv.TokenID = answerID
+	inv.ExpiresAt = claims.ExpiresAt
+	if err := i.email.SendInvitationEmail(inv, accept, decline, i.config.TTL); err != nil {
+		return Invitation{}, err
+	}
+	return inv, nil
+}
+
+// Resend revokes the pending link of inv and emails a fresh one
+func (i *InvitationService) Resend(inv Invitation) (Invitation, error) {
+	if err := i.Revoke(inv); err != nil {
+		return Invitation{}, err
+	}
+	return i.Send(inv)
+}
+
+// Revoke makes the pending links of inv unusable
+func (i *InvitationService) Revoke(inv Invitation) error {
+	if inv.TokenID == "" {
+		return nil
+	}
+	return i.tokens.Revoke(inv.TokenID, inv.ExpiresAt)
+}
+
+// Accept consumes an invitation token from the accept link and returns the
+// invitation to fulfil. Errors are the token errors of TokenService; revoked
+// or answered invitations fail with ErrTokenUsed and a decline token fails
+// with ErrTokenPurpose.
+func (i *InvitationService) Accept(token string) (Invitation, error) {
+	return i.answer(token, PurposeInvitationAccept)
+}
+
+// Decline consumes an invitation token from the decline link, so the
+// invitation can no longer be accepted, and returns the declined invitation
+func (i *InvitationService) Decline(token string) (Invitation, error) {
+	return i.answer(token, PurposeInvitationDecline)
+}
+
+// answer checks an invitation token issued for purpose and records the
+// answer, which uses up both links
+func (i *InvitationService) answer(token string, purpose TokenPurpose) (Invitation, error) {
+	claims, err := i.tokens.Parse(token, purpose)
+	if err != nil {
+		return Invitation{}, err
+	}
+	var subject invitationSubject
+	if err := json.Unmarshal([]byte(claims.Subject), &subject); err != nil || subject.ID == "" || subject.Answer == "" {
+		return Invitation{}, ErrInvitationInvalid
+	}
+	if err := i.tokens.useID(subject.Answer, claims.ExpiresAt); err != nil {
+		return Invitation{}, err
+	}
+	return Invitation{
+		ID:           subject.ID,
+		Email:        subject.Email,
+		Organization: subject.Organization,
+		Role:         subject.Role,
+		TokenID:      subject.Answer,
+		ExpiresAt:    claims.ExpiresAt,
+	}, nil
+}
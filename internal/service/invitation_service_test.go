@@ -0,0 +1,170 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testInvitation = Invitation{
+	ID:           "inv-1",
+	Email:        "Sam@Example.com",
+	InviterName:  "Maria Lopez",
+	Organization: "Acme Outdoor",
+	Role:         "Campaign Manager",
+}
+
+func TestInvitationService_SendAndAccept(t *testing.T) {
+	email, clock, transport := newTestEmailService(t)
+	i := NewInvitationService(email, newTestTokens(clock), InvitationConfig{})
+
+	inv, err := i.Send(testInvitation)
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	if inv.TokenID == "" || !inv.ExpiresAt.Equal(clock.Now().Add(7*24*time.Hour)) {
+		t.Errorf("Send() = %+v, want the token ID and a 7 day expiry", inv)
+	}
+
+	msg := transport.last()
+	if msg.To != "Sam@Example.com" || msg.Subject != "Maria Lopez invited you to join Acme Outdoor on Sponsoration" {
+		t.Errorf("sent %q to %q", msg.Subject, msg.To)
+	}
+	for _, want := range []string{"Maria Lopez", "Acme Outdoor", "Campaign Manager", "7 days", "Decline the invitation"} {
+		if !strings.Contains(msg.HTML, want) {
+			t.Errorf("HTML should contain %q", want)
+		}
+	}
+
+	accepted, err := i.Accept(sentLinkToken(t, msg, "/invitations/accept"))
+	if err != nil {
+		t.Fatalf("Accept() error = %v", err)
+	}
+	if accepted.ID != "inv-1" || accepted.Email != "sam@example.com" || accepted.Role != "Campaign Manager" {
+		t.Errorf("Accept() = %+v", accepted)
+	}
+}
+
+func TestInvitationService_Answers(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(i *InvitationService, clock *FakeClock, inv Invitation, decline string)
+		wantErr error
+	}{
+		{
+			name: "declined",
+			prepare: func(i *InvitationService, clock *FakeClock, inv Invitation, decline string) {
+				_, _ = i.Decline(decline)
+			},
+			wantErr: ErrTokenUsed,
+		},
+		{
+			name: "revoked",
+			prepare: func(i *InvitationService, clock *FakeClock, inv Invitation, decline string) {
+				_ = i.Revoke(inv)
+			},
+			wantErr: ErrTokenUsed,
+		},
+		{
+			name: "expired",
+			prepare: func(i *InvitationService, clock *FakeClock, inv Invitation, decline string) {
+				clock.Advance(7 * 24 * time.Hour)
+			},
+			wantErr: ErrTokenExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email, clock, transport := newTestEmailService(t)
+			i := NewInvitationService(email, newTestTokens(clock), InvitationConfig{})
+			inv, err := i.Send(testInvitation)
+			if err != nil {
+				t.Fatalf("Send() error = %v", err)
+			}
+			token := sentLinkToken(t, transport.last(), "/invitations/accept")
+
+			tt.prepare(i, clock, inv, sentLinkToken(t, transport.last(), "/invitations/decline"))
+
+			if _, err := i.Accept(token); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Accept() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInvitationService_ResendRevokesPreviousLink(t *testing.T) {
+	email, clock, transport := newTestEmailService(t)
+	i := NewInvitationService(email, newTestTokens(clock), InvitationConfig{})
+
+	inv, _ := i.Send(testInvitation)
+	first := sentLinkToken(t, transport.last(), "/invitations/accept")
+
+	clock.Advance(6 * 24 * time.Hour)
+	resent, err := i.Resend(inv)
+	if err != nil {
+		t.Fatalf("Resend() error = %v", err)
+	}
+	if resent.TokenID == inv.TokenID || !resent.ExpiresAt.After(inv.ExpiresAt) {
+		t.Errorf("Resend() = %+v, want a new link with a later expiry", resent)
+	}
+	second := sentLinkToken(t, transport.last(), "/invitations/accept")
+
+	if _, err := i.Accept(first); !errors.Is(err, ErrTokenUsed) {
+		t.Errorf("Accept(old link) error = %v, want ErrTokenUsed", err)
+	}
+	if _, err := i.Accept(second); err != nil {
+		t.Errorf("Accept(new link) error = %v", err)
+	}
+}
+
+func TestInvitationService_DeclineLink(t *testing.T) {
+	email, clock, transport := newTestEmailService(t)
+	i := NewInvitationService(email, newTestTokens(clock), InvitationConfig{})
+
+	if _, err := i.Send(testInvitation); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	msg := transport.last()
+	accept := sentLinkToken(t, msg, "/invitations/accept")
+	decline := sentLinkToken(t, msg, "/invitations/decline")
+	if accept == decline {
+		t.Fatal("accept and decline links should carry different tokens")
+	}
+
+	// Each link only answers its own way
+	if _, err := i.Accept(decline); !errors.Is(err, ErrTokenPurpose) {
+		t.Errorf("Accept(decline token) error = %v, want ErrTokenPurpose", err)
+	}
+	if _, err := i.Decline(accept); !errors.Is(err, ErrTokenPurpose) {
+		t.Errorf("Decline(accept token) error = %v, want ErrTokenPurpose", err)
+	}
+
+	declined, err := i.Decline(decline)
+	if err != nil || declined.ID != "inv-1" {
+		t.Fatalf("Decline() = %+v, %v", declined, err)
+	}
+	if _, err := i.Accept(accept); !errors.Is(err, ErrTokenUsed) {
+		t.Errorf("Accept() after Decline() error = %v, want ErrTokenUsed", err)
+	}
+}
+
+func TestInvitationService_SendValidates(t *testing.T) {
+	email, clock, transport := newTestEmailService(t)
+	i := NewInvitationService(email, newTestTokens(clock), InvitationConfig{})
+
+	noID := testInvitation
+	noID.ID = ""
+	badEmail := testInvitation
+	badEmail.Email = "not an address"
+
+	for _, inv := range []Invitation{noID, badEmail} {
+		if _, err := i.Send(inv); !errors.Is(err, ErrInvitationInvalid) {
+			t.Errorf("Send(%+v) error = %v, want ErrInvitationInvalid", inv, err)
+		}
+	}
+	if n := len(transport.messages()); n != 0 {
+		t.Errorf("sent %d emails, want none", n)
+	}
+}
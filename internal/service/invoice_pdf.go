@@ -0,0 +1,225 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/sponsoration/api/internal/pdf"
+)
+
+// Invoice layout in points on an A4 page
+const (
+	invoiceMargin      = 50.0
+	invoiceHeader      = 90.0
+	invoiceRight       = pdf.PageWidth - invoiceMargin
+	invoiceLogoHeight  = 40.0
+	invoiceLogoWidth   = 180.0
+	invoiceRowHeight   = 22.0
+	invoicePageBottom  = pdf.PageHeight - 90
+	invoiceDescription = 280.0 // width of the description column
+)
+
+// Right edges of the numeric invoice columns
+const (
+	invoiceQtyColumn    = 370.0
+	invoicePriceColumn  = 460.0
+	invoiceAmountColumn = invoiceRight - 8
+)
+
+// invoiceColors are the palette tokens the invoice is drawn with
+type invoiceColors struct {
+	accent, onAccent, heading, text, muted, border, shade pdf.Color
+}
+
+// newInvoiceColors parses the light palette, documents have no dark mode
+func newInvoiceColors(p Palette) (invoiceColors, error) {
+	var c invoiceColors
+	for _, token := range []struct {
+		dst   *pdf.Color
+		value string
+	}{
+		{&c.accent, p.Primary},
+		{&c.onAccent, p.OnAccent},
+		{&c.heading, p.Heading},
+		{&c.text, p.Text},
+		{&c.muted, p.Muted},
+		{&c.border, p.Border},
+		{&c.shade, p.CodeBox},
+	} {
+		color, err := pdf.ParseHexColor(token.value)
+		if err != nil {
+			return c, fmt.Errorf("failed to parse branding color: %w", err)
+		}
+		*token.dst = color
+	}
+	return c, nil
+}
+
+// invoicePDF draws the invoice of a receipt: the brand header with the logo,
+// invoice details, billing address, line items and totals. Long item lists
+// continue on further pages.
+func invoicePDF(rc RenderContext, r Receipt) ([]byte, error) {
+	colors, err := newInvoiceColors(rc.Branding.Theme.Light)
+	if err != nil {
+		return nil, err
+	}
+	money := func(amount int64) string {
+		return formatMoney(Money{Amount: amount, Currency: r.Currency}, rc.Locale)
+	}
+
+	doc := pdf.New()
+	doc.SetTitle("Invoice " + r.InvoiceNumber)
+	page := doc.AddPage()
+
+	// Header band with the logo, or the brand name when there is none
+	page.FillRect(0, 0, pdf.PageWidth, invoiceHeader, colors.accent)
+	if logo := rc.Branding.Logo; logo != nil && !logo.Bounds().Empty() {
+		b := logo.Bounds()
+		h := invoiceLogoHeight
+		w := h * float64(b.Dx()) / float64(b.Dy())
+		if w > invoiceLogoWidth {
+			w = invoiceLogoWidth
+			h = w * float64(b.Dy()) / float64(b.Dx())
+		}
+		page.DrawImage(doc.AddImage(logo), invoiceMargin, (invoiceHeader-h)/2, w, h)
+	} else {
+		page.Text(invoiceMargin, 54, pdf.HelveticaBold, 22, colors.onAccent, rc.Branding.Name)
+	}
+	page.TextRight(invoiceRight, 54, pdf.HelveticaBold, 22, colors.onAccent, "INVOICE")
+
+	// Billing address on the left, invoice details on the right
+	y := invoiceHeader + 45
+	page.Text(invoiceMargin, y, pdf.HelveticaBold, 9, colors.muted, "BILLED TO")
+	for i, line := range r.BillingAddress.Lines() {
+		font := pdf.Helvetica
+		if i == 0 {
+			font = pdf.HelveticaBold
+		}
+		page.Text(invoiceMargin, y+16+float64(i)*14, font, 10, colors.heading, line)
+	}
+	for i, d := range []detail{
+		{"Invoice number", r.InvoiceNumber},
+		{"Date", r.IssuedAt.Format("January 2, 2006")},
+		{"Paid with", r.PaymentMethod.String()},
+	} {
+		row := y + float64(i)*16
+		page.Text(340, row, pdf.Helvetica, 10, colors.muted, d.label)
+		page.TextRight(invoiceRight, row, pdf.HelveticaBold, 10, colors.heading, d.value)
+	}
+
+	// Line items
+	y += 120
+	tableHeader := func() {
+		page.FillRect(invoiceMargin, y, invoiceRight-invoiceMargin, invoiceRowHeight, colors.shade)
+		baseline := y + 14.5
+		page.Text(invoiceMargin+8, baseline, pdf.HelveticaBold, 9, colors.muted, "DESCRIPTION")
+		page.TextRight(invoiceQtyColumn, baseline, pdf.HelveticaBold, 9, colors.muted, "QTY")
+		page.TextRight(invoicePriceColumn, baseline, pdf.HelveticaBold, 9, colors.muted, "UNIT PRICE")
+		page.TextRight(invoiceAmountColumn, baseline, pdf.HelveticaBold, 9, colors.muted, "AMOUNT")
+		y += invoiceRowHeight
+	}
+	tableHeader()
+	for _, item := range r.Items {
+		lines := wrapText(item.Description, pdf.Helvetica, 10, invoiceDescription)
+		height := invoiceRowHeight + float64(len(lines)-1)*13
+		if y+height > invoicePageBottom {
+			page = doc.AddPage()
+			y = invoiceMargin
+			tableHeader()
+		}
+		baseline := y + 15
+		for i, line := range lines {
+			page.Text(invoiceMargin+8, baseline+float64(i)*13, pdf.Helvetica, 10, colors.heading, line)
+		}
+		page.TextRight(invoiceQtyColumn, baseline, pdf.Helvetica, 10, colors.text, strconv.FormatInt(item.Quantity, 10))
+		page.TextRight(invoicePriceColumn, baseline, pdf.Helvetica, 10, colors.text, money(item.UnitPrice))
+		page.TextRight(invoiceAmountColumn, baseline, pdf.Helvetica, 10, colors.heading, money(item.Amount()))
+		y += height
+		page.Line(invoiceMargin, y, invoiceRight, y, 0.5, colors.border)
+	}
+
+	// Totals, kept together on one page
+	totals := r.Totals()
+	rows := []detail{{"Subtotal", formatMoney(totals.Subtotal, rc.Locale)}}
+	for _, tax := range totals.Taxes {
+		rows = append(rows, detail{fmt.Sprintf("%s (%s)", tax.Name, formatRate(tax.Rate)), formatMoney(tax.Amount, rc.Locale)})
+	}
+	if y+float64(len(rows)+2)*18 > invoicePageBottom {
+		page = doc.AddPage()
+		y = invoiceMargin
+	}
+	y += 24
+	for _, row := range rows {
+		page.Text(340, y, pdf.Helvetica, 10, colors.text, row.label)
+		page.TextRight(invoiceAmountColumn, y, pdf.Helvetica, 10, colors.heading, row.value)
+		y += 18
+	}
+	page.Line(340, y-8, invoiceRight, y-8, 1, colors.heading)
+	y += 8
+	page.Text(340, y, pdf.HelveticaBold, 12, colors.heading, "Total paid")
+	page.TextRight(invoiceAmountColumn, y, pdf.HelveticaBold, 12, colors.accent, formatMoney(totals.Total, rc.Locale))
+
+	page.Text(invoiceMargin, pdf.PageHeight-50, pdf.Helvetica, 9, colors.muted,
+		fmt.Sprintf("Thank you for your business. © %d %s", r.IssuedAt.Year(), rc.Branding.Name))
+
+	return doc.Bytes(), nil
+}
+
+// wrapText breaks s into lines no wider than width, at spaces. A single
+// word wider than width gets a line of its own.
+func wrapText(s string, font pdf.Font, size, width float64) []string {
+	var lines []string
+	line := ""
+	word := ""
+	flush := func() {
+		if word == "" {
+			return
+		}
+		switch {
+		case line == "":
+			line = word
+		case pdf.TextWidth(font, size, line+" "+word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+		word = ""
+	}
+	for _, r := range s {
+		if r == ' ' {
+			flush()
+			continue
+		}
+		word += string(r)
+	}
+	flush()
+	if line != "" || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// InvoicePDF renders the invoice of a receipt as a PDF with the service
+// branding and locale
+func (s *EmailService) InvoicePDF(r Receipt) ([]byte, error) {
+	doc, err := invoicePDF(s.RenderContext(), r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render invoice %s: %w", r.InvoiceNumber, err)
+	}
+	return doc, nil
+}
+
+// SendReceiptWithInvoice sends the receipt email with its PDF invoice
+// attached
+func (s *EmailService) SendReceiptWithInvoice(email string, r Receipt) error {
+	doc, err := s.InvoicePDF(r)
+	if err != nil {
+		return err
+	}
+	return s.SendReceiptEmail(email, r, Attachment{
+		Filename:    r.InvoiceNumber + ".pdf",
+		ContentType: "application/pdf",
+		Content:     doc,
+	})
+}
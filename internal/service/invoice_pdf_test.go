@@ -0,0 +1,147 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sponsoration/api/internal/pdf"
+)
+
+// sampleLogo is a small two-tone mark
+func sampleLogo() image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, 12, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 12; x++ {
+			c := color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+			if x < 4 {
+				c = color.NRGBA{R: 16, G: 185, B: 129, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestInvoicePDFGolden(t *testing.T) {
+	doc, err := invoicePDF(goldenContext, sampleReceipt(goldenContext))
+	if err != nil {
+		t.Fatalf("invoicePDF: %v", err)
+	}
+	assertGolden(t, "invoice.pdf", string(doc))
+
+	rc := goldenContext
+	rc.Branding.Logo = sampleLogo()
+	doc, err = invoicePDF(rc, sampleReceipt(rc))
+	if err != nil {
+		t.Fatalf("invoicePDF with logo: %v", err)
+	}
+	assertGolden(t, "invoice_logo.pdf", string(doc))
+}
+
+func TestInvoicePDF_Content(t *testing.T) {
+	r := sampleReceipt(goldenContext)
+	doc, err := invoicePDF(goldenContext, r)
+	if err != nil {
+		t.Fatalf("invoicePDF: %v", err)
+	}
+	s := string(doc)
+	// Text is Windows-1252 with non-ASCII bytes escaped as octal
+	for _, want := range []string{
+		"(INV-2025-0042) Tj",
+		"(Sponsoration) Tj",
+		`(Bergstra\337e 12) Tj`,
+		`(\2003,500.00) Tj`,
+		"(VAT \\(19%\\)) Tj",
+		`(\2005,414.48) Tj`,
+		`(Visa \225\225\225\225 4242) Tj`,
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("invoice does not contain %q", want)
+		}
+	}
+	if strings.Contains(s, "/XObject") {
+		t.Error("invoice without a logo embeds an image")
+	}
+}
+
+func TestInvoicePDF_ManyItemsSpanPages(t *testing.T) {
+	r := sampleReceipt(goldenContext)
+	r.Items = nil
+	for i := 0; i < 60; i++ {
+		r.Items = append(r.Items, LineItem{Description: fmt.Sprintf("Story %d", i+1), Quantity: 1, UnitPrice: 1000})
+	}
+	doc, err := invoicePDF(goldenContext, r)
+	if err != nil {
+		t.Fatalf("invoicePDF: %v", err)
+	}
+	if !bytes.Contains(doc, []byte("/Count 3 ")) {
+		t.Error("60 line items should fill three pages")
+	}
+	if !bytes.Contains(doc, []byte("(Story 60) Tj")) {
+		t.Error("last line item is missing")
+	}
+}
+
+func TestInvoicePDF_InvalidBranding(t *testing.T) {
+	rc := goldenContext
+	rc.Branding.Theme.Light.Primary = "indigo"
+	if _, err := invoicePDF(rc, sampleReceipt(rc)); err == nil {
+		t.Error("expected an error for an invalid branding color")
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", []string{""}},
+		{"Instagram Story", []string{"Instagram Story"}},
+		{"Instagram Reel – Spring Trail Collection", []string{"Instagram Reel –", "Spring Trail", "Collection"}},
+		{"Supercalifragilistic", []string{"Supercalifragilistic"}},
+	}
+	for _, tt := range tests {
+		got := wrapText(tt.in, pdf.Helvetica, 10, 80)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("wrapText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSendReceiptWithInvoice(t *testing.T) {
+	s, _, transport := newTestEmailService(t)
+	r := sampleReceipt(s.RenderContext())
+
+	if err := s.SendReceiptWithInvoice("billing@acme.example", r); err != nil {
+		t.Fatalf("SendReceiptWithInvoice: %v", err)
+	}
+	msg := transport.last()
+	if len(msg.Attachments) != 1 {
+		t.Fatalf("got %d attachments, want 1", len(msg.Attachments))
+	}
+	a := msg.Attachments[0]
+	if a.Filename != "INV-2025-0042.pdf" || a.ContentType != "application/pdf" {
+		t.Errorf("attachment = %s (%s), want INV-2025-0042.pdf (application/pdf)", a.Filename, a.ContentType)
+	}
+	if !bytes.HasPrefix(a.Content, []byte("%PDF-")) {
+		t.Error("attachment is not a PDF")
+	}
+}
+
+func TestInvoicePDF_FooterYearIsIssueYear(t *testing.T) {
+	// Re-rendering last year's invoice keeps its footer
+	r := sampleReceipt(goldenContext)
+	r.IssuedAt = time.Date(2024, time.December, 30, 12, 0, 0, 0, time.UTC)
+	doc, err := invoicePDF(goldenContext, r)
+	if err != nil {
+		t.Fatalf("invoicePDF: %v", err)
+	}
+	if !bytes.Contains(doc, []byte(`\251 2024 Sponsoration`)) {
+		t.Error("footer should carry the year the invoice was issued")
+	}
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"fmt"
+	"time"
+)
+
+// MagicLoginConfig configures passwordless login links
+type MagicLoginConfig struct {
+	// TTL is how long a login link stays valid, as stated in the email.
+	// Defaults to 15 minutes.
+	TTL time.Duration
+	// Throttle, when set, limits how often links are sent per recipient and
+	// per fingerprint (see ThrottleMagicLogin)
+	Throttle *Throttle
+}
+
+// withDefaults fills in zero values
+func (c MagicLoginConfig) withDefaults() MagicLoginConfig {
+	if c.TTL <= 0 {
+		c.TTL = 15 * time.Minute
+	}
+	return c
+}
+
+// MagicLoginService sends passwordless login links and checks them. Links
+// are short-lived, single-use and only work from the device that asked for
+// them.
+type MagicLoginService struct {
+	email  *EmailService
+	tokens *TokenService
+	config MagicLoginConfig
+}
+
+// NewMagicLoginService creates a magic login service that signs links with
+// tokens and sends them through email
+func NewMagicLoginService(email *EmailService, tokens *TokenService, config MagicLoginConfig) *MagicLoginService {
+	return &MagicLoginService{
+		email:  email,
+		tokens: tokens,
+		config: config.withDefaults(),
+	}
+}
+
+// SendLink emails address a login link bound to fingerprint. The fingerprint
+// is any stable identifier of the requesting device, such as a device cookie
+// or the client IP, and must be presented again to Verify. Refused sends
+// return a *RateLimitError.
+func (m *MagicLoginService) SendLink(address, fingerprint string) error {
+	send := func() error {
+		token, err := m.tokens.IssueBound(PurposeMagicLogin, normalizeAddress(address), fingerprint, m.config.TTL)
+		if err != nil {
+			return fmt.Errorf("failed to issue login token: %w", err)
+		}
+		return m.email.SendMagicLoginEmail(address, token, m.config.TTL)
+	}
+	if m.config.Throttle == nil {
+		return send()
+	}
+	return m.config.Throttle.Do(ThrottleMagicLogin, address, fingerprint, send)
+}
+
+// Verify consumes a login token presented from the device identified by
+// fingerprint and returns the address to log in. Failures are
+// ErrTokenExpired, ErrTokenUsed, ErrTokenMismatch, or one of the other token
+// errors for tampered links.
+func (m *MagicLoginService) Verify(token, fingerprint string) (string, error) {
+	claims, err := m.tokens.ConsumeBound(token, PurposeMagicLogin, fingerprint)
+	if err != nil {
+		return "", err
+	}
+	return claims.Subject, nil
+}
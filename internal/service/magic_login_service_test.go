@@ -0,0 +1,107 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sentLoginToken extracts the token from the login link in the plain-text part
+func sentLoginToken(t *testing.T, msg EmailOptions) string {
+	t.Helper()
+	_, token, ok := strings.Cut(msg.Text, "/magic-login?token=")
+	if !ok {
+		t.Fatalf("no login link in %q", msg.Text)
+	}
+	return strings.Fields(token)[0]
+}
+
+func TestMagicLoginService_SendAndVerify(t *testing.T) {
+	email, clock, transport := newTestEmailService(t)
+	m := NewMagicLoginService(email, newTestTokens(clock), MagicLoginConfig{})
+
+	if err := m.SendLink("User@Example.com", "device-1"); err != nil {
+		t.Fatalf("SendLink() error = %v", err)
+	}
+	msg := transport.last()
+	if msg.To != "User@Example.com" || msg.Subject != "Your Sponsoration Login Link" {
+		t.Errorf("sent %q to %q", msg.Subject, msg.To)
+	}
+	token := sentLoginToken(t, msg)
+	if strings.Contains(msg.Text, "device-1") || strings.Contains(msg.HTML, "device-1") {
+		t.Error("email should not reveal the fingerprint")
+	}
+
+	address, err := m.Verify(token, "device-1")
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if address != "user@example.com" {
+		t.Errorf("Verify() = %q, want user@example.com", address)
+	}
+}
+
+func TestMagicLoginService_VerifyFailures(t *testing.T) {
+	tests := []struct {
+		name        string
+		prepare     func(m *MagicLoginService, clock *FakeClock, token string)
+		fingerprint string
+		wantErr     error
+	}{
+		{
+			name: "expired",
+			prepare: func(m *MagicLoginService, clock *FakeClock, token string) {
+				clock.Advance(15 * time.Minute)
+			},
+			fingerprint: "device-1",
+			wantErr:     ErrTokenExpired,
+		},
+		{
+			name: "used",
+			prepare: func(m *MagicLoginService, clock *FakeClock, token string) {
+				_, _ = m.Verify(token, "device-1")
+			},
+			fingerprint: "device-1",
+			wantErr:     ErrTokenUsed,
+		},
+		{
+			name:        "mismatched device",
+			fingerprint: "device-2",
+			wantErr:     ErrTokenMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email, clock, transport := newTestEmailService(t)
+			m := NewMagicLoginService(email, newTestTokens(clock), MagicLoginConfig{})
+			if err := m.SendLink("user@example.com", "device-1"); err != nil {
+				t.Fatalf("SendLink() error = %v", err)
+			}
+			token := sentLoginToken(t, transport.last())
+
+			if tt.prepare != nil {
+				tt.prepare(m, clock, token)
+			}
+
+			if _, err := m.Verify(token, tt.fingerprint); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMagicLoginService_MismatchDoesNotBurnToken(t *testing.T) {
+	email, clock, transport := newTestEmailService(t)
+	m := NewMagicLoginService(email, newTestTokens(clock), MagicLoginConfig{})
+	_ = m.SendLink("user@example.com", "device-1")
+	token := sentLoginToken(t, transport.last())
+
+	if _, err := m.Verify(token, "attacker"); !errors.Is(err, ErrTokenMismatch) {
+		t.Fatalf("Verify(attacker) error = %v, want ErrTokenMismatch", err)
+	}
+	if _, err := m.Verify(token, "device-1"); err != nil {
+		t.Errorf("Verify(device-1) after mismatch error = %v", err)
+	}
+}
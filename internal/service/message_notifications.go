@@ -0,0 +1,198 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ChatMessage is a message sent in a conversation between a brand and a
+// creator
+type ChatMessage struct {
+	ID             string
+	ConversationID string
+	// ConversationTitle names the conversation in the subject, e.g. the
+	// deal title. Defaults to the sender's name.
+	ConversationTitle string
+	RecipientID       string
+	RecipientEmail    string
+	SenderName        string
+	Body              string
+	SentAt            time.Time
+}
+
+// ReadFunc reports whether a user has read a conversation up to a time. It
+// is called right before a notification goes out.
+type ReadFunc func(userID, conversationID string, through time.Time) (bool, error)
+
+// MessageNotifierConfig configures message notifications
+type MessageNotifierConfig struct {
+	// Delay is how long to wait after the first unnotified message before
+	// emailing, so a burst of messages becomes one email. Defaults to 2
+	// minutes.
+	Delay time.Duration
+	// MaxMessages caps the messages quoted in one email, the rest are
+	// counted. Defaults to 5.
+	MaxMessages int
+	// Preview is the longest a quoted message gets before it's cut off.
+	// Defaults to 280 characters.
+	Preview int
+}
+
+// withDefaults fills in zero values
+func (c MessageNotifierConfig) withDefaults() MessageNotifierConfig {
+	if c.Delay <= 0 {
+		c.Delay = 2 * time.Minute
+	}
+	if c.MaxMessages <= 0 {
+		c.MaxMessages = 5
+	}
+	if c.Preview <= 0 {
+		c.Preview = 280
+	}
+	return c
+}
+
+// messageBatch is the pending notification of one recipient about one
+// conversation
+type messageBatch struct {
+	dueAt    time.Time
+	messages []ChatMessage
+}
+
+// MessageNotifier emails users about new chat messages. Messages are held
+// for a delay and coalesced per conversation, and nothing is sent if the
+// user read them in the meantime. Flush is meant to be called periodically,
+// e.g. every few seconds from a background job.
+type MessageNotifier struct {
+	email   *EmailService
+	hasRead ReadFunc
+	config  MessageNotifierConfig
+
+	mu      sync.Mutex
+	pending map[string]*messageBatch // by recipient and conversation
+}
+
+// NewMessageNotifier creates a message notifier that checks hasRead before
+// sending through email
+func NewMessageNotifier(email *EmailService, hasRead ReadFunc, config MessageNotifierConfig) *MessageNotifier {
+	return &MessageNotifier{
+		email:   email,
+		hasRead: hasRead,
+		config:  config.withDefaults(),
+		pending: map[string]*messageBatch{},
+	}
+}
+
+// Notify queues a notification about msg. It joins the pending email of the
+// same conversation if there is one, otherwise it starts a new one that is
+// due after the delay.
+func (n *MessageNotifier) Notify(msg ChatMessage) {
+	if msg.SentAt.IsZero() {
+		msg.SentAt = n.email.clock.Now()
+	}
+	key := msg.RecipientID + "\x00" + msg.ConversationID
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	batch, ok := n.pending[key]
+	if !ok {
+		batch = &messageBatch{dueAt: n.email.clock.Now().Add(n.config.Delay)}
+		n.pending[key] = batch
+	}
+	batch.messages = append(batch.messages, msg)
+}
+
+// Pending returns how many notification emails are waiting
+func (n *MessageNotifier) Pending() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.pending)
+}
+
+// Flush sends every notification that is due, skipping conversations the
+// recipient has read since, and returns how many emails were sent. A batch
+// that fails stays queued and is retried by the next Flush.
+func (n *MessageNotifier) Flush() (int, error) {
+	now := n.email.clock.Now()
+
+	n.mu.Lock()
+	var keys []string
+	due := map[string]*messageBatch{}
+	for key, batch := range n.pending {
+		if !now.Before(batch.dueAt) {
+			keys = append(keys, key)
+			due[key] = batch
+			delete(n.pending, key)
+		}
+	}
+	n.mu.Unlock()
+	sort.Strings(keys)
+
+	sent := 0
+	var errs []error
+	for _, key := range keys {
+		batch := due[key]
+		ok, err := n.send(batch)
+		if err != nil {
+			errs = append(errs, err)
+			n.requeue(key, batch)
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// requeue puts a failed batch back, merging messages that arrived while it
+// was being sent
+func (n *MessageNotifier) requeue(key string, batch *messageBatch) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if newer, ok := n.pending[key]; ok {
+		batch.messages = append(batch.messages, newer.messages...)
+	}
+	n.pending[key] = batch
+}
+
+// send emails one batch unless it has been read. It reports whether an email
+// went out.
+func (n *MessageNotifier) send(batch *messageBatch) (bool, error) {
+	first := batch.messages[0]
+	last := batch.messages[len(batch.messages)-1]
+
+	read, err := n.hasRead(first.RecipientID, first.ConversationID, last.SentAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to check read state of conversation %s: %w", first.ConversationID, err)
+	}
+	if read {
+		return false, nil
+	}
+
+	link := n.email.appURL + "/messages/" + url.PathEscape(first.ConversationID)
+	msg := messageNotificationEmail(n.email.RenderContext(), batch.messages, link, n.config.MaxMessages, n.config.Preview)
+	msg.To = first.RecipientEmail
+	msg.Headers = n.threadHeaders(first.ConversationID, last.ID)
+	if err := n.email.SendEmail(msg); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// threadHeaders make mail clients group the notifications of a conversation
+// into one thread. Every email replies to the same conversation ID, which
+// is never sent itself.
+func (n *MessageNotifier) threadHeaders(conversationID, lastMessageID string) map[string]string {
+	host := n.email.appHost()
+	root := fmt.Sprintf("<conversation.%s@%s>", url.PathEscape(conversationID), host)
+	return map[string]string{
+		"Message-ID":  fmt.Sprintf("<message.%s.%s@%s>", url.PathEscape(conversationID), url.PathEscape(lastMessageID), host),
+		"In-Reply-To": root,
+		"References":  root,
+	}
+}
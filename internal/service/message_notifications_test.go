@@ -0,0 +1,148 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readState records which conversations were read, and through when
+type readState map[string]time.Time
+
+func (r readState) hasRead(userID, conversationID string, through time.Time) (bool, error) {
+	at, ok := r[userID+"/"+conversationID]
+	return ok && !at.Before(through), nil
+}
+
+func chatMessage(id, conversation string) ChatMessage {
+	return ChatMessage{
+		ID: id, ConversationID: conversation, ConversationTitle: "Spring Trail Collection Launch",
+		RecipientID: "creator-1", RecipientEmail: "jane@example.com",
+		SenderName: "Maria Lopez", Body: "Message " + id,
+	}
+}
+
+func TestMessageNotifier_CoalescesPerConversation(t *testing.T) {
+	email, clock, transport := newTestEmailService(t)
+	n := NewMessageNotifier(email, readState{}.hasRead, MessageNotifierConfig{Delay: time.Minute})
+
+	for i := 1; i <= 5; i++ {
+		n.Notify(chatMessage(fmt.Sprintf("m%d", i), "conv-1"))
+		clock.Advance(10 * time.Second)
+	}
+	n.Notify(chatMessage("other", "conv-2"))
+	if n.Pending() != 2 {
+		t.Fatalf("Pending() = %d, want one email per conversation", n.Pending())
+	}
+
+	// The window runs from the first message, so conv-1 is due first
+	clock.Set(goldenTime.Add(time.Minute))
+	if sent, err := n.Flush(); sent != 1 || err != nil {
+		t.Fatalf("Flush() = %d, %v, want 1", sent, err)
+	}
+	msg := transport.last()
+	if msg.Subject != "New messages: Spring Trail Collection Launch" || !strings.Contains(msg.Text, "5 new messages") {
+		t.Errorf("got %q:\n%s", msg.Subject, msg.Text)
+	}
+	for i := 1; i <= 5; i++ {
+		if !strings.Contains(msg.Text, fmt.Sprintf("Message m%d", i)) {
+			t.Errorf("email is missing message m%d", i)
+		}
+	}
+
+	want := map[string]string{
+		"Message-ID":  "<message.conv-1.m5@app.example.com>",
+		"In-Reply-To": "<conversation.conv-1@app.example.com>",
+		"References":  "<conversation.conv-1@app.example.com>",
+	}
+	for name, value := range want {
+		if msg.Headers[name] != value {
+			t.Errorf("%s = %q, want %q", name, msg.Headers[name], value)
+		}
+	}
+
+	clock.Advance(time.Minute)
+	if sent, _ := n.Flush(); sent != 1 || n.Pending() != 0 {
+		t.Errorf("Flush() = %d with %d pending, want conv-2 sent", sent, n.Pending())
+	}
+	// Later notifications of the conversation thread with the first one
+	if got := transport.last().Headers["In-Reply-To"]; got != "<conversation.conv-2@app.example.com>" {
+		t.Errorf("In-Reply-To = %q", got)
+	}
+}
+
+func TestMessageNotifier_SkipsReadConversations(t *testing.T) {
+	read := readState{}
+	email, clock, transport := newTestEmailService(t)
+	n := NewMessageNotifier(email, read.hasRead, MessageNotifierConfig{Delay: time.Minute})
+
+	n.Notify(chatMessage("m1", "conv-1"))
+	clock.Advance(30 * time.Second)
+	read["creator-1/conv-1"] = clock.Now()
+
+	clock.Advance(time.Minute)
+	if sent, err := n.Flush(); sent != 0 || err != nil {
+		t.Fatalf("Flush() = %d, %v, want nothing sent", sent, err)
+	}
+	if len(transport.messages()) != 0 || n.Pending() != 0 {
+		t.Error("read conversation was still notified")
+	}
+
+	// A message after the read point is notified
+	n.Notify(chatMessage("m2", "conv-1"))
+	clock.Advance(time.Minute)
+	if sent, _ := n.Flush(); sent != 1 {
+		t.Errorf("Flush() = %d, want the unread message sent", sent)
+	}
+}
+
+func TestMessageNotifier_RetriesFailures(t *testing.T) {
+	failing := true
+	hasRead := func(string, string, time.Time) (bool, error) {
+		if failing {
+			return false, errors.New("database down")
+		}
+		return false, nil
+	}
+	email, clock, transport := newTestEmailService(t)
+	n := NewMessageNotifier(email, hasRead, MessageNotifierConfig{Delay: time.Minute})
+
+	n.Notify(chatMessage("m1", "conv-1"))
+	clock.Advance(time.Minute)
+	if _, err := n.Flush(); err == nil {
+		t.Fatal("Flush() error = nil, want the read check error")
+	}
+	n.Notify(chatMessage("m2", "conv-1"))
+	if n.Pending() != 1 {
+		t.Fatalf("Pending() = %d, want the failed batch kept", n.Pending())
+	}
+
+	failing = false
+	if sent, err := n.Flush(); sent != 1 || err != nil {
+		t.Fatalf("Flush() = %d, %v, want 1", sent, err)
+	}
+	if text := transport.last().Text; !strings.Contains(text, "Message m1") || !strings.Contains(text, "Message m2") {
+		t.Errorf("retried email is missing messages:\n%s", text)
+	}
+}
+
+func TestMessageNotificationEmail_Caps(t *testing.T) {
+	var messages []ChatMessage
+	for i := 1; i <= 8; i++ {
+		messages = append(messages, chatMessage(fmt.Sprintf("m%d", i), "conv-1"))
+	}
+	messages[7].Body = strings.Repeat("a", 50)
+
+	msg := messageNotificationEmail(testRenderContext(), messages, "https://app.example.com/messages/conv-1", 5, 20)
+	if strings.Contains(msg.Text, "Message m3") || !strings.Contains(msg.Text, "Message m4") {
+		t.Errorf("want the latest 5 messages:\n%s", msg.Text)
+	}
+	if !strings.Contains(msg.Text, "(3 earlier)") {
+		t.Errorf("earlier messages are not counted:\n%s", msg.Text)
+	}
+	if !strings.Contains(msg.Text, strings.Repeat("a", 19)+"…") {
+		t.Errorf("long message is not truncated:\n%s", msg.Text)
+	}
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Money is an amount in the minor unit of its currency, e.g. cents, so sums
+// never pick up floating point error
+type Money struct {
+	Amount   int64
+	Currency string // ISO 4217 code, e.g. "USD"
+}
+
+// currencySymbols are the symbols of common currencies. Others are written
+// with their code.
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"JPY": "¥",
+}
+
+// currencyDigits are the minor unit digits of currencies that don't have
+// two
+var currencyDigits = map[string]int{
+	"JPY": 0,
+	"KRW": 0,
+	"BHD": 3,
+	"KWD": 3,
+}
+
+// moneyFormat is how one language writes amounts
+type moneyFormat struct {
+	group       string
+	decimal     string
+	symbolAfter bool
+}
+
+// moneyLocales are the languages amounts can be written in. Other locales
+// fall back to English.
+var moneyLocales = map[string]moneyFormat{
+	"en": {group: ",", decimal: "."},
+	"de": {group: ".", decimal: ",", symbolAfter: true},
+	"es": {group: ".", decimal: ",", symbolAfter: true},
+	"fr": {group: " ", decimal: ",", symbolAfter: true},
+}
+
+// minorDigits returns the number of minor unit digits of currency
+func minorDigits(currency string) int {
+	if d, ok := currencyDigits[currency]; ok {
+		return d
+	}
+	return 2
+}
+
+// formatMoney writes m the way locale ("en", "de-DE", ...) does, e.g.
+// "$5,000.00" or "5.000,00 €". Spaces are non-breaking so amounts never
+// wrap.
+func formatMoney(m Money, locale string) string {
+	lang, _, _ := strings.Cut(strings.ToLower(locale), "-")
+	f, ok := moneyLocales[lang]
+	if !ok {
+		f = moneyLocales["en"]
+	}
+
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+
+	digits := minorDigits(m.Currency)
+	unit := int64(1)
+	for i := 0; i < digits; i++ {
+		unit *= 10
+	}
+
+	whole := fmt.Sprint(amount / unit)
+	var number strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			number.WriteString(f.group)
+		}
+		number.WriteRune(digit)
+	}
+	if digits > 0 {
+		fmt.Fprintf(&number, "%s%0*d", f.decimal, digits, amount%unit)
+	}
+
+	symbol, ok := currencySymbols[m.Currency]
+	if !ok {
+		symbol = m.Currency
+	}
+	switch {
+	case f.symbolAfter:
+		return sign + number.String() + " " + symbol
+	case ok:
+		return sign + symbol + number.String()
+	default:
+		return sign + symbol + " " + number.String()
+	}
+}
+
+// percentOf returns basisPoints/10000 of amount, rounded half away from zero
+// to the minor unit
+func percentOf(amount int64, basisPoints int64) int64 {
+	product := amount * basisPoints
+	if product < 0 {
+		return -((-product + 5000) / 10000)
+	}
+	return (product + 5000) / 10000
+}
+
+// formatRate writes a rate in basis points as a percentage, e.g. "19%" or
+// "8.25%"
+func formatRate(basisPoints int64) string {
+	s := fmt.Sprintf("%d.%02d", basisPoints/100, basisPoints%100)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	return s + "%"
+}
@@ -0,0 +1,55 @@
+package service
+
+import "testing"
+
+func TestFormatMoney(t *testing.T) {
+	tests := []struct {
+		m      Money
+		locale string
+		want   string
+	}{
+		{Money{500000, "USD"}, "en", "$5,000.00"},
+		{Money{5, "USD"}, "en", "$0.05"},
+		{Money{123456789, "EUR"}, "en", "€1,234,567.89"},
+		{Money{-2550, "GBP"}, "en", "-£25.50"},
+		{Money{100000, "CHF"}, "en", "CHF 1,000.00"},
+		{Money{123456789, "EUR"}, "de-DE", "1.234.567,89 €"},
+		{Money{123456, "EUR"}, "fr", "1 234,56 €"},
+		{Money{150000, "USD"}, "es", "1.500,00 $"},
+		{Money{1500, "JPY"}, "en", "¥1,500"},
+		{Money{1500, "KWD"}, "en", "KWD 1.500"},
+		{Money{500000, "USD"}, "ja", "$5,000.00"},
+	}
+	for _, tt := range tests {
+		if got := formatMoney(tt.m, tt.locale); got != tt.want {
+			t.Errorf("formatMoney(%+v, %q) = %q, want %q", tt.m, tt.locale, got, tt.want)
+		}
+	}
+}
+
+func TestPercentOf(t *testing.T) {
+	tests := []struct {
+		amount, basisPoints, want int64
+	}{
+		{10000, 1900, 1900}, // 19% of 100.00
+		{1999, 1900, 380},   // 3.7981 rounds up
+		{2050, 1000, 205},   // exact
+		{25, 1000, 3},       // 0.025 rounds half up
+		{-25, 1000, -3},     // and half away from zero
+		{1234, 825, 102},    // 8.25% of 12.34 = 1.01805
+		{0, 2000, 0},
+	}
+	for _, tt := range tests {
+		if got := percentOf(tt.amount, tt.basisPoints); got != tt.want {
+			t.Errorf("percentOf(%d, %d) = %d, want %d", tt.amount, tt.basisPoints, got, tt.want)
+		}
+	}
+}
+
+func TestFormatRate(t *testing.T) {
+	for basisPoints, want := range map[int64]string{1900: "19%", 825: "8.25%", 750: "7.5%", 0: "0%"} {
+		if got := formatRate(basisPoints); got != want {
+			t.Errorf("formatRate(%d) = %q, want %q", basisPoints, got, want)
+		}
+	}
+}
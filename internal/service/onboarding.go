@@ -0,0 +1,426 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrEnrollmentNotFound is returned for a user who isn't enrolled in the
+// sequence
+var ErrEnrollmentNotFound = errors.New("onboarding enrollment not found")
+
+// PurposeOnboardingUnsubscribe scopes the unsubscribe links of onboarding
+// emails
+const PurposeOnboardingUnsubscribe TokenPurpose = "onboarding_unsubscribe"
+
+// Onboarding conditions checked through ConditionFunc
+const (
+	ConditionProfileComplete  = "profile_complete"
+	ConditionSocialsConnected = "socials_connected"
+	ConditionFirstDeal        = "first_deal"
+)
+
+// ConditionFunc reports whether a user meets a named condition, such as
+// ConditionProfileComplete
+type ConditionFunc func(userID, condition string) (bool, error)
+
+// OnboardingStep is one email of an onboarding sequence
+type OnboardingStep struct {
+	// Name identifies the step, e.g. "complete_profile"
+	Name string
+	// Delay is the time after enrollment the step is sent at
+	Delay time.Duration
+	// SkipWhen names a condition that makes the step pointless, e.g.
+	// ConditionProfileComplete for the "complete your profile" email.
+	// Empty means the step is always sent.
+	SkipWhen string
+
+	Subject  string
+	Headline string
+	Body     string
+	Button   string
+	Path     string // APP_URL path the button links to
+}
+
+// OnboardingSequence is a series of emails sent after sign-up
+type OnboardingSequence struct {
+	Name  string
+	Steps []OnboardingStep // ordered by Delay
+	// ExitWhen names a condition that ends the sequence early, e.g.
+	// ConditionFirstDeal once the user is up and running
+	ExitWhen string
+}
+
+// DefaultOnboardingSequence is the creator onboarding that follows the
+// welcome email
+func DefaultOnboardingSequence() OnboardingSequence {
+	return OnboardingSequence{
+		Name:     "creator_onboarding",
+		ExitWhen: ConditionFirstDeal,
+		Steps: []OnboardingStep{
+			{
+				Name:     "complete_profile",
+				Delay:    24 * time.Hour,
+				SkipWhen: ConditionProfileComplete,
+				Subject:  "Complete your Sponsoration profile",
+				Headline: "Finish Setting Up Your Profile",
+				Body:     "Brands look at your profile first. Add a photo, a short bio and your niche so they know who they'd be working with.",
+				Button:   "Complete Profile",
+				Path:     "/settings/profile",
+			},
+			{
+				Name:     "connect_socials",
+				Delay:    3 * 24 * time.Hour,
+				SkipWhen: ConditionSocialsConnected,
+				Subject:  "Connect your social accounts",
+				Headline: "Connect Your Socials",
+				Body:     "Connected accounts show brands your real reach and engagement. Creators with connected accounts get more offers.",
+				Button:   "Connect Accounts",
+				Path:     "/settings/socials",
+			},
+			{
+				Name:     "browse_sponsors",
+				Delay:    7 * 24 * time.Hour,
+				Subject:  "Find your first sponsor",
+				Headline: "Browse Sponsors",
+				Body:     "Brands are looking for creators like you right now. Browse open campaigns and send your first pitch.",
+				Button:   "Browse Sponsors",
+				Path:     "/sponsors",
+			},
+		},
+	}
+}
+
+// EnrollmentStatus is where a user is in a sequence
+type EnrollmentStatus string
+
+// Enrollment statuses. Only active enrollments get emails.
+const (
+	EnrollmentActive       EnrollmentStatus = "active"
+	EnrollmentCompleted    EnrollmentStatus = "completed"    // every step was sent or skipped
+	EnrollmentExited       EnrollmentStatus = "exited"       // the exit condition was met
+	EnrollmentUnsubscribed EnrollmentStatus = "unsubscribed" // the user opted out
+)
+
+// Enrollment is a user's progress through a sequence
+type Enrollment struct {
+	UserID     string
+	Email      string
+	Name       string
+	Sequence   string
+	EnrolledAt time.Time
+	// NextStep is the index of the next step to send
+	NextStep int
+	Status   EnrollmentStatus
+}
+
+// OnboardingStore persists enrollments
+type OnboardingStore interface {
+	// Save creates or replaces the enrollment of e.UserID in e.Sequence
+	Save(e Enrollment) error
+	// SaveIfActive replaces the enrollment of e.UserID in e.Sequence only
+	// while the stored one is still active, and reports whether it did
+	SaveIfActive(e Enrollment) (bool, error)
+	// Get returns an enrollment or ErrEnrollmentNotFound
+	Get(sequence, userID string) (Enrollment, error)
+	// Active returns the active enrollments of a sequence
+	Active(sequence string) ([]Enrollment, error)
+}
+
+// OnboardingConfig configures an onboarding scheduler
+type OnboardingConfig struct {
+	// Sequence defaults to DefaultOnboardingSequence
+	Sequence *OnboardingSequence
+	// Store defaults to an in-memory store
+	Store OnboardingStore
+	// Conditions checks skip and exit conditions. Without it no condition
+	// is ever met.
+	Conditions ConditionFunc
+	// UnsubscribeTTL is how long unsubscribe links work. Defaults to 90
+	// days.
+	UnsubscribeTTL time.Duration
+}
+
+// withDefaults fills in zero values
+func (c OnboardingConfig) withDefaults() OnboardingConfig {
+	if c.Sequence == nil {
+		seq := DefaultOnboardingSequence()
+		c.Sequence = &seq
+	}
+	if c.Store == nil {
+		c.Store = NewMemoryOnboardingStore()
+	}
+	if c.Conditions == nil {
+		c.Conditions = func(string, string) (bool, error) { return false, nil }
+	}
+	if c.UnsubscribeTTL <= 0 {
+		c.UnsubscribeTTL = 90 * 24 * time.Hour
+	}
+	return c
+}
+
+// OnboardingScheduler sends a drip sequence to enrolled users. Advance is
+// meant to be called periodically, e.g. hourly from a background job.
+type OnboardingScheduler struct {
+	email  *EmailService
+	tokens *TokenService
+	config OnboardingConfig
+}
+
+// NewOnboardingScheduler creates a scheduler that sends through email and
+// signs unsubscribe links with tokens
+func NewOnboardingScheduler(email *EmailService, tokens *TokenService, config OnboardingConfig) *OnboardingScheduler {
+	return &OnboardingScheduler{
+		email:  email,
+		tokens: tokens,
+		config: config.withDefaults(),
+	}
+}
+
+// Enroll starts the sequence for a user, typically right after the welcome
+// email. Enrolling again restarts it, except for users who unsubscribed,
+// who stay unsubscribed.
+func (o *OnboardingScheduler) Enroll(userID, email, name string) error {
+	existing, err := o.config.Store.Get(o.config.Sequence.Name, userID)
+	if err != nil && !errors.Is(err, ErrEnrollmentNotFound) {
+		return fmt.Errorf("failed to load enrollment: %w", err)
+	}
+	if err == nil && existing.Status == EnrollmentUnsubscribed {
+		return nil
+	}
+
+	err = o.config.Store.Save(Enrollment{
+		UserID:     userID,
+		Email:      email,
+		Name:       name,
+		Sequence:   o.config.Sequence.Name,
+		EnrolledAt: o.email.clock.Now(),
+		Status:     EnrollmentActive,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save enrollment: %w", err)
+	}
+	return nil
+}
+
+// Unsubscribe stops the sequence for a user
+func (o *OnboardingScheduler) Unsubscribe(userID string) error {
+	e, err := o.config.Store.Get(o.config.Sequence.Name, userID)
+	if err != nil {
+		return err
+	}
+	e.Status = EnrollmentUnsubscribed
+	if err := o.config.Store.Save(e); err != nil {
+		return fmt.Errorf("failed to save enrollment: %w", err)
+	}
+	return nil
+}
+
+// UnsubscribeToken stops the sequence for the user an unsubscribe link was
+// sent to. The link keeps working when clicked again.
+func (o *OnboardingScheduler) UnsubscribeToken(token string) error {
+	claims, err := o.tokens.Parse(token, PurposeOnboardingUnsubscribe)
+	if err != nil {
+		return err
+	}
+	return o.Unsubscribe(claims.Subject)
+}
+
+// OneClickUnsubscribe handles the RFC 8058 one-click unsubscribe POST that
+// mail clients send to the List-Unsubscribe URL. Mount it at
+// /onboarding/unsubscribe. Other methods get 405, so link scanners following
+// the URL with GET don't unsubscribe anyone; serve a confirmation page for
+// GET instead.
+func (o *OnboardingScheduler) OneClickUnsubscribe() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		err := o.UnsubscribeToken(r.URL.Query().Get("token"))
+		switch {
+		case err == nil:
+			w.WriteHeader(http.StatusOK)
+		case errors.Is(err, ErrEnrollmentNotFound), errors.Is(err, ErrTokenMalformed), errors.Is(err, ErrTokenSignature),
+			errors.Is(err, ErrTokenExpired), errors.Is(err, ErrTokenPurpose):
+			http.Error(w, "invalid unsubscribe link", http.StatusBadRequest)
+		default:
+			http.Error(w, "unsubscribe failed", http.StatusInternalServerError)
+		}
+	})
+}
+
+// Advance sends the steps that are due and returns how many emails were
+// sent. Users who meet the exit condition leave the sequence. When several
+// steps are due at once, e.g. after downtime, only the latest is sent. A
+// failure for one user doesn't stop the others.
+func (o *OnboardingScheduler) Advance() (int, error) {
+	enrollments, err := o.config.Store.Active(o.config.Sequence.Name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list enrollments: %w", err)
+	}
+
+	sent := 0
+	var errs []error
+	for _, e := range enrollments {
+		ok, err := o.advance(e)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("onboarding of %s: %w", e.UserID, err))
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// advance moves one enrollment forward and reports whether an email was
+// sent
+func (o *OnboardingScheduler) advance(e Enrollment) (bool, error) {
+	seq := o.config.Sequence
+	now := o.email.clock.Now()
+
+	// The last step that is due, if any
+	due := -1
+	for i := e.NextStep; i < len(seq.Steps); i++ {
+		if !now.Before(e.EnrolledAt.Add(seq.Steps[i].Delay)) {
+			due = i
+		}
+	}
+	if due < 0 {
+		return false, nil
+	}
+
+	if met, err := o.met(e.UserID, seq.ExitWhen); err != nil {
+		return false, err
+	} else if met {
+		e.Status = EnrollmentExited
+		return false, o.save(e)
+	}
+
+	step := seq.Steps[due]
+	skip, err := o.met(e.UserID, step.SkipWhen)
+	if err != nil {
+		return false, err
+	}
+	if !skip {
+		// The user may have unsubscribed since the enrollments were listed
+		current, err := o.config.Store.Get(e.Sequence, e.UserID)
+		if err != nil {
+			return false, fmt.Errorf("failed to load enrollment: %w", err)
+		}
+		if current.Status != EnrollmentActive {
+			return false, nil
+		}
+		if err := o.send(e, step); err != nil {
+			return false, err
+		}
+	}
+
+	e.NextStep = due + 1
+	if e.NextStep >= len(seq.Steps) {
+		e.Status = EnrollmentCompleted
+	}
+	return !skip, o.save(e)
+}
+
+// met checks a condition, where an empty condition is never met
+func (o *OnboardingScheduler) met(userID, condition string) (bool, error) {
+	if condition == "" {
+		return false, nil
+	}
+	met, err := o.config.Conditions(userID, condition)
+	if err != nil {
+		return false, fmt.Errorf("failed to check condition %s: %w", condition, err)
+	}
+	return met, nil
+}
+
+// send emails one step with a fresh unsubscribe link
+func (o *OnboardingScheduler) send(e Enrollment, step OnboardingStep) error {
+	token, err := o.tokens.Issue(PurposeOnboardingUnsubscribe, e.UserID, o.config.UnsubscribeTTL)
+	if err != nil {
+		return fmt.Errorf("failed to issue unsubscribe token: %w", err)
+	}
+	unsubscribe := o.email.tokenLink("/onboarding/unsubscribe", token)
+
+	msg := onboardingEmail(o.email.RenderContext(), step, e.Name, o.email.appURL+step.Path, unsubscribe)
+	msg.To = e.Email
+	msg.Headers = map[string]string{
+		"List-Unsubscribe":      "<" + unsubscribe + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+	return o.email.SendEmail(msg)
+}
+
+// save stores the progress of an enrollment unless it stopped being active
+// meanwhile, e.g. because the user unsubscribed
+func (o *OnboardingScheduler) save(e Enrollment) error {
+	if _, err := o.config.Store.SaveIfActive(e); err != nil {
+		return fmt.Errorf("failed to save enrollment: %w", err)
+	}
+	return nil
+}
+
+// MemoryOnboardingStore is an in-process OnboardingStore. Enrollments are
+// lost on restart.
+type MemoryOnboardingStore struct {
+	mu          sync.Mutex
+	enrollments map[string]Enrollment
+}
+
+// NewMemoryOnboardingStore creates an empty in-memory onboarding store
+func NewMemoryOnboardingStore() *MemoryOnboardingStore {
+	return &MemoryOnboardingStore{enrollments: map[string]Enrollment{}}
+}
+
+// Save creates or replaces an enrollment
+func (m *MemoryOnboardingStore) Save(e Enrollment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.enrollments[e.Sequence+"\x00"+e.UserID] = e
+	return nil
+}
+
+// SaveIfActive replaces an enrollment while the stored one is active
+func (m *MemoryOnboardingStore) SaveIfActive(e Enrollment) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := e.Sequence + "\x00" + e.UserID
+	if current, ok := m.enrollments[key]; !ok || current.Status != EnrollmentActive {
+		return false, nil
+	}
+	m.enrollments[key] = e
+	return true, nil
+}
+
+// Get returns an enrollment
+func (m *MemoryOnboardingStore) Get(sequence, userID string) (Enrollment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.enrollments[sequence+"\x00"+userID]
+	if !ok {
+		return Enrollment{}, ErrEnrollmentNotFound
+	}
+	return e, nil
+}
+
+// Active returns the active enrollments of a sequence ordered by user ID
+func (m *MemoryOnboardingStore) Active(sequence string) ([]Enrollment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var active []Enrollment
+	for _, e := range m.enrollments {
+		if e.Sequence == sequence && e.Status == EnrollmentActive {
+			active = append(active, e)
+		}
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].UserID < active[j].UserID })
+	return active, nil
+}
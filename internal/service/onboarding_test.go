@@ -0,0 +1,232 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// conditions records which conditions each user meets
+type conditions map[string]map[string]bool
+
+func (c conditions) met(userID, condition string) (bool, error) {
+	return c[userID][condition], nil
+}
+
+func TestOnboardingScheduler_SendsStepsOnSchedule(t *testing.T) {
+	email, clock, transport := newTestEmailService(t)
+	store := NewMemoryOnboardingStore()
+	o := NewOnboardingScheduler(email, newTestTokens(clock), OnboardingConfig{Store: store})
+	if err := o.Enroll("u1", "jane@example.com", "Jane"); err != nil {
+		t.Fatalf("Enroll() error = %v", err)
+	}
+
+	var subjects []string
+	for day := 1; day <= 8; day++ {
+		clock.Set(goldenTime.Add(time.Duration(day) * 24 * time.Hour))
+		if _, err := o.Advance(); err != nil {
+			t.Fatalf("Advance() on day %d error = %v", day, err)
+		}
+	}
+	for _, msg := range transport.messages() {
+		subjects = append(subjects, msg.Subject)
+	}
+	want := []string{"Complete your Sponsoration profile", "Connect your social accounts", "Find your first sponsor"}
+	if len(subjects) != len(want) {
+		t.Fatalf("sent %q, want %q", subjects, want)
+	}
+	for i := range want {
+		if subjects[i] != want[i] {
+			t.Errorf("email %d = %q, want %q", i, subjects[i], want[i])
+		}
+	}
+
+	e, _ := store.Get("creator_onboarding", "u1")
+	if e.Status != EnrollmentCompleted {
+		t.Errorf("Status = %s, want completed", e.Status)
+	}
+	if got := transport.last().Headers["List-Unsubscribe"]; got == "" {
+		t.Error("List-Unsubscribe header is missing")
+	}
+}
+
+func TestOnboardingScheduler_Advance(t *testing.T) {
+	tests := []struct {
+		name string
+		met  conditions
+		// days are when Advance runs, counted from enrollment
+		days         []int
+		wantSubjects []string
+	}{
+		{
+			name:         "skips steps whose condition is met",
+			met:          conditions{"u1": {ConditionProfileComplete: true}},
+			days:         []int{1, 3},
+			wantSubjects: []string{"Connect your social accounts"},
+		},
+		{
+			// Down for four days: the profile step is stale
+			name:         "only the latest overdue step",
+			days:         []int{4},
+			wantSubjects: []string{"Connect your social accounts"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email, clock, transport := newTestEmailService(t)
+			o := NewOnboardingScheduler(email, newTestTokens(clock), OnboardingConfig{Conditions: tt.met.met})
+			_ = o.Enroll("u1", "jane@example.com", "Jane")
+
+			for _, day := range tt.days {
+				clock.Set(goldenTime.Add(time.Duration(day) * 24 * time.Hour))
+				if _, err := o.Advance(); err != nil {
+					t.Fatalf("Advance() on day %d error = %v", day, err)
+				}
+			}
+			var got []string
+			for _, msg := range transport.messages() {
+				got = append(got, msg.Subject)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.wantSubjects, "|") {
+				t.Errorf("sent %q, want %q", got, tt.wantSubjects)
+			}
+		})
+	}
+}
+
+func TestOnboardingScheduler_ExitsWhenConditionMet(t *testing.T) {
+	c := conditions{"u1": {}}
+	email, clock, transport := newTestEmailService(t)
+	store := NewMemoryOnboardingStore()
+	o := NewOnboardingScheduler(email, newTestTokens(clock), OnboardingConfig{Store: store, Conditions: c.met})
+	_ = o.Enroll("u1", "jane@example.com", "Jane")
+
+	clock.Advance(24 * time.Hour)
+	_, _ = o.Advance()
+	c["u1"][ConditionFirstDeal] = true
+
+	clock.Advance(7 * 24 * time.Hour)
+	if sent, _ := o.Advance(); sent != 0 || len(transport.messages()) != 1 {
+		t.Errorf("emails sent after the exit condition was met")
+	}
+	if e, _ := store.Get("creator_onboarding", "u1"); e.Status != EnrollmentExited {
+		t.Errorf("Status = %s, want exited", e.Status)
+	}
+}
+
+func TestOnboardingScheduler_Unsubscribe(t *testing.T) {
+	email, clock, transport := newTestEmailService(t)
+	o := NewOnboardingScheduler(email, newTestTokens(clock), OnboardingConfig{})
+	_ = o.Enroll("u1", "jane@example.com", "Jane")
+
+	clock.Advance(24 * time.Hour)
+	_, _ = o.Advance()
+	token := sentLinkToken(t, transport.last(), "/onboarding/unsubscribe")
+
+	for i := 0; i < 2; i++ {
+		if err := o.UnsubscribeToken(token); err != nil {
+			t.Fatalf("UnsubscribeToken() #%d error = %v", i+1, err)
+		}
+	}
+	clock.Advance(7 * 24 * time.Hour)
+	if sent, _ := o.Advance(); sent != 0 {
+		t.Error("email sent after unsubscribing")
+	}
+
+	if err := o.Unsubscribe("nobody"); !errors.Is(err, ErrEnrollmentNotFound) {
+		t.Errorf("Unsubscribe(nobody) error = %v, want ErrEnrollmentNotFound", err)
+	}
+	if err := o.UnsubscribeToken("garbage"); err == nil {
+		t.Error("UnsubscribeToken(garbage) succeeded")
+	}
+}
+
+func TestOnboardingScheduler_EnrollKeepsUnsubscribed(t *testing.T) {
+	email, clock, _ := newTestEmailService(t)
+	store := NewMemoryOnboardingStore()
+	o := NewOnboardingScheduler(email, newTestTokens(clock), OnboardingConfig{Store: store})
+	_ = o.Enroll("u1", "jane@example.com", "Jane")
+	_ = o.Unsubscribe("u1")
+
+	if err := o.Enroll("u1", "jane@example.com", "Jane"); err != nil {
+		t.Fatalf("Enroll() error = %v", err)
+	}
+	if e, _ := store.Get("creator_onboarding", "u1"); e.Status != EnrollmentUnsubscribed {
+		t.Errorf("Status = %q after enrolling again, want unsubscribed", e.Status)
+	}
+	clock.Advance(24 * time.Hour)
+	if sent, _ := o.Advance(); sent != 0 {
+		t.Error("email sent to an unsubscribed user")
+	}
+}
+
+func TestOnboardingScheduler_UnsubscribeDuringAdvance(t *testing.T) {
+	var o *OnboardingScheduler
+	// The user unsubscribes while Advance is checking the step's condition
+	unsubscribe := func(userID, condition string) (bool, error) {
+		return false, o.Unsubscribe(userID)
+	}
+	email, clock, transport := newTestEmailService(t)
+	store := NewMemoryOnboardingStore()
+	o = NewOnboardingScheduler(email, newTestTokens(clock), OnboardingConfig{Store: store, Conditions: unsubscribe})
+
+	_ = o.Enroll("u1", "jane@example.com", "Jane")
+	clock.Advance(24 * time.Hour)
+	if _, err := o.Advance(); err != nil {
+		t.Fatalf("Advance() error = %v", err)
+	}
+	if n := len(transport.messages()); n != 0 {
+		t.Errorf("sent %d emails after the user unsubscribed", n)
+	}
+	if e, _ := store.Get("creator_onboarding", "u1"); e.Status != EnrollmentUnsubscribed {
+		t.Errorf("Status = %q, want the unsubscribe to stick", e.Status)
+	}
+}
+
+func TestOnboardingScheduler_OneClickUnsubscribe(t *testing.T) {
+	email, clock, transport := newTestEmailService(t)
+	store := NewMemoryOnboardingStore()
+	o := NewOnboardingScheduler(email, newTestTokens(clock), OnboardingConfig{Store: store})
+	_ = o.Enroll("u1", "jane@example.com", "Jane")
+	clock.Advance(24 * time.Hour)
+	_, _ = o.Advance()
+
+	msg := transport.last()
+	if msg.Headers["List-Unsubscribe-Post"] != "List-Unsubscribe=One-Click" {
+		t.Errorf("headers = %v, want List-Unsubscribe-Post", msg.Headers)
+	}
+	link := strings.Trim(msg.Headers["List-Unsubscribe"], "<>")
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := o.OneClickUnsubscribe()
+	serve := func(method, target string) int {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(method, target, strings.NewReader("List-Unsubscribe=One-Click"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := serve(http.MethodGet, u.RequestURI()); code != http.StatusMethodNotAllowed {
+		t.Errorf("GET = %d, want 405", code)
+	}
+	if e, _ := store.Get("creator_onboarding", "u1"); e.Status != EnrollmentActive {
+		t.Fatal("GET should not unsubscribe")
+	}
+	if code := serve(http.MethodPost, u.RequestURI()); code != http.StatusOK {
+		t.Errorf("POST = %d, want 200", code)
+	}
+	if e, _ := store.Get("creator_onboarding", "u1"); e.Status != EnrollmentUnsubscribed {
+		t.Errorf("Status = %q after one-click unsubscribe", e.Status)
+	}
+	if code := serve(http.MethodPost, "/onboarding/unsubscribe?token=garbage"); code != http.StatusBadRequest {
+		t.Errorf("POST with a bad token = %d, want 400", code)
+	}
+}
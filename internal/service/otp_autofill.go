@@ -0,0 +1,39 @@
+package service
+
+import "fmt"
+
+// OTPAutofill turns on the domain-bound one-time code format, which lets iOS
+// and Android offer to fill in a code straight from the email: the code goes
+// into the subject and the text part ends with an "@<domain> #<code>" line.
+type OTPAutofill struct {
+	// Domain is the host of the site the code is entered on. Defaults to the
+	// APP_URL host.
+	Domain string
+	// Templates are the registry names of the templates that use the
+	// format, e.g. "verification" and "password_reset"
+	Templates []string
+}
+
+// enabled reports whether template uses the autofill format
+func (a OTPAutofill) enabled(template string) bool {
+	if a.Domain == "" {
+		return false
+	}
+	for _, name := range a.Templates {
+		if name == template {
+			return true
+		}
+	}
+	return false
+}
+
+// withOTPAutofill rewrites msg into the autofill format when it is enabled
+// for template. subject is the code-first subject line to use.
+func withOTPAutofill(rc RenderContext, template string, msg EmailOptions, code, subject string) EmailOptions {
+	if !rc.OTPAutofill.enabled(template) {
+		return msg
+	}
+	msg.Subject = subject
+	msg.Text += fmt.Sprintf("\n\n@%s #%s", rc.OTPAutofill.Domain, code)
+	return msg
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// autofillLine is the domain-bound code format iOS and Android look for on
+// the last line of a message
+var autofillLine = regexp.MustCompile(`(?:^|\n)@([A-Za-z0-9.-]+) #([^\s#]+)$`)
+
+func TestOTPAutofill_CodeIsExtractable(t *testing.T) {
+	rc := testRenderContext()
+	rc.OTPAutofill = OTPAutofill{Domain: "app.example.com", Templates: []string{"verification", "password_reset"}}
+
+	tests := []struct {
+		name string
+		msg  EmailOptions
+		code string
+	}{
+		{"verification", verificationEmail(rc, "ABC123", "https://app.example.com/verify-email?token=T", time.Hour), "ABC123"},
+		{"password_reset", passwordResetEmail(rc, "RESET456", "Hello,", "", time.Hour), "RESET456"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := autofillLine.FindStringSubmatch(tt.msg.Text)
+			if m == nil {
+				t.Fatalf("no autofill line at the end of %q", tt.msg.Text)
+			}
+			if m[1] != "app.example.com" || m[2] != tt.code {
+				t.Errorf("autofill line = @%s #%s, want @app.example.com #%s", m[1], m[2], tt.code)
+			}
+			if !strings.HasPrefix(tt.msg.Subject, tt.code+" ") {
+				t.Errorf("Subject = %q, want the code first", tt.msg.Subject)
+			}
+		})
+	}
+}
+
+func TestOTPAutofill_PerTemplate(t *testing.T) {
+	rc := testRenderContext()
+	rc.OTPAutofill = OTPAutofill{Domain: "app.example.com", Templates: []string{"verification"}}
+
+	msg := passwordResetEmail(rc, "RESET456", "Hello,", "", time.Hour)
+	if autofillLine.MatchString(msg.Text) || msg.Subject != "Reset Your Password" {
+		t.Errorf("password reset should keep the plain format, got %q / %q", msg.Subject, msg.Text)
+	}
+}
+
+func TestWithOTPAutofill_DefaultsToAppURLHost(t *testing.T) {
+	t.Setenv("APP_URL", "https://app.sponsoration.com:8443/")
+	transport := &recordingTransport{}
+	service := NewEmailService(WithTransport(transport), WithOTPAutofill("", "verification"))
+
+	if err := service.SendVerificationEmail("user@example.com", "ABC123"); err != nil {
+		t.Fatalf("SendVerificationEmail() error = %v", err)
+	}
+	m := autofillLine.FindStringSubmatch(transport.last().Text)
+	if m == nil || m[1] != "app.sponsoration.com" || m[2] != "ABC123" {
+		t.Errorf("autofill line = %v, want @app.sponsoration.com #ABC123", m)
+	}
+}
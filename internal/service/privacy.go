@@ -0,0 +1,389 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrDeletionNotFound is returned when no deletion is pending for a
+	// user, e.g. because it was already canceled or carried out
+	ErrDeletionNotFound = errors.New("account deletion not found")
+	// ErrDeletionInProgress is returned when scheduling a deletion that
+	// DeleteDue has already started carrying out
+	ErrDeletionInProgress = errors.New("account deletion already in progress")
+	// ErrDataExportInvalid is returned for a token whose content doesn't
+	// describe a data export
+	ErrDataExportInvalid = errors.New("data export token invalid")
+)
+
+// Token purposes of the privacy emails
+const (
+	PurposeCancelDeletion TokenPurpose = "cancel_deletion"
+	PurposeDataExport     TokenPurpose = "data_export"
+)
+
+// AccountContact is how to reach the owner of an account. It is captured
+// when a deletion or export is requested, so the emails about it can still
+// be sent once the user record is gone.
+type AccountContact struct {
+	UserID string
+	Email  string
+	Name   string
+}
+
+// PendingDeletion is an account deletion waiting out its grace period
+type PendingDeletion struct {
+	Contact     AccountContact
+	RequestedAt time.Time
+	DeleteAt    time.Time
+	// UndoTokenID is the ID of the token in the undo link. Only that link
+	// cancels this deletion. It is cleared when DeleteDue starts erasing,
+	// after which the deletion can't be canceled.
+	UndoTokenID string
+	// ErasedAt is set once the account data is gone but the confirmation
+	// email hasn't been sent yet
+	ErasedAt time.Time
+}
+
+// DeletionStore persists pending deletions with the captured contact
+type DeletionStore interface {
+	// Save creates or replaces the pending deletion of p.Contact.UserID
+	Save(p PendingDeletion) error
+	// Schedule is Save for a new or rescheduled deletion. It fails with
+	// ErrDeletionInProgress, saving nothing, while the stored deletion has
+	// been claimed.
+	Schedule(p PendingDeletion) error
+	// Get returns a pending deletion or ErrDeletionNotFound
+	Get(userID string) (PendingDeletion, error)
+	// Delete forgets a pending deletion
+	Delete(userID string) error
+	// Cancel deletes and returns the pending deletion of userID only while
+	// its UndoTokenID is undoTokenID, or fails with ErrDeletionNotFound
+	Cancel(userID, undoTokenID string) (PendingDeletion, error)
+	// Claim clears the UndoTokenID of the pending deletion of userID only
+	// while it is undoTokenID, or fails with ErrDeletionNotFound
+	Claim(userID, undoTokenID string) error
+	// Due returns the deletions with DeleteAt at or before now
+	Due(now time.Time) ([]PendingDeletion, error)
+}
+
+// EraseFunc deletes all data of a user. It may be called again for the same
+// user if an earlier run failed part way.
+type EraseFunc func(userID string) error
+
+// DataExport identifies a downloadable export of a user's data
+type DataExport struct {
+	UserID   string `json:"uid"`
+	ExportID string `json:"eid"`
+}
+
+// PrivacyConfig configures account deletion and data exports
+type PrivacyConfig struct {
+	// GracePeriod is how long a deletion can be undone before it's carried
+	// out. Defaults to 30 days.
+	GracePeriod time.Duration
+	// ExportTTL is how long a data export download link works. Defaults to
+	// 7 days.
+	ExportTTL time.Duration
+	// Store defaults to an in-memory store
+	Store DeletionStore
+}
+
+// withDefaults fills in zero values
+func (c PrivacyConfig) withDefaults() PrivacyConfig {
+	if c.GracePeriod <= 0 {
+		c.GracePeriod = 30 * 24 * time.Hour
+	}
+	if c.ExportTTL <= 0 {
+		c.ExportTTL = 7 * 24 * time.Hour
+	}
+	if c.Store == nil {
+		c.Store = NewMemoryDeletionStore()
+	}
+	return c
+}
+
+// PrivacyService runs the GDPR flows: account deletion with a grace period
+// and an undo link, and data exports with an expiring download link.
+// DeleteDue is meant to be called periodically, e.g. hourly from a
+// background job.
+type PrivacyService struct {
+	email  *EmailService
+	tokens *TokenService
+	config PrivacyConfig
+}
+
+// NewPrivacyService creates a privacy service that signs links with tokens
+// and sends them through email
+func NewPrivacyService(email *EmailService, tokens *TokenService, config PrivacyConfig) *PrivacyService {
+	return &PrivacyService{
+		email:  email,
+		tokens: tokens,
+		config: config.withDefaults(),
+	}
+}
+
+// ScheduleDeletion emails the owner an undo link and schedules deleting the
+// account after the grace period. Nothing is scheduled when the email can't
+// be sent. Scheduling again restarts the grace period and invalidates
+// earlier undo links, unless DeleteDue is already carrying the deletion out,
+// which fails with ErrDeletionInProgress.
+func (p *PrivacyService) ScheduleDeletion(contact AccountContact) (PendingDeletion, error) {
+	// Don't send an undo link for a deletion that can't be undone. The
+	// store checks again when saving, in case DeleteDue claims it meanwhile.
+	existing, err := p.config.Store.Get(contact.UserID)
+	if err != nil && !errors.Is(err, ErrDeletionNotFound) {
+		return PendingDeletion{}, fmt.Errorf("failed to load pending deletion: %w", err)
+	}
+	if err == nil && existing.UndoTokenID == "" {
+		return PendingDeletion{}, ErrDeletionInProgress
+	}
+
+	token, err := p.tokens.Issue(PurposeCancelDeletion, contact.UserID, p.config.GracePeriod)
+	if err != nil {
+		return PendingDeletion{}, fmt.Errorf("failed to issue undo token: %w", err)
+	}
+	claims, err := p.tokens.Parse(token, PurposeCancelDeletion)
+	if err != nil {
+		return PendingDeletion{}, fmt.Errorf("failed to issue undo token: %w", err)
+	}
+
+	pending := PendingDeletion{
+		Contact:     contact,
+		RequestedAt: claims.IssuedAt,
+		DeleteAt:    claims.ExpiresAt,
+		UndoTokenID: claims.ID,
+	}
+	undo := p.email.tokenLink("/account/deletion/cancel", token)
+	msg := deletionScheduledEmail(p.email.RenderContext(), contact.Name, pending.DeleteAt, undo)
+	msg.To = contact.Email
+	if err := p.email.SendEmail(msg); err != nil {
+		return PendingDeletion{}, err
+	}
+
+	err = p.config.Store.Schedule(pending)
+	if errors.Is(err, ErrDeletionInProgress) {
+		return PendingDeletion{}, err
+	}
+	if err != nil {
+		return PendingDeletion{}, fmt.Errorf("failed to save pending deletion: %w", err)
+	}
+	return pending, nil
+}
+
+// CancelDeletion cancels the deletion an undo link token was sent for and
+// consumes the token. The caller should reactivate the account. Errors are
+// the token errors of TokenService, or ErrDeletionNotFound when the deletion
+// was already canceled, rescheduled or carried out.
+func (p *PrivacyService) CancelDeletion(token string) (AccountContact, error) {
+	claims, err := p.tokens.Parse(token, PurposeCancelDeletion)
+	if err != nil {
+		return AccountContact{}, err
+	}
+	// Only cancel if DeleteDue hasn't claimed the deletion in the meantime.
+	// Cancel matches the token, so the link works once even before it is
+	// consumed, and a store failure leaves it working for a retry.
+	pending, err := p.config.Store.Cancel(claims.Subject, claims.ID)
+	if errors.Is(err, ErrDeletionNotFound) {
+		return AccountContact{}, err
+	}
+	if err != nil {
+		return AccountContact{}, fmt.Errorf("failed to cancel deletion: %w", err)
+	}
+	if _, err := p.tokens.Consume(token, PurposeCancelDeletion); err != nil {
+		log.Printf("❌ Failed to consume deletion undo token: %v", err)
+	}
+	return pending.Contact, nil
+}
+
+// DeleteDue erases the accounts whose grace period is over and emails each
+// owner a confirmation at the captured address. It returns how many
+// accounts were deleted. A failure for one account doesn't stop the others,
+// and is retried by the next DeleteDue.
+func (p *PrivacyService) DeleteDue(erase EraseFunc) (int, error) {
+	due, err := p.config.Store.Due(p.email.clock.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to list pending deletions: %w", err)
+	}
+
+	deleted := 0
+	var errs []error
+	for _, pending := range due {
+		ok, err := p.delete(pending, erase)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("deletion of %s: %w", pending.Contact.UserID, err))
+			continue
+		}
+		if ok {
+			deleted++
+		}
+	}
+	return deleted, errors.Join(errs...)
+}
+
+// delete erases one account, then confirms by email, and reports whether it
+// did. The deletion is claimed first so the undo link stops working before
+// anything is erased, and erasing is recorded so a failed email doesn't
+// erase again on retry. A deletion canceled or rescheduled since it was
+// listed is skipped.
+func (p *PrivacyService) delete(pending PendingDeletion, erase EraseFunc) (bool, error) {
+	if pending.UndoTokenID != "" {
+		err := p.config.Store.Claim(pending.Contact.UserID, pending.UndoTokenID)
+		if errors.Is(err, ErrDeletionNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to claim pending deletion: %w", err)
+		}
+		pending.UndoTokenID = ""
+	}
+
+	if pending.ErasedAt.IsZero() {
+		if err := erase(pending.Contact.UserID); err != nil {
+			return false, fmt.Errorf("failed to erase account: %w", err)
+		}
+		pending.ErasedAt = p.email.clock.Now()
+		if err := p.config.Store.Save(pending); err != nil {
+			return false, fmt.Errorf("failed to save pending deletion: %w", err)
+		}
+	}
+
+	msg := accountDeletedEmail(p.email.RenderContext(), pending.Contact.Name)
+	msg.To = pending.Contact.Email
+	if err := p.email.SendEmail(msg); err != nil {
+		return false, err
+	}
+	if err := p.config.Store.Delete(pending.Contact.UserID); err != nil {
+		return false, fmt.Errorf("failed to delete pending deletion: %w", err)
+	}
+	return true, nil
+}
+
+// SendDataExportReady emails the owner a signed link to download exportID,
+// valid for the export TTL
+func (p *PrivacyService) SendDataExportReady(contact AccountContact, exportID string) error {
+	raw, err := json.Marshal(DataExport{UserID: contact.UserID, ExportID: exportID})
+	if err != nil {
+		return fmt.Errorf("failed to encode data export: %w", err)
+	}
+	token, err := p.tokens.Issue(PurposeDataExport, string(raw), p.config.ExportTTL)
+	if err != nil {
+		return fmt.Errorf("failed to issue data export token: %w", err)
+	}
+
+	link := p.email.tokenLink("/account/export/download", token)
+	rc := p.email.RenderContext()
+	msg := dataExportReadyEmail(rc, contact.Name, link, p.config.ExportTTL, rc.Now.Add(p.config.ExportTTL))
+	msg.To = contact.Email
+	return p.email.SendEmail(msg)
+}
+
+// DownloadExport checks a download link token and returns the export to serve.
+// The link works any number of times until it expires. Errors are the token
+// errors of TokenService or ErrDataExportInvalid.
+func (p *PrivacyService) DownloadExport(token string) (DataExport, error) {
+	claims, err := p.tokens.Parse(token, PurposeDataExport)
+	if err != nil {
+		return DataExport{}, err
+	}
+	var export DataExport
+	if err := json.Unmarshal([]byte(claims.Subject), &export); err != nil || export.UserID == "" || export.ExportID == "" {
+		return DataExport{}, ErrDataExportInvalid
+	}
+	return export, nil
+}
+
+// MemoryDeletionStore is an in-process DeletionStore. Pending deletions are
+// lost on restart.
+type MemoryDeletionStore struct {
+	mu      sync.Mutex
+	pending map[string]PendingDeletion // by user ID
+}
+
+// NewMemoryDeletionStore creates an empty in-memory deletion store
+func NewMemoryDeletionStore() *MemoryDeletionStore {
+	return &MemoryDeletionStore{pending: map[string]PendingDeletion{}}
+}
+
+// Save creates or replaces a pending deletion
+func (m *MemoryDeletionStore) Save(p PendingDeletion) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pending[p.Contact.UserID] = p
+	return nil
+}
+
+// Schedule saves a pending deletion unless the stored one is claimed
+func (m *MemoryDeletionStore) Schedule(p PendingDeletion) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if old, ok := m.pending[p.Contact.UserID]; ok && old.UndoTokenID == "" {
+		return ErrDeletionInProgress
+	}
+	m.pending[p.Contact.UserID] = p
+	return nil
+}
+
+// Get returns a pending deletion
+func (m *MemoryDeletionStore) Get(userID string) (PendingDeletion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.pending[userID]
+	if !ok {
+		return PendingDeletion{}, ErrDeletionNotFound
+	}
+	return p, nil
+}
+
+// Delete forgets a pending deletion
+func (m *MemoryDeletionStore) Delete(userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.pending, userID)
+	return nil
+}
+
+// Cancel deletes a pending deletion while its undo token matches
+func (m *MemoryDeletionStore) Cancel(userID, undoTokenID string) (PendingDeletion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.pending[userID]
+	if !ok || undoTokenID == "" || p.UndoTokenID != undoTokenID {
+		return PendingDeletion{}, ErrDeletionNotFound
+	}
+	delete(m.pending, userID)
+	return p, nil
+}
+
+// Claim clears the undo token of a pending deletion while it matches
+func (m *MemoryDeletionStore) Claim(userID, undoTokenID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.pending[userID]
+	if !ok || undoTokenID == "" || p.UndoTokenID != undoTokenID {
+		return ErrDeletionNotFound
+	}
+	p.UndoTokenID = ""
+	m.pending[userID] = p
+	return nil
+}
+
+// Due returns the deletions due at or before now ordered by user ID
+func (m *MemoryDeletionStore) Due(now time.Time) ([]PendingDeletion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var due []PendingDeletion
+	for _, p := range m.pending {
+		if !p.DeleteAt.After(now) {
+			due = append(due, p)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].Contact.UserID < due[j].Contact.UserID })
+	return due, nil
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var janeContact = AccountContact{UserID: "u1", Email: "jane@example.com", Name: "Jane"}
+
+func TestPrivacyService_DeletionAfterGracePeriod(t *testing.T) {
+	email, clock, transport := newTestEmailService(t)
+	p := NewPrivacyService(email, newTestTokens(clock), PrivacyConfig{})
+	pending, err := p.ScheduleDeletion(janeContact)
+	if err != nil {
+		t.Fatalf("ScheduleDeletion() error = %v", err)
+	}
+	if want := goldenTime.Add(30 * 24 * time.Hour); !pending.DeleteAt.Equal(want) {
+		t.Errorf("DeleteAt = %v, want %v", pending.DeleteAt, want)
+	}
+	if msg := transport.last(); msg.To != "jane@example.com" || !strings.Contains(msg.Text, "April 13, 2025") {
+		t.Errorf("scheduled email to %s:\n%s", msg.To, msg.Text)
+	}
+
+	var erased []string
+	erase := func(userID string) error {
+		erased = append(erased, userID)
+		return nil
+	}
+	clock.Advance(29 * 24 * time.Hour)
+	if deleted, err := p.DeleteDue(erase); deleted != 0 || err != nil {
+		t.Fatalf("DeleteDue() during the grace period = %d, %v", deleted, err)
+	}
+
+	// The user record is gone by now, the captured address is still used
+	clock.Advance(24 * time.Hour)
+	if deleted, err := p.DeleteDue(erase); deleted != 1 || err != nil {
+		t.Fatalf("DeleteDue() = %d, %v, want 1", deleted, err)
+	}
+	if len(erased) != 1 || erased[0] != "u1" {
+		t.Errorf("erased %v, want [u1]", erased)
+	}
+	if msg := transport.last(); msg.To != "jane@example.com" || msg.Subject != "Your Sponsoration account has been deleted" {
+		t.Errorf("got %q to %s", msg.Subject, msg.To)
+	}
+	if deleted, _ := p.DeleteDue(erase); deleted != 0 {
+		t.Error("account deleted twice")
+	}
+}
+
+func TestPrivacyService_CancelDeletion(t *testing.T) {
+	email, clock, transport := newTestEmailService(t)
+	p := NewPrivacyService(email, newTestTokens(clock), PrivacyConfig{})
+	_, _ = p.ScheduleDeletion(janeContact)
+	first := sentLinkToken(t, transport.last(), "/account/deletion/cancel")
+
+	// Requesting again replaces the first undo link
+	clock.Advance(time.Hour)
+	_, _ = p.ScheduleDeletion(janeContact)
+	second := sentLinkToken(t, transport.last(), "/account/deletion/cancel")
+	if _, err := p.CancelDeletion(first); !errors.Is(err, ErrDeletionNotFound) {
+		t.Errorf("CancelDeletion(first link) error = %v, want ErrDeletionNotFound", err)
+	}
+
+	contact, err := p.CancelDeletion(second)
+	if err != nil || contact != janeContact {
+		t.Fatalf("CancelDeletion() = %+v, %v", contact, err)
+	}
+	if _, err := p.CancelDeletion(second); !errors.Is(err, ErrDeletionNotFound) {
+		t.Errorf("second CancelDeletion() error = %v, want ErrDeletionNotFound", err)
+	}
+
+	clock.Advance(31 * 24 * time.Hour)
+	erase := func(string) error { t.Error("canceled account was erased"); return nil }
+	if deleted, _ := p.DeleteDue(erase); deleted != 0 {
+		t.Errorf("DeleteDue() = %d, want nothing deleted", deleted)
+	}
+}
+
+func TestPrivacyService_DeletionRetries(t *testing.T) {
+	email, clock, transport := newTestEmailService(t)
+	p := NewPrivacyService(email, newTestTokens(clock), PrivacyConfig{})
+	_, _ = p.ScheduleDeletion(janeContact)
+	clock.Advance(30 * 24 * time.Hour)
+
+	erases := 0
+	erase := func(string) error {
+		erases++
+		return nil
+	}
+	transport.err = errors.New("smtp down")
+	if _, err := p.DeleteDue(erase); err == nil {
+		t.Fatal("DeleteDue() error = nil, want the send error")
+	}
+
+	// The email is retried without erasing again
+	transport.err = nil
+	if deleted, err := p.DeleteDue(erase); deleted != 1 || err != nil {
+		t.Fatalf("DeleteDue() retry = %d, %v, want 1", deleted, err)
+	}
+	if erases != 1 {
+		t.Errorf("erased %d times, want once", erases)
+	}
+}
+
+// failCancelStore fails the next Cancel, like a database that is briefly
+// unavailable
+type failCancelStore struct {
+	DeletionStore
+	fail *bool
+}
+
+func (s failCancelStore) Cancel(userID, undoTokenID string) (PendingDeletion, error) {
+	if *s.fail {
+		*s.fail = false
+		return PendingDeletion{}, errors.New("database is locked")
+	}
+	return s.DeletionStore.Cancel(userID, undoTokenID)
+}
+
+func TestPrivacyService_CancelDeletionStoreFailure(t *testing.T) {
+	email, clock, transport := newTestEmailService(t)
+	p := NewPrivacyService(email, newTestTokens(clock), PrivacyConfig{})
+	fail := true
+	p.config.Store = failCancelStore{p.config.Store, &fail}
+	_, _ = p.ScheduleDeletion(janeContact)
+	token := sentLinkToken(t, transport.last(), "/account/deletion/cancel")
+
+	if _, err := p.CancelDeletion(token); err == nil || errors.Is(err, ErrDeletionNotFound) {
+		t.Fatalf("CancelDeletion() error = %v, want the store error", err)
+	}
+
+	// The undo link still works
+	if contact, err := p.CancelDeletion(token); err != nil || contact != janeContact {
+		t.Errorf("CancelDeletion() retry = %+v, %v", contact, err)
+	}
+}
+
+func TestPrivacyService_RescheduleDeletionInProgress(t *testing.T) {
+	email, clock, transport := newTestEmailService(t)
+	p := NewPrivacyService(email, newTestTokens(clock), PrivacyConfig{})
+	_, _ = p.ScheduleDeletion(janeContact)
+	clock.Advance(30 * 24 * time.Hour)
+
+	// The account is erased but the confirmation is still to be sent
+	transport.err = errors.New("smtp down")
+	if _, err := p.DeleteDue(func(string) error { return nil }); err == nil {
+		t.Fatal("DeleteDue() error = nil, want the send error")
+	}
+	transport.err = nil
+	sent := len(transport.messages())
+	if _, err := p.ScheduleDeletion(janeContact); !errors.Is(err, ErrDeletionInProgress) {
+		t.Errorf("ScheduleDeletion() error = %v, want ErrDeletionInProgress", err)
+	}
+	if n := len(transport.messages()); n != sent {
+		t.Errorf("sent %d emails, want no new undo link", n-sent)
+	}
+	if deleted, err := p.DeleteDue(func(string) error { return nil }); deleted != 1 || err != nil {
+		t.Errorf("DeleteDue() retry = %d, %v, want the confirmation sent", deleted, err)
+	}
+}
+
+func TestPrivacyService_ScheduleDeletionSendFailure(t *testing.T) {
+	email, clock, transport := newTestEmailService(t)
+	p := NewPrivacyService(email, newTestTokens(clock), PrivacyConfig{})
+	transport.err = errors.New("smtp down")
+	if _, err := p.ScheduleDeletion(janeContact); err == nil {
+		t.Fatal("ScheduleDeletion() error = nil, want the send error")
+	}
+
+	// Without the undo link the account must not be deleted
+	clock.Advance(31 * 24 * time.Hour)
+	erase := func(string) error { t.Error("account erased without an undo link sent"); return nil }
+	if deleted, _ := p.DeleteDue(erase); deleted != 0 {
+		t.Errorf("DeleteDue() = %d, want nothing deleted", deleted)
+	}
+}
+
+// dueHookStore runs hook after listing due deletions, to interleave a
+// cancellation with DeleteDue
+type dueHookStore struct {
+	DeletionStore
+	hook func()
+}
+
+func (s dueHookStore) Due(now time.Time) ([]PendingDeletion, error) {
+	due, err := s.DeletionStore.Due(now)
+	s.hook()
+	return due, err
+}
+
+func TestPrivacyService_CancelRacesDeleteDue(t *testing.T) {
+	tests := []struct {
+		name string
+		// whileErasing cancels from inside erase instead of right after
+		// DeleteDue listed the deletion
+		whileErasing bool
+		wantDeleted  int
+		wantCancel   error
+	}{
+		{name: "after listing, the account is kept", wantDeleted: 0},
+		{name: "while erasing, too late", whileErasing: true, wantDeleted: 1, wantCancel: ErrDeletionNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email, clock, transport := newTestEmailService(t)
+			p := NewPrivacyService(email, newTestTokens(clock), PrivacyConfig{})
+			_, _ = p.ScheduleDeletion(janeContact)
+			token := sentLinkToken(t, transport.last(), "/account/deletion/cancel")
+			clock.Advance(30 * 24 * time.Hour)
+
+			// The undo link is clicked a second before the deadline, while
+			// DeleteDue is already running on a clock that is past it
+			canceled := errors.New("not canceled")
+			cancelJustInTime := func() {
+				now := clock.Now()
+				clock.Set(now.Add(-time.Second))
+				defer clock.Set(now)
+				_, canceled = p.CancelDeletion(token)
+			}
+			erased := 0
+			erase := func(string) error {
+				erased++
+				if tt.whileErasing {
+					cancelJustInTime()
+				}
+				return nil
+			}
+			if !tt.whileErasing {
+				p.config.Store = dueHookStore{p.config.Store, cancelJustInTime}
+			}
+
+			if deleted, err := p.DeleteDue(erase); deleted != tt.wantDeleted || erased != tt.wantDeleted || err != nil {
+				t.Errorf("DeleteDue() = %d, %v with %d erased, want %d", deleted, err, erased, tt.wantDeleted)
+			}
+			if !errors.Is(canceled, tt.wantCancel) {
+				t.Errorf("CancelDeletion() error = %v, want %v", canceled, tt.wantCancel)
+			}
+		})
+	}
+}
+
+func TestPrivacyService_DataExport(t *testing.T) {
+	email, clock, transport := newTestEmailService(t)
+	p := NewPrivacyService(email, newTestTokens(clock), PrivacyConfig{})
+	if err := p.SendDataExportReady(janeContact, "export-9"); err != nil {
+		t.Fatalf("SendDataExportReady() error = %v", err)
+	}
+	token := sentLinkToken(t, transport.last(), "/account/export/download")
+
+	// The link can be used more than once until it expires
+	for i := 0; i < 2; i++ {
+		export, err := p.DownloadExport(token)
+		if err != nil || export != (DataExport{UserID: "u1", ExportID: "export-9"}) {
+			t.Fatalf("DownloadExport() #%d = %+v, %v", i+1, export, err)
+		}
+	}
+	clock.Advance(7 * 24 * time.Hour)
+	if _, err := p.DownloadExport(token); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("DownloadExport() after 7 days error = %v, want ErrTokenExpired", err)
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"strings"
+	"time"
+)
+
+// Receipt is a completed payment. The same data renders the receipt email
+// and the invoice document.
+type Receipt struct {
+	InvoiceNumber  string
+	IssuedAt       time.Time
+	Currency       string // ISO 4217 code, e.g. "USD"
+	CustomerName   string
+	Items          []LineItem
+	Taxes          []Tax
+	PaymentMethod  PaymentMethod
+	BillingAddress Address
+}
+
+// LineItem is one billed product or service. Prices are in minor units of
+// the receipt currency.
+type LineItem struct {
+	Description string
+	Quantity    int64
+	UnitPrice   int64
+}
+
+// Amount is the line total
+func (l LineItem) Amount() int64 {
+	return l.Quantity * l.UnitPrice
+}
+
+// Tax is charged on the subtotal. Rate is in basis points: 1900 is 19%.
+type Tax struct {
+	Name string
+	Rate int64
+}
+
+// PaymentMethod is the card a payment was made with
+type PaymentMethod struct {
+	Brand string // e.g. "Visa"
+	// Last4 are the last digits of the card number. Anything longer is cut
+	// down to the last four digits.
+	Last4 string
+}
+
+// String writes the card as "Visa •••• 4242"
+func (p PaymentMethod) String() string {
+	var digits []rune
+	for _, r := range p.Last4 {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, r)
+		}
+	}
+	if len(digits) > 4 {
+		digits = digits[len(digits)-4:]
+	}
+	return strings.TrimSpace(p.Brand + " •••• " + string(digits))
+}
+
+// Address is a postal billing address
+type Address struct {
+	Name       string
+	Line1      string
+	Line2      string
+	City       string
+	Region     string
+	PostalCode string
+	Country    string
+}
+
+// Lines returns the non-empty lines of the address
+func (a Address) Lines() []string {
+	city := strings.TrimSpace(strings.Join(nonEmpty(a.PostalCode, a.City), " "))
+	if a.Region != "" {
+		city = strings.TrimSpace(city + ", " + a.Region)
+	}
+	return nonEmpty(a.Name, a.Line1, a.Line2, city, a.Country)
+}
+
+// nonEmpty drops empty strings
+func nonEmpty(values ...string) []string {
+	var kept []string
+	for _, v := range values {
+		if v != "" {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+// TaxAmount is a tax with the amount charged
+type TaxAmount struct {
+	Tax
+	Amount Money
+}
+
+// ReceiptTotals are the sums of a receipt
+type ReceiptTotals struct {
+	Subtotal Money
+	Taxes    []TaxAmount
+	Total    Money
+}
+
+// Totals sums the line items and computes each tax on the subtotal, rounded
+// half away from zero to the minor unit. The total is the sum of the rounded
+// amounts, so the printed figures always add up.
+func (r Receipt) Totals() ReceiptTotals {
+	var subtotal int64
+	for _, item := range r.Items {
+		subtotal += item.Amount()
+	}
+
+	totals := ReceiptTotals{Subtotal: Money{Amount: subtotal, Currency: r.Currency}}
+	total := subtotal
+	for _, tax := range r.Taxes {
+		amount := percentOf(subtotal, tax.Rate)
+		totals.Taxes = append(totals.Taxes, TaxAmount{Tax: tax, Amount: Money{Amount: amount, Currency: r.Currency}})
+		total += amount
+	}
+	totals.Total = Money{Amount: total, Currency: r.Currency}
+	return totals
+}
+
+// SendReceiptEmail sends the receipt of a payment, optionally with the
+// invoice attached
+func (s *EmailService) SendReceiptEmail(email string, r Receipt, attachments ...Attachment) error {
+	msg := receiptEmail(s.RenderContext(), r)
+	msg.To = email
+	msg.Attachments = attachments
+	return s.SendEmail(msg)
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReceipt_Totals(t *testing.T) {
+	r := Receipt{
+		Currency: "USD",
+		Items: []LineItem{
+			{Description: "Post", Quantity: 3, UnitPrice: 333},
+			{Description: "Fee", Quantity: 1, UnitPrice: 1},
+		},
+		Taxes: []Tax{{Name: "State tax", Rate: 625}, {Name: "City tax", Rate: 200}},
+	}
+
+	totals := r.Totals()
+	if totals.Subtotal.Amount != 1000 {
+		t.Errorf("Subtotal = %d, want 1000", totals.Subtotal.Amount)
+	}
+	// 6.25% of 10.00 = 0.625 rounds to 0.63; 2% = 0.20
+	if len(totals.Taxes) != 2 || totals.Taxes[0].Amount.Amount != 63 || totals.Taxes[1].Amount.Amount != 20 {
+		t.Errorf("Taxes = %+v, want 63 and 20", totals.Taxes)
+	}
+	if totals.Total.Amount != 1083 || totals.Total.Currency != "USD" {
+		t.Errorf("Total = %+v, want 1083 USD", totals.Total)
+	}
+}
+
+func TestPaymentMethod_String(t *testing.T) {
+	tests := []struct {
+		p    PaymentMethod
+		want string
+	}{
+		{PaymentMethod{Brand: "Visa", Last4: "4242"}, "Visa •••• 4242"},
+		{PaymentMethod{Brand: "Mastercard", Last4: "5555 4444 3333 1111"}, "Mastercard •••• 1111"},
+		{PaymentMethod{Last4: "0005"}, "•••• 0005"},
+	}
+	for _, tt := range tests {
+		if got := tt.p.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestAddress_Lines(t *testing.T) {
+	a := Address{Name: "Acme Inc.", Line1: "1 Main St", City: "Springfield", Region: "IL", PostalCode: "62701", Country: "United States"}
+	want := []string{"Acme Inc.", "1 Main St", "62701 Springfield, IL", "United States"}
+	if got := a.Lines(); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Lines() = %q, want %q", got, want)
+	}
+}
+
+func TestSendReceiptEmail(t *testing.T) {
+	s, _, transport := newTestEmailService(t, WithLocale("de"))
+	r := Receipt{
+		InvoiceNumber:  "INV-7",
+		IssuedAt:       time.Date(2025, time.March, 14, 0, 0, 0, 0, time.UTC),
+		Currency:       "EUR",
+		Items:          []LineItem{{Description: "Reel <4K>", Quantity: 2, UnitPrice: 123456}},
+		Taxes:          []Tax{{Name: "MwSt.", Rate: 1900}},
+		PaymentMethod:  PaymentMethod{Brand: "Visa", Last4: "4242"},
+		BillingAddress: Address{Name: "Acme GmbH", City: "Berlin", Country: "Germany"},
+	}
+	invoice := Attachment{Filename: "INV-7.pdf", ContentType: "application/pdf", Content: []byte("%PDF")}
+
+	if err := s.SendReceiptEmail("billing@example.com", r, invoice); err != nil {
+		t.Fatalf("SendReceiptEmail() error = %v", err)
+	}
+	msg := transport.last()
+
+	if msg.To != "billing@example.com" || msg.Subject != "Your Sponsoration receipt INV-7" {
+		t.Errorf("sent %q to %q", msg.Subject, msg.To)
+	}
+	if len(msg.Attachments) != 1 || msg.Attachments[0].Filename != "INV-7.pdf" {
+		t.Errorf("Attachments = %+v, want the invoice", msg.Attachments)
+	}
+	for _, want := range []string{
+		"2 × Reel <4K>: 2.469,12\u00a0€",
+		"MwSt. (19%): 469,13\u00a0€",
+		"Total paid: 2.938,25\u00a0€",
+		"Paid with: Visa •••• 4242",
+		"Acme GmbH\nBerlin\nGermany",
+	} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("text should contain %q, got %q", want, msg.Text)
+		}
+	}
+	if !strings.Contains(msg.HTML, "Reel &lt;4K&gt;") {
+		t.Error("HTML should escape line item descriptions")
+	}
+}
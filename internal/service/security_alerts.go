@@ -0,0 +1,140 @@
+package service
+
+import (
+	"time"
+
+	// Embeds the time zone database so user time zones resolve on hosts
+	// without one
+	_ "time/tzdata"
+)
+
+// SecurityEvent is an account change users are alerted about
+type SecurityEvent string
+
+// Security events
+const (
+	SecurityPasswordChanged  SecurityEvent = "password_changed"
+	SecurityNewDeviceSignIn  SecurityEvent = "new_device_sign_in"
+	SecurityTwoFactorChanged SecurityEvent = "two_factor_changed"
+	SecurityAPIKeyCreated    SecurityEvent = "api_key_created"
+)
+
+// PurposeSecurityReport scopes tokens of "this wasn't me" links
+const PurposeSecurityReport TokenPurpose = "security_report"
+
+// SecurityContext describes where and when a security event happened
+type SecurityContext struct {
+	// Time of the event. Defaults to now.
+	Time time.Time
+	// TimeZone is the user's IANA time zone, e.g. "Europe/Berlin". The event
+	// time is shown in UTC when empty or unknown.
+	TimeZone string
+	// Location is the approximate location, e.g. from IP geolocation
+	Location  string
+	IPAddress string
+	UserAgent string
+	// ReportToken is put in the "this wasn't me" link, so the user can lock
+	// the account without signing in. Issue it with PurposeSecurityReport.
+	// Without a token the link goes to the security settings page.
+	ReportToken string
+}
+
+// securityAlert is everything a security alert template needs
+type securityAlert struct {
+	Event SecurityEvent
+	SecurityContext
+	// Detail names what changed, e.g. the API key name. Optional.
+	Detail string
+}
+
+// SendPasswordChangedEmail alerts the user that their password was changed
+func (s *EmailService) SendPasswordChangedEmail(email string, ctx SecurityContext) error {
+	return s.sendSecurityAlert(email, securityAlert{Event: SecurityPasswordChanged, SecurityContext: ctx})
+}
+
+// SendNewDeviceSignInEmail alerts the user of a sign-in from a device they
+// haven't used before
+func (s *EmailService) SendNewDeviceSignInEmail(email string, ctx SecurityContext) error {
+	return s.sendSecurityAlert(email, securityAlert{Event: SecurityNewDeviceSignIn, SecurityContext: ctx})
+}
+
+// SendTwoFactorChangedEmail alerts the user that two-factor authentication
+// was changed. change says how, e.g. "Turned off" or "Authenticator app
+// replaced".
+func (s *EmailService) SendTwoFactorChangedEmail(email, change string, ctx SecurityContext) error {
+	return s.sendSecurityAlert(email, securityAlert{Event: SecurityTwoFactorChanged, SecurityContext: ctx, Detail: change})
+}
+
+// SendAPIKeyCreatedEmail alerts the user that an API key named keyName was
+// created on their account
+func (s *EmailService) SendAPIKeyCreatedEmail(email, keyName string, ctx SecurityContext) error {
+	return s.sendSecurityAlert(email, securityAlert{Event: SecurityAPIKeyCreated, SecurityContext: ctx, Detail: keyName})
+}
+
+// sendSecurityAlert renders and sends one security alert
+func (s *EmailService) sendSecurityAlert(email string, alert securityAlert) error {
+	if alert.Time.IsZero() {
+		alert.Time = s.clock.Now()
+	}
+	link := s.tokenLink("/security/report", alert.ReportToken)
+	if link == "" {
+		link = s.appURL + "/settings/security"
+	}
+
+	msg := securityAlertEmail(s.RenderContext(), alert, link)
+	msg.To = email
+	return s.SendEmail(msg)
+}
+
+// localTime formats the event time in the user's time zone
+func (a securityAlert) localTime() string {
+	t := a.Time.UTC()
+	if a.TimeZone != "" {
+		if loc, err := time.LoadLocation(a.TimeZone); err == nil {
+			t = a.Time.In(loc)
+		}
+	}
+	return t.Format("Monday, January 2, 2006 at 15:04 MST")
+}
+
+// orUnknown fills in missing details
+func orUnknown(s string) string {
+	if s == "" {
+		return "Unknown"
+	}
+	return s
+}
+
+// securityCopy is the wording of one kind of security alert
+type securityCopy struct {
+	subject     string
+	headline    string
+	summary     string
+	detailLabel string
+}
+
+// securityCopies holds the wording of every security event
+var securityCopies = map[SecurityEvent]securityCopy{
+	SecurityPasswordChanged: {
+		subject:  "Your Sponsoration password was changed",
+		headline: "Your Password Was Changed",
+		summary:  "The password of your Sponsoration account was just changed.",
+	},
+	SecurityNewDeviceSignIn: {
+		subject:  "New sign-in to your Sponsoration account",
+		headline: "New Sign-In Detected",
+		summary:  "Your Sponsoration account was just signed in to from a device we haven't seen before.",
+	},
+	SecurityTwoFactorChanged: {
+		subject:     "Two-factor authentication was changed",
+		headline:    "Two-Factor Authentication Changed",
+		summary:     "The two-factor authentication settings of your Sponsoration account were just changed.",
+		detailLabel: "Change",
+	},
+	SecurityAPIKeyCreated: {
+		subject:     "A new API key was created",
+		headline:    "New API Key Created",
+		summary:     "A new API key was just created on your Sponsoration account. It can access your account without a password.",
+		detailLabel: "Key name",
+	},
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSecurityAlerts(t *testing.T) {
+	eventTime := time.Date(2025, time.July, 1, 22, 15, 0, 0, time.UTC)
+	ctx := SecurityContext{
+		Time:      eventTime,
+		TimeZone:  "America/New_York",
+		Location:  "New York, US",
+		IPAddress: "198.51.100.1",
+		UserAgent: `Firefox <script>`,
+	}
+
+	tests := []struct {
+		name        string
+		send        func(s *EmailService) error
+		wantSubject string
+		wantDetail  string
+	}{
+		{
+			name:        "password changed",
+			send:        func(s *EmailService) error { return s.SendPasswordChangedEmail("user@example.com", ctx) },
+			wantSubject: "Your Sponsoration password was changed",
+		},
+		{
+			name:        "new device",
+			send:        func(s *EmailService) error { return s.SendNewDeviceSignInEmail("user@example.com", ctx) },
+			wantSubject: "New sign-in to your Sponsoration account",
+		},
+		{
+			name:        "two factor",
+			send:        func(s *EmailService) error { return s.SendTwoFactorChangedEmail("user@example.com", "Turned off", ctx) },
+			wantSubject: "Two-factor authentication was changed",
+			wantDetail:  "Change: Turned off",
+		},
+		{
+			name:        "api key",
+			send:        func(s *EmailService) error { return s.SendAPIKeyCreatedEmail("user@example.com", "CI deploy key", ctx) },
+			wantSubject: "A new API key was created",
+			wantDetail:  "Key name: CI deploy key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &recordingTransport{}
+			if err := tt.send(NewEmailService(WithTransport(transport))); err != nil {
+				t.Fatalf("send error = %v", err)
+			}
+			msg := transport.last()
+
+			if msg.Subject != tt.wantSubject {
+				t.Errorf("Subject = %q, want %q", msg.Subject, tt.wantSubject)
+			}
+			for _, want := range []string{
+				"When: Tuesday, July 1, 2025 at 18:15 EDT",
+				"Where: New York, US",
+				"IP address: 198.51.100.1",
+				"Device: Firefox <script>",
+				tt.wantDetail,
+			} {
+				if !strings.Contains(msg.Text, want) {
+					t.Errorf("text should contain %q, got %q", want, msg.Text)
+				}
+			}
+			if strings.Contains(msg.HTML, "<script>") || !strings.Contains(msg.HTML, "Firefox &lt;script&gt;") {
+				t.Error("HTML should escape the user agent")
+			}
+			if !strings.Contains(msg.HTML, `class="notice"`) || !strings.Contains(msg.HTML, "This Wasn't Me") {
+				t.Error("HTML should have the security notice and the report button")
+			}
+		})
+	}
+}
+
+func TestSecurityAlerts_ReportLinkAndDefaults(t *testing.T) {
+	s, _, transport := newTestEmailService(t)
+
+	_ = s.SendPasswordChangedEmail("user@example.com", SecurityContext{TimeZone: "Not/AZone"})
+	msg := transport.last()
+	for _, want := range []string{
+		"When: Friday, March 14, 2025 at 09:30 UTC",
+		"Where: Unknown",
+		s.appURL + "/settings/security",
+	} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("text should contain %q, got %q", want, msg.Text)
+		}
+	}
+
+	_ = s.SendPasswordChangedEmail("user@example.com", SecurityContext{ReportToken: "k1.abc.def"})
+	if want := s.appURL + "/security/report?token=k1.abc.def"; !strings.Contains(transport.last().Text, want) {
+		t.Errorf("text should link to %q", want)
+	}
+}
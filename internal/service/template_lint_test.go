@@ -0,0 +1,19 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/sponsoration/api/internal/emaillint"
+)
+
+func TestTemplatesLint(t *testing.T) {
+	for _, tmpl := range Templates() {
+		t.Run(tmpl.Name, func(t *testing.T) {
+			msg := tmpl.Sample(testRenderContext())
+			emaillint.AssertClean(t, tmpl.Name, msg.HTML)
+			for _, issue := range emaillint.CheckPlaceholders(msg.Subject + "\n" + msg.Text) {
+				t.Errorf("%s (text): %s", tmpl.Name, issue)
+			}
+		})
+	}
+}
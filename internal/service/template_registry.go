@@ -0,0 +1,359 @@
+package service
+
+import (
+	"fmt"
+	"time"
+)
+
+// RegisteredTemplate is an email template together with fixture data that
+// renders a representative sample of it
+type RegisteredTemplate struct {
+	Name   string
+	Sample func(rc RenderContext) EmailOptions
+}
+
+// templateRegistry lists every email template the service can send
+var templateRegistry = []RegisteredTemplate{
+	{
+		Name: "verification",
+		Sample: func(rc RenderContext) EmailOptions {
+			return verificationEmail(rc, "ABC123", "https://app.example.com/verify-email?token=SAMPLE-TOKEN", 24*time.Hour)
+		},
+	},
+	{
+		Name: "password_reset",
+		Sample: func(rc RenderContext) EmailOptions {
+			return passwordResetEmail(rc, "RESET456", "Hi John Doe,", "https://app.example.com/reset-password?token=SAMPLE-TOKEN", time.Hour)
+		},
+	},
+	{
+		Name: "magic_login",
+		Sample: func(rc RenderContext) EmailOptions {
+			return magicLoginEmail(rc, "https://app.example.com/magic-login?token=SAMPLE-TOKEN", 15*time.Minute)
+		},
+	},
+	{
+		Name: "email_change_confirm",
+		Sample: func(rc RenderContext) EmailOptions {
+			return emailChangeConfirmEmail(rc, "jane.new@example.com", "https://app.example.com/confirm-email-change?token=SAMPLE-TOKEN", 24*time.Hour)
+		},
+	},
+	{
+		Name: "email_change_notice",
+		Sample: func(rc RenderContext) EmailOptions {
+			return emailChangeNoticeEmail(rc, "jane.new@example.com", "https://app.example.com/revert-email-change?token=SAMPLE-TOKEN", 7*24*time.Hour)
+		},
+	},
+	{
+		Name: "security_password_changed",
+		Sample: func(rc RenderContext) EmailOptions {
+			return securityAlertEmail(rc, sampleSecurityAlert(rc, SecurityPasswordChanged, ""), sampleReportLink)
+		},
+	},
+	{
+		Name: "security_new_device_sign_in",
+		Sample: func(rc RenderContext) EmailOptions {
+			return securityAlertEmail(rc, sampleSecurityAlert(rc, SecurityNewDeviceSignIn, ""), sampleReportLink)
+		},
+	},
+	{
+		Name: "security_two_factor_changed",
+		Sample: func(rc RenderContext) EmailOptions {
+			return securityAlertEmail(rc, sampleSecurityAlert(rc, SecurityTwoFactorChanged, "Turned off"), sampleReportLink)
+		},
+	},
+	{
+		Name: "security_api_key_created",
+		Sample: func(rc RenderContext) EmailOptions {
+			return securityAlertEmail(rc, sampleSecurityAlert(rc, SecurityAPIKeyCreated, "CI deploy key"), sampleReportLink)
+		},
+	},
+	{
+		Name: "invitation",
+		Sample: func(rc RenderContext) EmailOptions {
+			inv := Invitation{InviterName: "Maria Lopez", Organization: "Acme Outdoor", Role: "Campaign Manager"}
+			return invitationEmail(rc, inv,
+				"https://app.example.com/invitations/accept?token=SAMPLE-TOKEN",
+				"https://app.example.com/invitations/decline?token=SAMPLE-TOKEN", 7*24*time.Hour)
+		},
+	},
+	{
+		Name: "deal_proposed",
+		Sample: func(rc RenderContext) EmailOptions {
+			return sampleDealNotification(rc, DealNotification{Event: DealProposed, ActorName: "Acme Outdoor"})
+		},
+	},
+	{
+		Name: "deal_countered",
+		Sample: func(rc RenderContext) EmailOptions {
+			return sampleDealNotification(rc, DealNotification{
+				Event: DealCountered, ActorName: "Jane Smith",
+				PreviousAmount: &Money{Amount: 400000, Currency: "USD"},
+				Message:        "Happy to do it with a second Story for $5,000.",
+			})
+		},
+	},
+	{
+		Name: "deal_accepted",
+		Sample: func(rc RenderContext) EmailOptions {
+			return sampleDealNotification(rc, DealNotification{Event: DealAccepted, ActorName: "Acme Outdoor"})
+		},
+	},
+	{
+		Name: "deal_declined",
+		Sample: func(rc RenderContext) EmailOptions {
+			return sampleDealNotification(rc, DealNotification{
+				Event: DealDeclined, ActorName: "Acme Outdoor",
+				Message: "We've filled our spring campaign. Let's talk again in the fall!",
+			})
+		},
+	},
+	{
+		Name: "deliverable_submitted",
+		Sample: func(rc RenderContext) EmailOptions {
+			return sampleDealNotification(rc, DealNotification{Event: DeliverableSubmitted, ActorName: "Jane Smith", Deliverable: "Instagram Reel"})
+		},
+	},
+	{
+		Name: "deliverable_approved",
+		Sample: func(rc RenderContext) EmailOptions {
+			return sampleDealNotification(rc, DealNotification{Event: DeliverableApproved, ActorName: "Acme Outdoor", Deliverable: "Instagram Reel"})
+		},
+	},
+	{
+		Name: "deal_completed",
+		Sample: func(rc RenderContext) EmailOptions {
+			return sampleDealNotification(rc, DealNotification{Event: DealCompleted, ActorName: "Acme Outdoor"})
+		},
+	},
+	{
+		Name: "receipt",
+		Sample: func(rc RenderContext) EmailOptions {
+			return receiptEmail(rc, sampleReceipt(rc))
+		},
+	},
+	{
+		Name: "meeting_invite",
+		Sample: func(rc RenderContext) EmailOptions {
+			return meetingInviteEmail(rc, sampleMeeting())
+		},
+	},
+	{
+		Name: "meeting_canceled",
+		Sample: func(rc RenderContext) EmailOptions {
+			return meetingCanceledEmail(rc, sampleMeeting())
+		},
+	},
+	{
+		Name: "digest",
+		Sample: func(rc RenderContext) EmailOptions {
+			return digestEmail(rc, sampleDigest(rc))
+		},
+	},
+	{
+		Name: "message_notification",
+		Sample: func(rc RenderContext) EmailOptions {
+			return messageNotificationEmail(rc, sampleMessages(rc), "https://app.example.com/messages/conv-7", 5, 280)
+		},
+	},
+	{
+		Name: "onboarding_complete_profile",
+		Sample: func(rc RenderContext) EmailOptions {
+			return sampleOnboardingEmail(rc, 0)
+		},
+	},
+	{
+		Name: "onboarding_connect_socials",
+		Sample: func(rc RenderContext) EmailOptions {
+			return sampleOnboardingEmail(rc, 1)
+		},
+	},
+	{
+		Name: "onboarding_browse_sponsors",
+		Sample: func(rc RenderContext) EmailOptions {
+			return sampleOnboardingEmail(rc, 2)
+		},
+	},
+	{
+		Name: "deadline_reminder",
+		Sample: func(rc RenderContext) EmailOptions {
+			return deadlineReminderEmail(rc, sampleDeliverable(rc), 24*time.Hour, "https://app.example.com/deals/deal-42")
+		},
+	},
+	{
+		Name: "account_deletion_scheduled",
+		Sample: func(rc RenderContext) EmailOptions {
+			return deletionScheduledEmail(rc, "Jane", rc.Now.Add(30*24*time.Hour),
+				"https://app.example.com/account/deletion/cancel?token=SAMPLE-TOKEN")
+		},
+	},
+	{
+		Name: "account_deleted",
+		Sample: func(rc RenderContext) EmailOptions {
+			return accountDeletedEmail(rc, "Jane")
+		},
+	},
+	{
+		Name: "data_export_ready",
+		Sample: func(rc RenderContext) EmailOptions {
+			return dataExportReadyEmail(rc, "Jane", "https://app.example.com/account/export/download?token=SAMPLE-TOKEN",
+				7*24*time.Hour, rc.Now.Add(7*24*time.Hour))
+		},
+	},
+	{
+		Name: "welcome",
+		Sample: func(rc RenderContext) EmailOptions {
+			return welcomeEmail(rc, "Jane Smith", "https://app.example.com")
+		},
+	},
+}
+
+// sampleReportLink is the "this wasn't me" link of security alert samples
+const sampleReportLink = "https://app.example.com/security/report?token=SAMPLE-TOKEN"
+
+// sampleSecurityAlert is a security alert from a browser in Berlin
+func sampleSecurityAlert(rc RenderContext, event SecurityEvent, detail string) securityAlert {
+	return securityAlert{
+		Event: event,
+		SecurityContext: SecurityContext{
+			Time:      rc.Now,
+			TimeZone:  "Europe/Berlin",
+			Location:  "Berlin, Germany",
+			IPAddress: "203.0.113.7",
+			UserAgent: "Chrome 122 on macOS",
+		},
+		Detail: detail,
+	}
+}
+
+// sampleDealNotification fills in the sample deal and renders n
+func sampleDealNotification(rc RenderContext, n DealNotification) EmailOptions {
+	n.Deal = Deal{
+		ID:          "deal-42",
+		Title:       "Spring Trail Collection Launch",
+		BrandName:   "Acme Outdoor",
+		CreatorName: "Jane Smith",
+		Amount:      Money{Amount: 500000, Currency: "USD"},
+		Deadline:    time.Date(2025, time.April, 30, 0, 0, 0, 0, time.UTC),
+	}
+	msg, err := dealNotificationEmail(rc, n, "https://app.example.com/deals/deal-42")
+	if err != nil {
+		// The samples only use events with wording
+		panic(err)
+	}
+	return msg
+}
+
+// sampleReceipt is a sponsor's payment for a campaign with German VAT
+func sampleReceipt(rc RenderContext) Receipt {
+	return Receipt{
+		InvoiceNumber: "INV-2025-0042",
+		IssuedAt:      rc.Now,
+		Currency:      "EUR",
+		CustomerName:  "Acme Outdoor GmbH",
+		Items: []LineItem{
+			{Description: "Instagram Reel – Spring Trail Collection", Quantity: 1, UnitPrice: 350000},
+			{Description: "Instagram Story", Quantity: 3, UnitPrice: 33333},
+			{Description: "Platform fee", Quantity: 1, UnitPrice: 4999},
+		},
+		Taxes:         []Tax{{Name: "VAT", Rate: 1900}},
+		PaymentMethod: PaymentMethod{Brand: "Visa", Last4: "4242"},
+		BillingAddress: Address{
+			Name:       "Acme Outdoor GmbH",
+			Line1:      "Bergstraße 12",
+			City:       "München",
+			PostalCode: "80331",
+			Country:    "Germany",
+		},
+	}
+}
+
+// sampleMeeting is a deal kickoff call scheduled from Berlin
+func sampleMeeting() Meeting {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	start := time.Date(2025, time.March, 20, 15, 0, 0, 0, berlin)
+	return Meeting{
+		ID:          "kickoff-deal-42",
+		Title:       "Kickoff: Spring Trail Collection Launch",
+		Description: "Agenda:\n1. Campaign goals\n2. Content calendar\n3. Approval process",
+		Start:       start,
+		End:         start.Add(30 * time.Minute),
+		Location:    "Google Meet",
+		URL:         "https://meet.example.com/abc-defg-hij",
+		Organizer:   Participant{Name: "Maria Lopez", Email: "maria@acme.example"},
+		Attendees: []Participant{
+			{Name: "Maria Lopez", Email: "maria@acme.example"},
+			{Name: "Jane Smith", Email: "jane@example.com"},
+		},
+	}
+}
+
+// sampleDigest is a creator's daily digest with offers and messages
+func sampleDigest(rc RenderContext) digest {
+	events := []DigestEvent{
+		{Kind: DigestOffers, Title: "Acme Outdoor sent you an offer", Summary: "Spring Trail Collection Launch · $5,000.00",
+			Link: "https://app.example.com/deals/deal-42", OccurredAt: rc.Now.Add(-2 * time.Hour)},
+		{Kind: DigestOffers, Title: "Northwind Coffee sent you an offer", Summary: "Cold Brew Summer · $1,200.00",
+			Link: "https://app.example.com/deals/deal-43", OccurredAt: rc.Now.Add(-5 * time.Hour)},
+		{Kind: DigestMessages, Title: "Maria Lopez sent you 3 messages", Summary: "Can we move the kickoff call to Thursday?",
+			Link: "https://app.example.com/messages/conv-7", OccurredAt: rc.Now.Add(-time.Hour)},
+	}
+	return digest{
+		Name:     "Jane",
+		Cadence:  DigestDaily,
+		Sections: groupDigestEvents(events, 5),
+		Total:    len(events),
+		Settings: "https://app.example.com/settings/notifications",
+	}
+}
+
+// sampleMessages are a brand's burst of chat messages to a creator
+func sampleMessages(rc RenderContext) []ChatMessage {
+	msg := ChatMessage{
+		ConversationID:    "conv-7",
+		ConversationTitle: "Spring Trail Collection Launch",
+		RecipientEmail:    "jane@example.com",
+		SenderName:        "Maria Lopez",
+	}
+	var messages []ChatMessage
+	for i, body := range []string{
+		"Hi Jane! Thanks for sending over the draft.",
+		"Can we move the kickoff call to Thursday?",
+		"Also, could the Reel mention the new trail shoes in the first few seconds?",
+	} {
+		m := msg
+		m.ID = fmt.Sprintf("msg-%d", i+1)
+		m.Body = body
+		m.SentAt = rc.Now.Add(time.Duration(i) * 20 * time.Second)
+		messages = append(messages, m)
+	}
+	return messages
+}
+
+// sampleOnboardingEmail renders a step of the default onboarding sequence
+func sampleOnboardingEmail(rc RenderContext, step int) EmailOptions {
+	s := DefaultOnboardingSequence().Steps[step]
+	return onboardingEmail(rc, s, "Jane", "https://app.example.com"+s.Path,
+		"https://app.example.com/onboarding/unsubscribe?token=SAMPLE-TOKEN")
+}
+
+// sampleDeliverable is a Reel due a day after rc.Now
+func sampleDeliverable(rc RenderContext) Deliverable {
+	return Deliverable{
+		ID:           "deliverable-7",
+		DealID:       "deal-42",
+		DealTitle:    "Spring Trail Collection Launch",
+		Title:        "Instagram Reel",
+		CreatorEmail: "jane@example.com",
+		CreatorName:  "Jane",
+		Deadline:     rc.Now.Add(24 * time.Hour).UTC(),
+	}
+}
+
+// Templates returns every registered email template. Used by the template
+// linter, golden tests and previews.
+func Templates() []RegisteredTemplate {
+	templates := make([]RegisteredTemplate, len(templateRegistry))
+	copy(templates, templateRegistry)
+	return templates
+}
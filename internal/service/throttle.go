@@ -0,0 +1,297 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited matches every *RateLimitError with errors.Is
+var ErrRateLimited = errors.New("rate limited")
+
+// ThrottleAction identifies a kind of send that is limited separately
+type ThrottleAction string
+
+// Throttled actions
+const (
+	ThrottleVerification  ThrottleAction = "verification"
+	ThrottlePasswordReset ThrottleAction = "password_reset"
+	ThrottleMagicLogin    ThrottleAction = "magic_login"
+)
+
+// Throttle scopes reported in RateLimitError
+const (
+	ScopeRecipient = "recipient"
+	ScopeClient    = "client"
+)
+
+// RateLimitError is returned when a send is refused. RetryAt is the earliest
+// time the same send can succeed.
+type RateLimitError struct {
+	Action     ThrottleAction
+	Scope      string
+	RetryAt    time.Time
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s emails rate limited per %s: retry after %s", e.Action, e.Scope, e.RetryAfter.Round(time.Second))
+}
+
+// Is makes errors.Is(err, ErrRateLimited) true
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// RateLimit allows Max sends per sliding Window, at least Cooldown apart.
+// Zero fields take the value of DefaultThrottlePolicy; a negative Max or
+// Cooldown leaves that part of the limit off.
+type RateLimit struct {
+	Max      int
+	Window   time.Duration
+	Cooldown time.Duration
+}
+
+// ThrottlePolicy limits one action per recipient address and per client,
+// where the client is whatever the caller identifies requesters by: an IP,
+// an account ID or a device fingerprint
+type ThrottlePolicy struct {
+	Recipient RateLimit
+	Client    RateLimit
+}
+
+// ThrottleConfig holds the policy of each throttled action. Fields left zero
+// get their value from DefaultThrottlePolicy.
+type ThrottleConfig struct {
+	Verification  ThrottlePolicy
+	PasswordReset ThrottlePolicy
+	MagicLogin    ThrottlePolicy
+}
+
+// DefaultThrottlePolicy allows a recipient 5 emails an hour, one a minute,
+// and a client 20 emails an hour
+func DefaultThrottlePolicy() ThrottlePolicy {
+	return ThrottlePolicy{
+		Recipient: RateLimit{Max: 5, Window: time.Hour, Cooldown: time.Minute},
+		Client:    RateLimit{Max: 20, Window: time.Hour},
+	}
+}
+
+// withDefaults fills in zero values
+func (c ThrottleConfig) withDefaults() ThrottleConfig {
+	defaults := DefaultThrottlePolicy()
+	for _, p := range []*ThrottlePolicy{&c.Verification, &c.PasswordReset, &c.MagicLogin} {
+		p.Recipient = p.Recipient.withDefaults(defaults.Recipient)
+		p.Client = p.Client.withDefaults(defaults.Client)
+	}
+	return c
+}
+
+// withDefaults fills in the zero fields of l from d
+func (l RateLimit) withDefaults(d RateLimit) RateLimit {
+	if l.Max == 0 {
+		l.Max = d.Max
+	}
+	if l.Window <= 0 {
+		l.Window = d.Window
+	}
+	if l.Cooldown == 0 {
+		l.Cooldown = d.Cooldown
+	}
+	return l
+}
+
+// policy returns the policy of action
+func (c ThrottleConfig) policy(action ThrottleAction) ThrottlePolicy {
+	switch action {
+	case ThrottlePasswordReset:
+		return c.PasswordReset
+	case ThrottleMagicLogin:
+		return c.MagicLogin
+	default:
+		return c.Verification
+	}
+}
+
+// RateLimitKey is one send history a hit is checked against and recorded
+// under. Hits before Since no longer matter and may be forgotten.
+type RateLimitKey struct {
+	Key   string
+	Since time.Time
+}
+
+// RateLimitStore keeps the send history rate limits are computed from
+type RateLimitStore interface {
+	// Take passes check the hits recorded under each key at or after its
+	// Since, oldest first, and records a hit at at under every key if check
+	// returns nil. Checking and recording are one atomic step, so
+	// concurrent callers sharing the store can't both pass the check.
+	Take(keys []RateLimitKey, at time.Time, check func(hits [][]time.Time) error) error
+	// Release removes one hit recorded at at under each key
+	Release(keys []RateLimitKey, at time.Time) error
+}
+
+// MemoryRateLimitStore is an in-process RateLimitStore
+type MemoryRateLimitStore struct {
+	mu   sync.Mutex
+	hits map[string][]time.Time
+}
+
+// NewMemoryRateLimitStore creates an empty in-memory rate limit store
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{hits: map[string][]time.Time{}}
+}
+
+// Take checks and records a hit under keys while holding the store lock
+func (m *MemoryRateLimitStore) Take(keys []RateLimitKey, at time.Time, check func(hits [][]time.Time) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hits := make([][]time.Time, len(keys))
+	for i, k := range keys {
+		kept := m.hits[k.Key][:0]
+		for _, t := range m.hits[k.Key] {
+			if !t.Before(k.Since) {
+				kept = append(kept, t)
+			}
+		}
+		m.hits[k.Key] = kept
+		hits[i] = slices.Clone(kept)
+	}
+	if err := check(hits); err != nil {
+		return err
+	}
+	for _, k := range keys {
+		m.hits[k.Key] = append(m.hits[k.Key], at)
+	}
+	return nil
+}
+
+// Release removes one hit recorded at at under each key
+func (m *MemoryRateLimitStore) Release(keys []RateLimitKey, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, k := range keys {
+		if i := slices.IndexFunc(m.hits[k.Key], at.Equal); i >= 0 {
+			m.hits[k.Key] = slices.Delete(m.hits[k.Key], i, i+1)
+		}
+	}
+	return nil
+}
+
+// Throttle refuses auth email sends that exceed per-recipient or per-client
+// limits, so a bot can't flood an inbox or burn the sender reputation
+type Throttle struct {
+	store  RateLimitStore
+	clock  Clock
+	config ThrottleConfig
+}
+
+// NewThrottle creates a throttle that keeps its history in store
+func NewThrottle(store RateLimitStore, clock Clock, config ThrottleConfig) *Throttle {
+	return &Throttle{
+		store:  store,
+		clock:  clock,
+		config: config.withDefaults(),
+	}
+}
+
+// Allow records a send of action to recipient requested by client, or
+// returns a *RateLimitError without recording it if a limit is hit. An empty
+// client skips the client limit.
+func (t *Throttle) Allow(action ThrottleAction, recipient, client string) error {
+	_, _, err := t.take(action, recipient, client)
+	return err
+}
+
+// Do runs send if Allow allows it. A send that fails is taken back, so it
+// doesn't count against the limits.
+func (t *Throttle) Do(action ThrottleAction, recipient, client string, send func() error) error {
+	keys, at, err := t.take(action, recipient, client)
+	if err != nil {
+		return err
+	}
+	if err := send(); err != nil {
+		if err := t.store.Release(keys, at); err != nil {
+			log.Printf("❌ Failed to release rate limit hit: %v", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// take records a send like Allow and returns where and when it was recorded
+func (t *Throttle) take(action ThrottleAction, recipient, client string) ([]RateLimitKey, time.Time, error) {
+	policy := t.config.policy(action)
+	now := t.clock.Now()
+
+	type check struct {
+		scope string
+		limit RateLimit
+	}
+	checks := []check{{ScopeRecipient, policy.Recipient}}
+	keys := []RateLimitKey{{throttleKey(action, ScopeRecipient, normalizeAddress(recipient)), now.Add(-policy.Recipient.span())}}
+	if client != "" {
+		checks = append(checks, check{ScopeClient, policy.Client})
+		keys = append(keys, RateLimitKey{throttleKey(action, ScopeClient, client), now.Add(-policy.Client.span())})
+	}
+
+	err := t.store.Take(keys, now, func(hits [][]time.Time) error {
+		for i, c := range checks {
+			if retryAt := c.limit.retryAt(hits[i], now); retryAt.After(now) {
+				return &RateLimitError{Action: action, Scope: c.scope, RetryAt: retryAt, RetryAfter: retryAt.Sub(now)}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return keys, now, nil
+}
+
+// retryAt returns when the next hit after hits is allowed by l, which is not
+// after now if it is allowed already
+func (l RateLimit) retryAt(hits []time.Time, now time.Time) time.Time {
+	if len(hits) == 0 {
+		return now
+	}
+
+	retryAt := now
+	if l.Cooldown > 0 {
+		if next := hits[len(hits)-1].Add(l.Cooldown); next.After(retryAt) {
+			retryAt = next
+		}
+	}
+	if l.Max > 0 {
+		var inWindow []time.Time
+		for _, at := range hits {
+			if at.After(now.Add(-l.Window)) {
+				inWindow = append(inWindow, at)
+			}
+		}
+		if len(inWindow) >= l.Max {
+			// The window frees up once enough of the oldest hits age out
+			if next := inWindow[len(inWindow)-l.Max].Add(l.Window); next.After(retryAt) {
+				retryAt = next
+			}
+		}
+	}
+	return retryAt
+}
+
+// span is how far back history matters to limit
+func (l RateLimit) span() time.Duration {
+	if l.Window > l.Cooldown {
+		return l.Window
+	}
+	return l.Cooldown
+}
+
+// throttleKey is the store key of one limited counter
+func throttleKey(action ThrottleAction, scope, id string) string {
+	return "throttle:" + string(action) + ":" + scope + ":" + id
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func newTestThrottle(config ThrottleConfig) (*Throttle, *FakeClock) {
+	clock := NewFakeClock(goldenTime)
+	return NewThrottle(NewMemoryRateLimitStore(), clock, config), clock
+}
+
+// retryAfter returns the RetryAfter of a *RateLimitError, or fails the test
+func retryAfter(t *testing.T, err error, scope string) time.Duration {
+	t.Helper()
+	var limited *RateLimitError
+	if !errors.As(err, &limited) {
+		t.Fatalf("error = %v, want *RateLimitError", err)
+	}
+	if limited.Scope != scope {
+		t.Errorf("Scope = %q, want %q", limited.Scope, scope)
+	}
+	if !errors.Is(err, ErrRateLimited) {
+		t.Error("errors.Is(err, ErrRateLimited) = false")
+	}
+	return limited.RetryAfter
+}
+
+func TestThrottle_Allow(t *testing.T) {
+	tests := []struct {
+		name   string
+		config ThrottleConfig
+		action ThrottleAction
+		// recipient is the recipient of send i, the last one is refused
+		recipient func(i int) string
+		client    string
+		sends     int
+		gap       time.Duration
+		wantScope string
+		wantRetry time.Duration
+		// other is limited separately and still allowed
+		other func(th *Throttle) error
+	}{
+		{
+			name:   "recipient cooldown",
+			action: ThrottleVerification,
+			recipient: func(i int) string {
+				if i == 0 {
+					return "user@example.com"
+				}
+				return "User@Example.com"
+			},
+			sends:     1,
+			gap:       20 * time.Second,
+			wantScope: ScopeRecipient,
+			wantRetry: 40 * time.Second,
+			other: func(th *Throttle) error {
+				return th.Allow(ThrottleMagicLogin, "user@example.com", "")
+			},
+		},
+		{
+			// The first send ages out of the window 30 minutes later
+			name:      "recipient window",
+			config:    ThrottleConfig{PasswordReset: ThrottlePolicy{Recipient: RateLimit{Max: 3, Window: time.Hour}}},
+			action:    ThrottlePasswordReset,
+			recipient: func(int) string { return "user@example.com" },
+			sends:     3,
+			gap:       10 * time.Minute,
+			wantScope: ScopeRecipient,
+			wantRetry: 30 * time.Minute,
+		},
+		{
+			name:      "client",
+			config:    ThrottleConfig{Verification: ThrottlePolicy{Client: RateLimit{Max: 2, Window: time.Hour}}},
+			action:    ThrottleVerification,
+			recipient: func(i int) string { return fmt.Sprintf("victim%d@example.com", i) },
+			client:    "203.0.113.7",
+			sends:     2,
+			wantScope: ScopeClient,
+			wantRetry: time.Hour,
+			other: func(th *Throttle) error {
+				return th.Allow(ThrottleVerification, "victim9@example.com", "198.51.100.1")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			throttle, clock := newTestThrottle(tt.config)
+			for i := 0; i < tt.sends; i++ {
+				if err := throttle.Allow(tt.action, tt.recipient(i), tt.client); err != nil {
+					t.Fatalf("Allow() #%d error = %v", i+1, err)
+				}
+				clock.Advance(tt.gap)
+			}
+
+			err := throttle.Allow(tt.action, tt.recipient(tt.sends), tt.client)
+			if got := retryAfter(t, err, tt.wantScope); got != tt.wantRetry {
+				t.Errorf("RetryAfter = %v, want %v", got, tt.wantRetry)
+			}
+			if tt.other != nil {
+				if err := tt.other(throttle); err != nil {
+					t.Errorf("Allow() of other error = %v", err)
+				}
+			}
+
+			clock.Advance(tt.wantRetry)
+			if err := throttle.Allow(tt.action, tt.recipient(tt.sends), tt.client); err != nil {
+				t.Errorf("Allow() after RetryAfter error = %v", err)
+			}
+		})
+	}
+}
+
+func TestVerificationService_Throttled(t *testing.T) {
+	email, clock, transport := newTestEmailService(t)
+	v := NewVerificationService(email, NewMemoryCodeStore(), VerificationConfig{})
+	v.config.Throttle = NewThrottle(NewMemoryRateLimitStore(), clock, ThrottleConfig{})
+
+	if err := v.SendCodeFrom("user@example.com", "203.0.113.7"); err != nil {
+		t.Fatalf("SendCodeFrom() error = %v", err)
+	}
+	code := sentCode(t, transport.last())
+
+	if err := v.SendCodeFrom("user@example.com", "203.0.113.7"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("second SendCodeFrom() error = %v, want ErrRateLimited", err)
+	}
+	if n := len(transport.messages()); n != 1 {
+		t.Errorf("sent %d emails, want 1", n)
+	}
+	if err := v.Verify("user@example.com", code); err != nil {
+		t.Errorf("refused resend should keep the pending code valid, Verify() error = %v", err)
+	}
+}
+
+func TestThrottle_FieldDefaults(t *testing.T) {
+	// Only the window max is set; the cooldown still defaults to a minute
+	throttle, _ := newTestThrottle(ThrottleConfig{
+		MagicLogin: ThrottlePolicy{Recipient: RateLimit{Max: 10}},
+	})
+
+	_ = throttle.Allow(ThrottleMagicLogin, "user@example.com", "")
+	err := throttle.Allow(ThrottleMagicLogin, "user@example.com", "")
+	if got := retryAfter(t, err, ScopeRecipient); got != time.Minute {
+		t.Errorf("RetryAfter = %v, want 1m", got)
+	}
+
+	// A negative cooldown turns it off
+	throttle, _ = newTestThrottle(ThrottleConfig{
+		MagicLogin: ThrottlePolicy{Recipient: RateLimit{Cooldown: -1}},
+	})
+	for i := 0; i < 5; i++ {
+		if err := throttle.Allow(ThrottleMagicLogin, "user@example.com", ""); err != nil {
+			t.Fatalf("Allow() #%d error = %v", i+1, err)
+		}
+	}
+	if err := throttle.Allow(ThrottleMagicLogin, "user@example.com", ""); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("sixth Allow() error = %v, want the default Max of 5 to apply", err)
+	}
+}
+
+func TestThrottle_FailedSendIsNotCounted(t *testing.T) {
+	throttle, _ := newTestThrottle(ThrottleConfig{})
+	failed := errors.New("transport down")
+
+	err := throttle.Do(ThrottlePasswordReset, "user@example.com", "203.0.113.7", func() error { return failed })
+	if !errors.Is(err, failed) {
+		t.Fatalf("Do() error = %v, want the send error", err)
+	}
+	// Without the failed send counted, the cooldown doesn't apply
+	if err := throttle.Do(ThrottlePasswordReset, "user@example.com", "203.0.113.7", func() error { return nil }); err != nil {
+		t.Fatalf("Do() after a failed send error = %v", err)
+	}
+	err = throttle.Do(ThrottlePasswordReset, "user@example.com", "203.0.113.7", func() error { return nil })
+	retryAfter(t, err, ScopeRecipient)
+}
+
+func TestEmailService_Throttled(t *testing.T) {
+	tests := []struct {
+		name  string
+		scope string
+		send  func(s *EmailService, i int) error
+	}{
+		{
+			name:  "password reset per recipient",
+			scope: ScopeRecipient,
+			send: func(s *EmailService, _ int) error {
+				return s.SendPasswordResetEmail("user@example.com", "RESET123")
+			},
+		},
+		{
+			name:  "password reset per client",
+			scope: ScopeClient,
+			send: func(s *EmailService, i int) error {
+				return s.SendPasswordResetEmailFrom(fmt.Sprintf("user%d@example.com", i), "203.0.113.7", "RESET123", "", time.Hour)
+			},
+		},
+		{
+			name:  "verification per recipient",
+			scope: ScopeRecipient,
+			send: func(s *EmailService, _ int) error {
+				return s.SendVerificationEmail("user@example.com", "ABC123")
+			},
+		},
+		{
+			name:  "verification per client",
+			scope: ScopeClient,
+			send: func(s *EmailService, i int) error {
+				return s.SendVerificationEmailFrom(fmt.Sprintf("user%d@example.com", i), "203.0.113.7", "ABC123", "", time.Hour)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			throttle, clock := newTestThrottle(ThrottleConfig{
+				Verification:  ThrottlePolicy{Client: RateLimit{Max: 2}},
+				PasswordReset: ThrottlePolicy{Client: RateLimit{Max: 2}},
+			})
+			email, _, transport := newTestEmailService(t, WithClock(clock), WithThrottle(throttle))
+
+			// The recipient cooldown stops the second send, the client
+			// limit the third
+			sends := 2
+			if tt.scope == ScopeClient {
+				sends = 3
+			}
+			var err error
+			for i := 0; i < sends; i++ {
+				err = tt.send(email, i)
+				if i < sends-1 && err != nil {
+					t.Fatalf("send #%d error = %v", i+1, err)
+				}
+			}
+			retryAfter(t, err, tt.scope)
+			if n := len(transport.messages()); n != sends-1 {
+				t.Errorf("sent %d emails, want %d", n, sends-1)
+			}
+		})
+	}
+}
+
+func TestVerificationService_UsesEmailThrottle(t *testing.T) {
+	email, clock, transport := newTestEmailService(t)
+	v := NewVerificationService(email, NewMemoryCodeStore(), VerificationConfig{})
+	v.email.throttle = NewThrottle(NewMemoryRateLimitStore(), clock, ThrottleConfig{})
+
+	if err := v.SendCodeFrom("user@example.com", "203.0.113.7"); err != nil {
+		t.Fatalf("SendCodeFrom() error = %v", err)
+	}
+	// The send is counted once, by the service
+	clock.Advance(time.Minute)
+	if err := v.SendCodeFrom("user@example.com", "203.0.113.7"); err != nil {
+		t.Fatalf("SendCodeFrom() after the cooldown error = %v", err)
+	}
+	if err := v.SendCodeFrom("user@example.com", "203.0.113.7"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("third SendCodeFrom() error = %v, want ErrRateLimited", err)
+	}
+	if n := len(transport.messages()); n != 2 {
+		t.Errorf("sent %d emails, want 2", n)
+	}
+}
@@ -0,0 +1,350 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Token errors returned by TokenService
+var (
+	ErrTokenMalformed = errors.New("token malformed")
+	ErrTokenSignature = errors.New("token signature invalid")
+	ErrTokenExpired   = errors.New("token expired")
+	ErrTokenUsed      = errors.New("token already used")
+	ErrTokenPurpose   = errors.New("token issued for a different purpose")
+	ErrTokenMismatch  = errors.New("token bound to a different device")
+)
+
+// TokenPurpose scopes a token to one flow so a reset token can't verify an
+// email and vice versa
+type TokenPurpose string
+
+// Token purposes
+const (
+	PurposeEmailVerification TokenPurpose = "verify_email"
+	PurposePasswordReset     TokenPurpose = "reset_password"
+	PurposeMagicLogin        TokenPurpose = "magic_login"
+	PurposeEmailChange       TokenPurpose = "change_email"
+	PurposeEmailChangeRevert TokenPurpose = "revert_email_change"
+)
+
+// SigningKey is an HMAC secret identified by ID. The ID is embedded in
+// tokens so the right key can be found after rotation.
+type SigningKey struct {
+	ID     string
+	Secret []byte
+}
+
+// Keyring holds the active signing key and retired keys that are still
+// accepted for verification
+type Keyring struct {
+	keys []SigningKey
+}
+
+// NewKeyring creates a keyring that signs with active and also verifies
+// tokens signed with any of previous
+func NewKeyring(active SigningKey, previous ...SigningKey) *Keyring {
+	return &Keyring{keys: append([]SigningKey{active}, previous...)}
+}
+
+// ParseKeyring parses "id:base64secret,id:base64secret". The first key is
+// the active one. This is the format of TOKEN_SIGNING_KEYS.
+func ParseKeyring(spec string) (*Keyring, error) {
+	var keys []SigningKey
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(part, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("invalid signing key %q: want id:base64secret", part)
+		}
+		// The ID is the first dot-separated field of every token
+		if strings.Contains(id, ".") {
+			return nil, fmt.Errorf("invalid signing key %q: the ID must not contain '.'", id)
+		}
+		secret, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid signing key %q: %w", id, err)
+		}
+		if len(secret) < 32 {
+			return nil, fmt.Errorf("signing key %q is too short: need at least 32 bytes", id)
+		}
+		keys = append(keys, SigningKey{ID: id, Secret: secret})
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no signing keys configured")
+	}
+	return NewKeyring(keys[0], keys[1:]...), nil
+}
+
+// active returns the key new tokens are signed with
+func (k *Keyring) active() SigningKey {
+	return k.keys[0]
+}
+
+// lookup finds a key by ID
+func (k *Keyring) lookup(id string) (SigningKey, bool) {
+	for _, key := range k.keys {
+		if key.ID == id {
+			return key, true
+		}
+	}
+	return SigningKey{}, false
+}
+
+// TokenClaims is the signed content of a token
+type TokenClaims struct {
+	ID        string
+	Purpose   TokenPurpose
+	Subject   string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	// Binding is a keyed hash of the value the token was bound to with
+	// IssueBound, or empty
+	Binding string
+}
+
+// tokenPayload is the wire form of TokenClaims
+type tokenPayload struct {
+	ID        string `json:"jti"`
+	Purpose   string `json:"pur"`
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	Binding   string `json:"bnd,omitempty"`
+}
+
+// UsedTokenStore remembers consumed token IDs until the tokens expire
+type UsedTokenStore interface {
+	// MarkUsed records id as used. firstUse is false if it was already
+	// recorded.
+	MarkUsed(id string, expiresAt time.Time) (firstUse bool, err error)
+	// DeleteExpired forgets tokens that expired before the given time
+	DeleteExpired(before time.Time) error
+}
+
+// MemoryUsedTokenStore is an in-process UsedTokenStore
+type MemoryUsedTokenStore struct {
+	mu   sync.Mutex
+	used map[string]time.Time
+}
+
+// NewMemoryUsedTokenStore creates an empty in-memory used token store
+func NewMemoryUsedTokenStore() *MemoryUsedTokenStore {
+	return &MemoryUsedTokenStore{used: map[string]time.Time{}}
+}
+
+// MarkUsed records id as used
+func (m *MemoryUsedTokenStore) MarkUsed(id string, expiresAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.used[id]; ok {
+		return false, nil
+	}
+	m.used[id] = expiresAt
+	return true, nil
+}
+
+// DeleteExpired forgets tokens that expired before the given time
+func (m *MemoryUsedTokenStore) DeleteExpired(before time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, expiresAt := range m.used {
+		if expiresAt.Before(before) {
+			delete(m.used, id)
+		}
+	}
+	return nil
+}
+
+// TokenService issues and checks HMAC-signed, expiring, single-use tokens
+// for links in emails. Tokens look like "<key id>.<payload>.<signature>",
+// all base64url encoded.
+type TokenService struct {
+	keys  *Keyring
+	used  UsedTokenStore
+	clock Clock
+}
+
+// NewTokenService creates a token service. used tracks consumed tokens.
+func NewTokenService(keys *Keyring, used UsedTokenStore, clock Clock) *TokenService {
+	return &TokenService{keys: keys, used: used, clock: clock}
+}
+
+// Issue returns a token for subject that is valid for ttl
+func (t *TokenService) Issue(purpose TokenPurpose, subject string, ttl time.Duration) (string, error) {
+	return t.issue(purpose, subject, nil, ttl)
+}
+
+// IssueBound is like Issue but binds the token to a caller-chosen value such
+// as a device fingerprint. Only a keyed hash of binding is embedded; check it
+// with ConsumeBound.
+func (t *TokenService) IssueBound(purpose TokenPurpose, subject, binding string, ttl time.Duration) (string, error) {
+	return t.issue(purpose, subject, &binding, ttl)
+}
+
+// issue signs a new token, optionally bound
+func (t *TokenService) issue(purpose TokenPurpose, subject string, binding *string, ttl time.Duration) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to generate token id: %w", err)
+	}
+
+	key := t.keys.active()
+	bound := ""
+	if binding != nil {
+		bound = hashBinding(key.Secret, *binding)
+	}
+
+	now := t.clock.Now()
+	payload, err := json.Marshal(tokenPayload{
+		ID:        base64.RawURLEncoding.EncodeToString(id),
+		Purpose:   string(purpose),
+		Subject:   subject,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+		Binding:   bound,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode token: %w", err)
+	}
+
+	signed := key.ID + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign(key.Secret, signed)), nil
+}
+
+// Parse checks the signature, purpose and expiry of token without
+// consuming it
+func (t *TokenService) Parse(token string, purpose TokenPurpose) (TokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return TokenClaims{}, ErrTokenMalformed
+	}
+
+	key, ok := t.keys.lookup(parts[0])
+	if !ok {
+		return TokenClaims{}, ErrTokenSignature
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return TokenClaims{}, ErrTokenMalformed
+	}
+	if !hmac.Equal(sig, sign(key.Secret, parts[0]+"."+parts[1])) {
+		return TokenClaims{}, ErrTokenSignature
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return TokenClaims{}, ErrTokenMalformed
+	}
+	var p tokenPayload
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return TokenClaims{}, ErrTokenMalformed
+	}
+
+	claims := TokenClaims{
+		ID:        p.ID,
+		Purpose:   TokenPurpose(p.Purpose),
+		Subject:   p.Subject,
+		IssuedAt:  time.Unix(p.IssuedAt, 0).UTC(),
+		ExpiresAt: time.Unix(p.ExpiresAt, 0).UTC(),
+		Binding:   p.Binding,
+	}
+	if claims.Purpose != purpose {
+		return TokenClaims{}, ErrTokenPurpose
+	}
+	if !t.clock.Now().Before(claims.ExpiresAt) {
+		return TokenClaims{}, ErrTokenExpired
+	}
+	return claims, nil
+}
+
+// Consume checks token like Parse and marks it used, so a second Consume
+// fails with ErrTokenUsed. Tokens from IssueBound fail with ErrTokenMismatch
+// and must be consumed with ConsumeBound.
+func (t *TokenService) Consume(token string, purpose TokenPurpose) (TokenClaims, error) {
+	claims, err := t.Parse(token, purpose)
+	if err != nil {
+		return TokenClaims{}, err
+	}
+	if claims.Binding != "" {
+		return TokenClaims{}, ErrTokenMismatch
+	}
+	return t.markUsed(claims)
+}
+
+// markUsed records the use of a checked token
+func (t *TokenService) markUsed(claims TokenClaims) (TokenClaims, error) {
+	if err := t.useID(claims.ID, claims.ExpiresAt); err != nil {
+		return TokenClaims{}, err
+	}
+	return claims, nil
+}
+
+// useID records a single use of id until expiresAt, failing with
+// ErrTokenUsed if it was used or revoked before. Services whose links share
+// one answer, like the accept and decline links of an invitation, use a
+// common ID for all of them.
+func (t *TokenService) useID(id string, expiresAt time.Time) error {
+	if err := t.used.DeleteExpired(t.clock.Now()); err != nil {
+		return fmt.Errorf("failed to prune used tokens: %w", err)
+	}
+	firstUse, err := t.used.MarkUsed(id, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to record token use: %w", err)
+	}
+	if !firstUse {
+		return ErrTokenUsed
+	}
+	return nil
+}
+
+// Revoke makes the token with the given ID unusable, as if it had been
+// consumed. expiresAt is the token's expiry, after which it needn't be
+// remembered.
+func (t *TokenService) Revoke(id string, expiresAt time.Time) error {
+	if _, err := t.used.MarkUsed(id, expiresAt); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+// ConsumeBound is like Consume for tokens from IssueBound. A token presented
+// with a different binding fails with ErrTokenMismatch and stays unused, so
+// a forwarded or intercepted link can't burn it for the rightful device.
+func (t *TokenService) ConsumeBound(token string, purpose TokenPurpose, binding string) (TokenClaims, error) {
+	claims, err := t.Parse(token, purpose)
+	if err != nil {
+		return TokenClaims{}, err
+	}
+
+	key, _ := t.keys.lookup(strings.SplitN(token, ".", 2)[0])
+	if claims.Binding == "" || !hmac.Equal([]byte(claims.Binding), []byte(hashBinding(key.Secret, binding))) {
+		return TokenClaims{}, ErrTokenMismatch
+	}
+	return t.markUsed(claims)
+}
+
+// hashBinding hides the bound value, which may be an IP address, from anyone
+// who can read the token. It is keyed so small spaces like IPv4 can't be
+// brute forced.
+func hashBinding(secret []byte, binding string) string {
+	return base64.RawURLEncoding.EncodeToString(sign(secret, "binding:"+binding)[:16])
+}
+
+// sign returns HMAC-SHA256(secret, msg)
+func sign(secret []byte, msg string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(msg))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testKey returns a 32-byte signing key
+func testKey(id string) SigningKey {
+	return SigningKey{ID: id, Secret: []byte(strings.Repeat(id, 32)[:32])}
+}
+
+func newTestTokenService(keys *Keyring) (*TokenService, *FakeClock) {
+	clock := NewFakeClock(goldenTime)
+	return NewTokenService(keys, NewMemoryUsedTokenStore(), clock), clock
+}
+
+func TestTokenService_IssueAndConsume(t *testing.T) {
+	tokens, clock := newTestTokenService(NewKeyring(testKey("k1")))
+
+	token, err := tokens.Issue(PurposePasswordReset, "user@example.com", time.Hour)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	if strings.Count(token, ".") != 2 || !strings.HasPrefix(token, "k1.") {
+		t.Errorf("token %q should be <kid>.<payload>.<signature>", token)
+	}
+
+	claims, err := tokens.Parse(token, PurposePasswordReset)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if claims.Subject != "user@example.com" || claims.Purpose != PurposePasswordReset {
+		t.Errorf("claims = %+v", claims)
+	}
+	if want := clock.Now().Add(time.Hour); !claims.ExpiresAt.Equal(want) {
+		t.Errorf("ExpiresAt = %v, want %v", claims.ExpiresAt, want)
+	}
+
+	if _, err := tokens.Consume(token, PurposePasswordReset); err != nil {
+		t.Fatalf("Consume() error = %v", err)
+	}
+	if _, err := tokens.Consume(token, PurposePasswordReset); !errors.Is(err, ErrTokenUsed) {
+		t.Errorf("second Consume() error = %v, want ErrTokenUsed", err)
+	}
+}
+
+func TestTokenService_Rejects(t *testing.T) {
+	tokens, clock := newTestTokenService(NewKeyring(testKey("k1")))
+	valid, _ := tokens.Issue(PurposeEmailVerification, "user@example.com", time.Hour)
+
+	forged, _ := NewTokenService(NewKeyring(SigningKey{ID: "k1", Secret: []byte(strings.Repeat("x", 32))}), NewMemoryUsedTokenStore(), clock).
+		Issue(PurposeEmailVerification, "user@example.com", time.Hour)
+	parts := strings.Split(valid, ".")
+
+	tests := []struct {
+		name    string
+		token   string
+		purpose TokenPurpose
+		advance time.Duration
+		wantErr error
+	}{
+		{"malformed", "not-a-token", PurposeEmailVerification, 0, ErrTokenMalformed},
+		{"signed with another secret", forged, PurposeEmailVerification, 0, ErrTokenSignature},
+		{"unknown key id", "k9." + parts[1] + "." + parts[2], PurposeEmailVerification, 0, ErrTokenSignature},
+		{"tampered payload", parts[0] + "." + parts[1] + "x." + parts[2], PurposeEmailVerification, 0, ErrTokenSignature},
+		{"wrong purpose", valid, PurposePasswordReset, 0, ErrTokenPurpose},
+		{"expired", valid, PurposeEmailVerification, time.Hour, ErrTokenExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock.Advance(tt.advance)
+			defer clock.Advance(-tt.advance)
+
+			if _, err := tokens.Consume(tt.token, tt.purpose); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Consume() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTokenService_KeyRotation(t *testing.T) {
+	old, clock := newTestTokenService(NewKeyring(testKey("k1")))
+	token, _ := old.Issue(PurposeEmailVerification, "user@example.com", time.Hour)
+
+	rotated := NewTokenService(NewKeyring(testKey("k2"), testKey("k1")), NewMemoryUsedTokenStore(), clock)
+	if _, err := rotated.Parse(token, PurposeEmailVerification); err != nil {
+		t.Errorf("token signed with a retired key should still verify: %v", err)
+	}
+	if fresh, _ := rotated.Issue(PurposeEmailVerification, "user@example.com", time.Hour); !strings.HasPrefix(fresh, "k2.") {
+		t.Errorf("new tokens should be signed with the active key, got %q", fresh)
+	}
+
+	dropped := NewTokenService(NewKeyring(testKey("k2")), NewMemoryUsedTokenStore(), clock)
+	if _, err := dropped.Parse(token, PurposeEmailVerification); !errors.Is(err, ErrTokenSignature) {
+		t.Errorf("token signed with a removed key error = %v, want ErrTokenSignature", err)
+	}
+}
+
+func TestTokenService_Binding(t *testing.T) {
+	tokens, _ := newTestTokenService(NewKeyring(testKey("k1")))
+
+	bound, _ := tokens.IssueBound(PurposeMagicLogin, "user@example.com", "203.0.113.7", time.Hour)
+	if strings.Contains(bound, "203.0.113.7") {
+		t.Error("token should not carry the bound value in clear")
+	}
+	if _, err := tokens.ConsumeBound(bound, PurposeMagicLogin, "198.51.100.1"); !errors.Is(err, ErrTokenMismatch) {
+		t.Errorf("ConsumeBound(other) error = %v, want ErrTokenMismatch", err)
+	}
+	if _, err := tokens.Consume(bound, PurposeMagicLogin); !errors.Is(err, ErrTokenMismatch) {
+		t.Errorf("Consume(bound) error = %v, want ErrTokenMismatch", err)
+	}
+	if _, err := tokens.ConsumeBound(bound, PurposeMagicLogin, "203.0.113.7"); err != nil {
+		t.Errorf("ConsumeBound() error = %v", err)
+	}
+
+	unbound, _ := tokens.Issue(PurposeMagicLogin, "user@example.com", time.Hour)
+	if _, err := tokens.ConsumeBound(unbound, PurposeMagicLogin, ""); !errors.Is(err, ErrTokenMismatch) {
+		t.Errorf("ConsumeBound(unbound) error = %v, want ErrTokenMismatch", err)
+	}
+}
+
+func TestParseKeyring(t *testing.T) {
+	secret := "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=" // 32 bytes
+
+	tests := []struct {
+		name     string
+		spec     string
+		wantIDs  []string
+		wantFail bool
+	}{
+		{"single key", "k1:" + secret, []string{"k1"}, false},
+		{"active first", "k2:" + secret + ", k1:" + secret, []string{"k2", "k1"}, false},
+		{"empty", "", nil, true},
+		{"missing id", ":" + secret, nil, true},
+		{"dot id", ".:" + secret, nil, true},
+		{"dotted id", "k.1:" + secret, nil, true},
+		{"bad base64", "k1:***", nil, true},
+		{"short secret", "k1:c2hvcnQ=", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := ParseKeyring(tt.spec)
+			if (err != nil) != tt.wantFail {
+				t.Fatalf("ParseKeyring() error = %v, wantFail %v", err, tt.wantFail)
+			}
+			if err != nil {
+				return
+			}
+			var ids []string
+			for _, k := range keys.keys {
+				ids = append(ids, k.ID)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.wantIDs, ",") {
+				t.Errorf("key ids = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Verification errors returned by VerificationService.Verify
+var (
+	ErrCodeExpired     = errors.New("verification code expired")
+	ErrCodeInvalid     = errors.New("verification code invalid")
+	ErrTooManyAttempts = errors.New("too many verification attempts")
+)
+
+// DefaultCodeAlphabet leaves out characters that are easy to confuse when
+// typed from an email (0/O, 1/I/L)
+const DefaultCodeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+// VerificationConfig configures code generation and checking
+type VerificationConfig struct {
+	// Alphabet codes are drawn from. Defaults to DefaultCodeAlphabet.
+	Alphabet string
+	// Length of generated codes. Defaults to 8.
+	Length int
+	// TTL is how long a code stays valid. It is also the expiry shown in the
+	// email. Defaults to DefaultCodeTTL.
+	TTL time.Duration
+	// MaxAttempts is how many guesses are allowed per code. Defaults to 5.
+	MaxAttempts int
+	// Tokens, when set, adds a one-click verification link to the email
+	// next to the code
+	Tokens *TokenService
+	// Throttle, when set, limits how often codes are sent (see
+	// ThrottleVerification). Defaults to the throttle of the email service
+	// (WithThrottle).
+	Throttle *Throttle
+}
+
+// withDefaults fills in zero values
+func (c VerificationConfig) withDefaults() VerificationConfig {
+	if c.Alphabet == "" {
+		c.Alphabet = DefaultCodeAlphabet
+	}
+	if c.Length <= 0 {
+		c.Length = 8
+	}
+	if c.TTL <= 0 {
+		c.TTL = DefaultCodeTTL
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 5
+	}
+	return c
+}
+
+// VerificationService issues email verification codes and checks them. Codes
+// are generated with crypto/rand, stored only as salted hashes and expire
+// after the configured TTL.
+type VerificationService struct {
+	email  *EmailService
+	store  CodeStore
+	config VerificationConfig
+}
+
+// NewVerificationService creates a verification service that sends codes
+// through email and keeps them in store
+func NewVerificationService(email *EmailService, store CodeStore, config VerificationConfig) *VerificationService {
+	return &VerificationService{
+		email:  email,
+		store:  store,
+		config: config.withDefaults(),
+	}
+}
+
+// SendCode generates a new code for address, replacing any pending one, and
+// emails it
+func (v *VerificationService) SendCode(address string) error {
+	return v.SendCodeFrom(address, "")
+}
+
+// SendCodeFrom is SendCode on behalf of client (an IP or account ID), which
+// is rate limited alongside the recipient when a Throttle is configured.
+// Refused sends return a *RateLimitError and leave any pending code valid.
+func (v *VerificationService) SendCodeFrom(address, client string) error {
+	throttle := v.throttle()
+	if throttle == nil {
+		return v.sendCode(address)
+	}
+	return throttle.Do(ThrottleVerification, address, client, func() error {
+		return v.sendCode(address)
+	})
+}
+
+// sendCode replaces the pending code of address and emails the new one
+func (v *VerificationService) sendCode(address string) error {
+	code, err := generateCode(v.config.Alphabet, v.config.Length)
+	if err != nil {
+		return fmt.Errorf("failed to generate verification code: %w", err)
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("failed to generate verification code: %w", err)
+	}
+
+	token := ""
+	rec := CodeRecord{
+		Hash:      hashCode(salt, v.normalize(code)),
+		Salt:      salt,
+		ExpiresAt: v.email.clock.Now().Add(v.config.TTL),
+	}
+	if v.config.Tokens != nil {
+		token, err = v.config.Tokens.Issue(PurposeEmailVerification, normalizeAddress(address), v.config.TTL)
+		if err != nil {
+			return fmt.Errorf("failed to issue verification token: %w", err)
+		}
+		claims, err := v.config.Tokens.Parse(token, PurposeEmailVerification)
+		if err != nil {
+			return fmt.Errorf("failed to issue verification token: %w", err)
+		}
+		rec.TokenID, rec.TokenExpiresAt = claims.ID, claims.ExpiresAt
+	}
+
+	key := verificationKey(address)
+	previous, err := v.store.Get(key)
+	if err != nil && !errors.Is(err, ErrCodeNotFound) {
+		return fmt.Errorf("failed to load pending verification code: %w", err)
+	}
+	if err := v.store.Save(key, rec); err != nil {
+		return fmt.Errorf("failed to store verification code: %w", err)
+	}
+	// The link sent with the replaced code must not verify any more
+	if err := v.revokeToken(previous); err != nil {
+		return err
+	}
+
+	return v.email.sendVerificationEmail(address, code, token, v.config.TTL)
+}
+
+// throttle returns the throttle codes are limited by, if any
+func (v *VerificationService) throttle() *Throttle {
+	if v.config.Throttle != nil {
+		return v.config.Throttle
+	}
+	return v.email.throttle
+}
+
+// VerifyToken consumes a verification link token and the pending code it
+// was sent with, returning the verified address. Errors are the token
+// errors of TokenService.
+func (v *VerificationService) VerifyToken(token string) (string, error) {
+	if v.config.Tokens == nil {
+		return "", errors.New("verification links are not enabled")
+	}
+
+	claims, err := v.config.Tokens.Consume(token, PurposeEmailVerification)
+	if err != nil {
+		return "", err
+	}
+	if err := v.store.Delete(verificationKey(claims.Subject)); err != nil {
+		return "", fmt.Errorf("failed to consume verification code: %w", err)
+	}
+	return claims.Subject, nil
+}
+
+// Verify checks code against the pending code for address. The code is
+// consumed on success. Errors are ErrCodeNotFound, ErrCodeExpired,
+// ErrTooManyAttempts, ErrCodeInvalid or a store failure.
+func (v *VerificationService) Verify(address, code string) error {
+	key := verificationKey(address)
+
+	// Count the attempt atomically, reading the record it was counted
+	// against, so concurrent guesses can't exceed the limit
+	rec, err := v.store.IncrementAttempts(key)
+	if err != nil {
+		return err
+	}
+	if !v.email.clock.Now().Before(rec.ExpiresAt) {
+		_ = v.store.Delete(key)
+		return ErrCodeExpired
+	}
+	if rec.Attempts > v.config.MaxAttempts {
+		return ErrTooManyAttempts
+	}
+
+	if subtle.ConstantTimeCompare(hashCode(rec.Salt, v.normalize(code)), rec.Hash) != 1 {
+		return ErrCodeInvalid
+	}
+
+	if err := v.store.Delete(key); err != nil {
+		return fmt.Errorf("failed to consume verification code: %w", err)
+	}
+	return v.revokeToken(rec)
+}
+
+// revokeToken makes the verification link sent with rec unusable
+func (v *VerificationService) revokeToken(rec CodeRecord) error {
+	if rec.TokenID == "" || v.config.Tokens == nil {
+		return nil
+	}
+	if err := v.config.Tokens.Revoke(rec.TokenID, rec.TokenExpiresAt); err != nil {
+		return fmt.Errorf("failed to revoke verification token: %w", err)
+	}
+	return nil
+}
+
+// normalize makes user input comparable with the generated code. Codes from
+// an upper-case alphabet are matched case-insensitively.
+func (v *VerificationService) normalize(code string) string {
+	code = strings.Join(strings.Fields(code), "")
+	if strings.ToUpper(v.config.Alphabet) == v.config.Alphabet {
+		code = strings.ToUpper(code)
+	}
+	return code
+}
+
+// verificationKey is the store key of the pending code for an address
+func verificationKey(address string) string {
+	return "verify:" + normalizeAddress(address)
+}
+
+// normalizeAddress makes email addresses comparable
+func normalizeAddress(address string) string {
+	return strings.ToLower(strings.TrimSpace(address))
+}
+
+// generateCode returns a uniformly random code of length characters from
+// alphabet
+func generateCode(alphabet string, length int) (string, error) {
+	chars := []rune(alphabet)
+	max := big.NewInt(int64(len(chars)))
+
+	code := make([]rune, length)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = chars[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// hashCode returns SHA-256(salt || code)
+func hashCode(salt []byte, code string) []byte {
+	h := sha256.New()
+	h.Write(salt)
+	h.Write([]byte(code))
+	return h.Sum(nil)
+}
@@ -0,0 +1,252 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sentCode extracts the code from the plain-text verification email
+func sentCode(t *testing.T, msg EmailOptions) string {
+	t.Helper()
+	_, code, ok := strings.Cut(msg.Text, "Your verification code is: ")
+	if !ok {
+		t.Fatalf("no verification code in %q", msg.Text)
+	}
+	return strings.Fields(code)[0]
+}
+
+func TestGenerateCode(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		code, err := generateCode(DefaultCodeAlphabet, 8)
+		if err != nil {
+			t.Fatalf("generateCode() error = %v", err)
+		}
+		if len(code) != 8 {
+			t.Errorf("len(code) = %d, want 8", len(code))
+		}
+		for _, c := range code {
+			if !strings.ContainsRune(DefaultCodeAlphabet, c) {
+				t.Errorf("code %q has character %q outside the alphabet", code, c)
+			}
+		}
+		seen[code] = true
+	}
+	if len(seen) < 95 {
+		t.Errorf("generated only %d distinct codes out of 100", len(seen))
+	}
+}
+
+func TestVerificationService_SendAndVerify(t *testing.T) {
+	email, _, transport := newTestEmailService(t)
+	store := NewMemoryCodeStore()
+	v := NewVerificationService(email, store, VerificationConfig{Alphabet: "0123456789", Length: 6})
+
+	if err := v.SendCode("User@Example.com"); err != nil {
+		t.Fatalf("SendCode() error = %v", err)
+	}
+
+	msg := transport.last()
+	if msg.To != "User@Example.com" {
+		t.Errorf("To = %q, want User@Example.com", msg.To)
+	}
+	code := sentCode(t, msg)
+	if len(code) != 6 || strings.Trim(code, "0123456789") != "" {
+		t.Errorf("code %q does not follow the configured alphabet and length", code)
+	}
+
+	rec, err := store.Get(verificationKey("user@example.com"))
+	if err != nil {
+		t.Fatalf("store.Get() error = %v", err)
+	}
+	if strings.Contains(string(rec.Hash), code) {
+		t.Error("store should hold a hash, not the code")
+	}
+
+	if err := v.Verify("user@example.com", code); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if err := v.Verify("user@example.com", code); !errors.Is(err, ErrCodeNotFound) {
+		t.Errorf("second Verify() error = %v, want ErrCodeNotFound", err)
+	}
+}
+
+func TestVerificationService_Verify(t *testing.T) {
+	tests := []struct {
+		name    string
+		prepare func(v *VerificationService, clock *FakeClock, code string)
+		input   func(code string) string
+		wantErr error
+	}{
+		{
+			name:  "correct code",
+			input: func(code string) string { return code },
+		},
+		{
+			name:  "lower case input with spaces",
+			input: func(code string) string { return " " + strings.ToLower(code[:4]) + " " + strings.ToLower(code[4:]) },
+		},
+		{
+			name:    "wrong code",
+			input:   func(code string) string { return "WRONG234" },
+			wantErr: ErrCodeInvalid,
+		},
+		{
+			name: "expired code",
+			prepare: func(v *VerificationService, clock *FakeClock, code string) {
+				clock.Advance(24 * time.Hour)
+			},
+			input:   func(code string) string { return code },
+			wantErr: ErrCodeExpired,
+		},
+		{
+			name: "locked after max attempts",
+			prepare: func(v *VerificationService, clock *FakeClock, code string) {
+				for i := 0; i < 3; i++ {
+					_ = v.Verify("user@example.com", "WRONG234")
+				}
+			},
+			input:   func(code string) string { return code },
+			wantErr: ErrTooManyAttempts,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email, clock, transport := newTestEmailService(t)
+			v := NewVerificationService(email, NewMemoryCodeStore(), VerificationConfig{MaxAttempts: 3})
+			if err := v.SendCode("user@example.com"); err != nil {
+				t.Fatalf("SendCode() error = %v", err)
+			}
+			code := sentCode(t, transport.last())
+
+			if tt.prepare != nil {
+				tt.prepare(v, clock, code)
+			}
+
+			err := v.Verify("user@example.com", tt.input(code))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerificationService_Resend(t *testing.T) {
+	tests := []struct {
+		name string
+		// sent reads the code or link token of a sent email
+		sent       func(t *testing.T, msg EmailOptions) string
+		verify     func(v *VerificationService, sent string) error
+		wantOldErr error
+	}{
+		{
+			name: "replaces the code",
+			sent: sentCode,
+			verify: func(v *VerificationService, code string) error {
+				return v.Verify("user@example.com", code)
+			},
+			wantOldErr: ErrCodeInvalid,
+		},
+		{
+			name: "revokes the link",
+			sent: func(t *testing.T, msg EmailOptions) string {
+				return sentLinkToken(t, msg, "/verify-email")
+			},
+			verify: func(v *VerificationService, token string) error {
+				_, err := v.VerifyToken(token)
+				return err
+			},
+			wantOldErr: ErrTokenUsed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			email, clock, transport := newTestEmailService(t)
+			v := NewVerificationService(email, NewMemoryCodeStore(), VerificationConfig{Tokens: newTestTokens(clock)})
+
+			_ = v.SendCode("user@example.com")
+			first := tt.sent(t, transport.last())
+			_ = v.SendCode("user@example.com")
+			second := tt.sent(t, transport.last())
+
+			// Random codes can repeat
+			if first != second {
+				if err := tt.verify(v, first); !errors.Is(err, tt.wantOldErr) {
+					t.Errorf("verify(old) error = %v, want %v", err, tt.wantOldErr)
+				}
+			}
+			if err := tt.verify(v, second); err != nil {
+				t.Errorf("verify(new) error = %v", err)
+			}
+		})
+	}
+}
+
+func TestVerificationService_SendFailure(t *testing.T) {
+	email, _, transport := newTestEmailService(t)
+	v := NewVerificationService(email, NewMemoryCodeStore(), VerificationConfig{})
+	transport.err = errors.New("smtp down")
+
+	if err := v.SendCode("user@example.com"); err == nil {
+		t.Error("SendCode() should return the delivery error")
+	}
+}
+
+func TestVerificationService_MagicLink(t *testing.T) {
+	email, clock, transport := newTestEmailService(t)
+	store := NewMemoryCodeStore()
+	v := NewVerificationService(email, store, VerificationConfig{Tokens: newTestTokens(clock)})
+
+	if err := v.SendCode("User@Example.com"); err != nil {
+		t.Fatalf("SendCode() error = %v", err)
+	}
+	msg := transport.last()
+
+	prefix := v.email.appURL + "/verify-email?token="
+	_, link, ok := strings.Cut(msg.Text, "Or verify with one click: ")
+	if !ok || !strings.HasPrefix(link, prefix) {
+		t.Fatalf("text part should carry the verification link, got %q", msg.Text)
+	}
+	if !strings.Contains(msg.HTML, "Verify Email Address") || !strings.Contains(msg.HTML, sentCode(t, msg)) {
+		t.Error("HTML should show both the link button and the code")
+	}
+
+	token := strings.TrimPrefix(link, prefix)
+	address, err := v.VerifyToken(token)
+	if err != nil {
+		t.Fatalf("VerifyToken() error = %v", err)
+	}
+	if address != "user@example.com" {
+		t.Errorf("VerifyToken() = %q, want user@example.com", address)
+	}
+	if _, err := store.Get(verificationKey(address)); !errors.Is(err, ErrCodeNotFound) {
+		t.Error("verifying by link should consume the pending code")
+	}
+	if _, err := v.VerifyToken(token); !errors.Is(err, ErrTokenUsed) {
+		t.Errorf("second VerifyToken() error = %v, want ErrTokenUsed", err)
+	}
+}
+
+func TestVerificationService_ExpiryMatchesTTL(t *testing.T) {
+	email, clock, transport := newTestEmailService(t)
+	v := NewVerificationService(email, NewMemoryCodeStore(), VerificationConfig{TTL: 15 * time.Minute})
+
+	if err := v.SendCode("user@example.com"); err != nil {
+		t.Fatalf("SendCode() error = %v", err)
+	}
+	msg := transport.last()
+	if !strings.Contains(msg.HTML, "<strong>15 minutes</strong>") || !strings.Contains(msg.Text, "expires in 15 minutes") {
+		t.Error("email should state the configured 15 minute TTL")
+	}
+	if strings.Contains(msg.HTML, "24 hours") {
+		t.Error("email should not mention the default TTL")
+	}
+
+	clock.Advance(15 * time.Minute)
+	if err := v.Verify("user@example.com", sentCode(t, msg)); !errors.Is(err, ErrCodeExpired) {
+		t.Errorf("Verify() after TTL error = %v, want ErrCodeExpired", err)
+	}
+}
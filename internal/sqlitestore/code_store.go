@@ -0,0 +1,112 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sponsoration/api/internal/service"
+)
+
+// CodeStore is a service.CodeStore persisted in SQLite
+type CodeStore struct {
+	db *sql.DB
+}
+
+var _ service.CodeStore = (*CodeStore)(nil)
+
+// NewCodeStore creates the verification_codes table if needed and returns a
+// store backed by it
+func NewCodeStore(db *sql.DB) (*CodeStore, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS verification_codes (
+			key              TEXT PRIMARY KEY,
+			hash             BLOB    NOT NULL,
+			salt             BLOB    NOT NULL,
+			expires_at       INTEGER NOT NULL,
+			attempts         INTEGER NOT NULL DEFAULT 0,
+			token_id         TEXT    NOT NULL DEFAULT '',
+			token_expires_at INTEGER
+		)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create verification_codes table: %w", err)
+	}
+	return &CodeStore{db: db}, nil
+}
+
+// Save stores rec under key, replacing any previous code
+func (s *CodeStore) Save(key string, rec service.CodeRecord) error {
+	var tokenExpiresAt *int64
+	if !rec.TokenExpiresAt.IsZero() {
+		at := rec.TokenExpiresAt.UnixNano()
+		tokenExpiresAt = &at
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO verification_codes (key, hash, salt, expires_at, attempts, token_id, token_expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (key) DO UPDATE SET
+			hash = excluded.hash,
+			salt = excluded.salt,
+			expires_at = excluded.expires_at,
+			attempts = excluded.attempts,
+			token_id = excluded.token_id,
+			token_expires_at = excluded.token_expires_at`,
+		key, rec.Hash, rec.Salt, rec.ExpiresAt.UnixNano(), rec.Attempts, rec.TokenID, tokenExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to save verification code: %w", err)
+	}
+	return nil
+}
+
+// Get returns the record stored under key, or service.ErrCodeNotFound
+func (s *CodeStore) Get(key string) (service.CodeRecord, error) {
+	rec, err := scanCode(s.db.QueryRow(`
+		SELECT hash, salt, expires_at, attempts, token_id, token_expires_at
+		FROM verification_codes WHERE key = ?`, key))
+	if errors.Is(err, sql.ErrNoRows) {
+		return service.CodeRecord{}, service.ErrCodeNotFound
+	}
+	if err != nil {
+		return service.CodeRecord{}, fmt.Errorf("failed to load verification code: %w", err)
+	}
+	return rec, nil
+}
+
+// IncrementAttempts atomically records a verification attempt for key and
+// returns the updated record
+func (s *CodeStore) IncrementAttempts(key string) (service.CodeRecord, error) {
+	rec, err := scanCode(s.db.QueryRow(`
+		UPDATE verification_codes SET attempts = attempts + 1 WHERE key = ?
+		RETURNING hash, salt, expires_at, attempts, token_id, token_expires_at`, key))
+	if errors.Is(err, sql.ErrNoRows) {
+		return service.CodeRecord{}, service.ErrCodeNotFound
+	}
+	if err != nil {
+		return service.CodeRecord{}, fmt.Errorf("failed to record verification attempt: %w", err)
+	}
+	return rec, nil
+}
+
+// scanCode reads one verification_codes row
+func scanCode(row *sql.Row) (service.CodeRecord, error) {
+	var rec service.CodeRecord
+	var expiresAt int64
+	var tokenExpiresAt sql.NullInt64
+	if err := row.Scan(&rec.Hash, &rec.Salt, &expiresAt, &rec.Attempts, &rec.TokenID, &tokenExpiresAt); err != nil {
+		return service.CodeRecord{}, err
+	}
+	rec.ExpiresAt = time.Unix(0, expiresAt).UTC()
+	if tokenExpiresAt.Valid {
+		rec.TokenExpiresAt = time.Unix(0, tokenExpiresAt.Int64).UTC()
+	}
+	return rec, nil
+}
+
+// Delete removes the code stored under key
+func (s *CodeStore) Delete(key string) error {
+	if _, err := s.db.Exec(`DELETE FROM verification_codes WHERE key = ?`, key); err != nil {
+		return fmt.Errorf("failed to delete verification code: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package sqlitestore
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sponsoration/api/internal/service"
+)
+
+func newTestCodeStore(t *testing.T, path string) *CodeStore {
+	t.Helper()
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	store, err := NewCodeStore(db)
+	if err != nil {
+		t.Fatalf("NewCodeStore() error = %v", err)
+	}
+	return store
+}
+
+func TestCodeStore(t *testing.T) {
+	store := newTestCodeStore(t, ":memory:")
+	expires := time.Date(2025, time.March, 15, 9, 30, 0, 0, time.UTC)
+	rec := service.CodeRecord{Hash: []byte("hash"), Salt: []byte("salt"), ExpiresAt: expires, TokenID: "tok-1", TokenExpiresAt: expires}
+
+	if _, err := store.Get("verify:a@example.com"); !errors.Is(err, service.ErrCodeNotFound) {
+		t.Errorf("Get(missing) error = %v, want ErrCodeNotFound", err)
+	}
+	if _, err := store.IncrementAttempts("verify:a@example.com"); !errors.Is(err, service.ErrCodeNotFound) {
+		t.Errorf("IncrementAttempts(missing) error = %v, want ErrCodeNotFound", err)
+	}
+
+	if err := store.Save("verify:a@example.com", rec); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	for want := 1; want <= 2; want++ {
+		got, err := store.IncrementAttempts("verify:a@example.com")
+		if err != nil || got.Attempts != want || !bytes.Equal(got.Hash, rec.Hash) {
+			t.Errorf("IncrementAttempts() = %+v, %v, want the record with %d attempts", got, err, want)
+		}
+	}
+
+	got, err := store.Get("verify:a@example.com")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if !bytes.Equal(got.Hash, rec.Hash) || !bytes.Equal(got.Salt, rec.Salt) || !got.ExpiresAt.Equal(expires) || got.Attempts != 2 ||
+		got.TokenID != "tok-1" || !got.TokenExpiresAt.Equal(expires) {
+		t.Errorf("Get() = %+v, want %+v with 2 attempts", got, rec)
+	}
+
+	// Saving again replaces the code and resets attempts, a code without a
+	// link keeps no token expiry
+	rec.TokenID, rec.TokenExpiresAt = "", time.Time{}
+	if err := store.Save("verify:a@example.com", rec); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if got, _ := store.Get("verify:a@example.com"); got.Attempts != 0 || got.TokenID != "" || !got.TokenExpiresAt.IsZero() {
+		t.Errorf("Get() after re-save = %+v, want no attempts and no token", got)
+	}
+
+	if err := store.Delete("verify:a@example.com"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get("verify:a@example.com"); !errors.Is(err, service.ErrCodeNotFound) {
+		t.Errorf("Get(deleted) error = %v, want ErrCodeNotFound", err)
+	}
+}
+
+func TestCodeStore_PersistsAcrossReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "codes.db")
+	rec := service.CodeRecord{Hash: []byte("hash"), Salt: []byte("salt"), ExpiresAt: time.Now()}
+
+	if err := newTestCodeStore(t, path).Save("k", rec); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, err := newTestCodeStore(t, path).Get("k"); err != nil {
+		t.Errorf("Get() after reopen error = %v", err)
+	}
+}
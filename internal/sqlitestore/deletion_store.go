@@ -0,0 +1,185 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sponsoration/api/internal/service"
+)
+
+// DeletionStore is a service.DeletionStore persisted in SQLite, so pending
+// account deletions and the captured addresses survive restarts
+type DeletionStore struct {
+	db *sql.DB
+}
+
+var _ service.DeletionStore = (*DeletionStore)(nil)
+
+// NewDeletionStore creates the pending_deletions table if needed and
+// returns a store backed by it
+func NewDeletionStore(db *sql.DB) (*DeletionStore, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS pending_deletions (
+			user_id       TEXT PRIMARY KEY,
+			email         TEXT    NOT NULL,
+			name          TEXT    NOT NULL,
+			requested_at  INTEGER NOT NULL,
+			delete_at     INTEGER NOT NULL,
+			undo_token_id TEXT    NOT NULL,
+			erased_at     INTEGER
+		);
+		CREATE INDEX IF NOT EXISTS pending_deletions_delete_at ON pending_deletions (delete_at)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pending_deletions table: %w", err)
+	}
+	return &DeletionStore{db: db}, nil
+}
+
+// upsertDeletion inserts a pending deletion or replaces the stored one
+const upsertDeletion = `
+	INSERT INTO pending_deletions (user_id, email, name, requested_at, delete_at, undo_token_id, erased_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT (user_id) DO UPDATE SET
+		email = excluded.email,
+		name = excluded.name,
+		requested_at = excluded.requested_at,
+		delete_at = excluded.delete_at,
+		undo_token_id = excluded.undo_token_id,
+		erased_at = excluded.erased_at`
+
+// Save creates or replaces the pending deletion of a user
+func (s *DeletionStore) Save(p service.PendingDeletion) error {
+	if _, err := s.db.Exec(upsertDeletion, deletionArgs(p)...); err != nil {
+		return fmt.Errorf("failed to save pending deletion: %w", err)
+	}
+	return nil
+}
+
+// Schedule saves a pending deletion unless the stored one is claimed, which
+// fails with service.ErrDeletionInProgress
+func (s *DeletionStore) Schedule(p service.PendingDeletion) error {
+	res, err := s.db.Exec(upsertDeletion+`
+		WHERE pending_deletions.undo_token_id != ''`, deletionArgs(p)...)
+	if err != nil {
+		return fmt.Errorf("failed to schedule pending deletion: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to schedule pending deletion: %w", err)
+	}
+	if n == 0 {
+		return service.ErrDeletionInProgress
+	}
+	return nil
+}
+
+// deletionArgs are the upsertDeletion parameters of p
+func deletionArgs(p service.PendingDeletion) []any {
+	var erasedAt *int64
+	if !p.ErasedAt.IsZero() {
+		at := p.ErasedAt.UnixNano()
+		erasedAt = &at
+	}
+	return []any{p.Contact.UserID, p.Contact.Email, p.Contact.Name, p.RequestedAt.UnixNano(), p.DeleteAt.UnixNano(),
+		p.UndoTokenID, erasedAt}
+}
+
+// Get returns the pending deletion of a user, or service.ErrDeletionNotFound
+func (s *DeletionStore) Get(userID string) (service.PendingDeletion, error) {
+	p, err := scanDeletion(s.db.QueryRow(`
+		SELECT user_id, email, name, requested_at, delete_at, undo_token_id, erased_at
+		FROM pending_deletions WHERE user_id = ?`, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return service.PendingDeletion{}, service.ErrDeletionNotFound
+	}
+	if err != nil {
+		return service.PendingDeletion{}, fmt.Errorf("failed to load pending deletion: %w", err)
+	}
+	return p, nil
+}
+
+// Delete forgets the pending deletion of a user
+func (s *DeletionStore) Delete(userID string) error {
+	if _, err := s.db.Exec(`DELETE FROM pending_deletions WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("failed to delete pending deletion: %w", err)
+	}
+	return nil
+}
+
+// Cancel deletes and returns the pending deletion of a user while its undo
+// token ID matches, or fails with service.ErrDeletionNotFound
+func (s *DeletionStore) Cancel(userID, undoTokenID string) (service.PendingDeletion, error) {
+	p, err := scanDeletion(s.db.QueryRow(`
+		DELETE FROM pending_deletions WHERE user_id = ? AND undo_token_id = ? AND undo_token_id != ''
+		RETURNING user_id, email, name, requested_at, delete_at, undo_token_id, erased_at`, userID, undoTokenID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return service.PendingDeletion{}, service.ErrDeletionNotFound
+	}
+	if err != nil {
+		return service.PendingDeletion{}, fmt.Errorf("failed to cancel pending deletion: %w", err)
+	}
+	return p, nil
+}
+
+// Claim clears the undo token ID of a pending deletion while it matches, or
+// fails with service.ErrDeletionNotFound
+func (s *DeletionStore) Claim(userID, undoTokenID string) error {
+	res, err := s.db.Exec(`
+		UPDATE pending_deletions SET undo_token_id = ''
+		WHERE user_id = ? AND undo_token_id = ? AND undo_token_id != ''`, userID, undoTokenID)
+	if err != nil {
+		return fmt.Errorf("failed to claim pending deletion: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to claim pending deletion: %w", err)
+	}
+	if n == 0 {
+		return service.ErrDeletionNotFound
+	}
+	return nil
+}
+
+// Due returns the deletions due at or before now ordered by user ID
+func (s *DeletionStore) Due(now time.Time) ([]service.PendingDeletion, error) {
+	rows, err := s.db.Query(`
+		SELECT user_id, email, name, requested_at, delete_at, undo_token_id, erased_at
+		FROM pending_deletions WHERE delete_at <= ? ORDER BY user_id`, now.UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load due deletions: %w", err)
+	}
+	defer rows.Close()
+
+	var due []service.PendingDeletion
+	for rows.Next() {
+		p, err := scanDeletion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load due deletions: %w", err)
+		}
+		due = append(due, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load due deletions: %w", err)
+	}
+	return due, nil
+}
+
+// scanDeletion reads one pending_deletions row
+func scanDeletion(row interface{ Scan(...any) error }) (service.PendingDeletion, error) {
+	var p service.PendingDeletion
+	var requestedAt, deleteAt int64
+	var erasedAt sql.NullInt64
+	err := row.Scan(&p.Contact.UserID, &p.Contact.Email, &p.Contact.Name, &requestedAt, &deleteAt,
+		&p.UndoTokenID, &erasedAt)
+	if err != nil {
+		return service.PendingDeletion{}, err
+	}
+	p.RequestedAt = time.Unix(0, requestedAt).UTC()
+	p.DeleteAt = time.Unix(0, deleteAt).UTC()
+	if erasedAt.Valid {
+		p.ErasedAt = time.Unix(0, erasedAt.Int64).UTC()
+	}
+	return p, nil
+}
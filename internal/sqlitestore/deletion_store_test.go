@@ -0,0 +1,126 @@
+package sqlitestore
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sponsoration/api/internal/service"
+)
+
+func TestDeletionStore(t *testing.T) {
+	db, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer db.Close()
+
+	store, err := NewDeletionStore(db)
+	if err != nil {
+		t.Fatalf("NewDeletionStore() error = %v", err)
+	}
+	now := time.Date(2025, time.March, 14, 9, 30, 0, 0, time.UTC)
+
+	p := service.PendingDeletion{
+		Contact:     service.AccountContact{UserID: "u1", Email: "jane@example.com", Name: "Jane"},
+		RequestedAt: now,
+		DeleteAt:    now.Add(30 * 24 * time.Hour),
+		UndoTokenID: "tok-1",
+	}
+	if err := store.Save(p); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if got, err := store.Get("u1"); err != nil || got != p {
+		t.Errorf("Get() = %+v, %v, want %+v", got, err, p)
+	}
+	if _, err := store.Get("nobody"); !errors.Is(err, service.ErrDeletionNotFound) {
+		t.Errorf("Get(nobody) error = %v, want ErrDeletionNotFound", err)
+	}
+
+	if due, _ := store.Due(now); len(due) != 0 {
+		t.Errorf("Due() before the grace period ended = %d deletions, want none", len(due))
+	}
+	p.ErasedAt = p.DeleteAt
+	if err := store.Save(p); err != nil {
+		t.Fatalf("Save() again error = %v", err)
+	}
+	due, err := store.Due(p.DeleteAt)
+	if err != nil || len(due) != 1 || due[0] != p {
+		t.Errorf("Due() = %+v, %v, want the erased deletion", due, err)
+	}
+
+	if err := store.Delete("u1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get("u1"); !errors.Is(err, service.ErrDeletionNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want ErrDeletionNotFound", err)
+	}
+}
+
+func TestDeletionStore_CancelAndClaim(t *testing.T) {
+	db, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer db.Close()
+
+	store, err := NewDeletionStore(db)
+	if err != nil {
+		t.Fatalf("NewDeletionStore() error = %v", err)
+	}
+	now := time.Date(2025, time.March, 14, 9, 30, 0, 0, time.UTC)
+	p := service.PendingDeletion{
+		Contact:     service.AccountContact{UserID: "u1", Email: "jane@example.com", Name: "Jane"},
+		RequestedAt: now,
+		DeleteAt:    now.Add(30 * 24 * time.Hour),
+		UndoTokenID: "tok-1",
+	}
+	_ = store.Save(p)
+
+	if _, err := store.Cancel("u1", "tok-0"); !errors.Is(err, service.ErrDeletionNotFound) {
+		t.Errorf("Cancel(old token) error = %v, want ErrDeletionNotFound", err)
+	}
+	if err := store.Claim("u1", "tok-1"); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	if err := store.Claim("u1", "tok-1"); !errors.Is(err, service.ErrDeletionNotFound) {
+		t.Errorf("second Claim() error = %v, want ErrDeletionNotFound", err)
+	}
+	if _, err := store.Cancel("u1", "tok-1"); !errors.Is(err, service.ErrDeletionNotFound) {
+		t.Errorf("Cancel() after Claim() error = %v, want ErrDeletionNotFound", err)
+	}
+	if got, _ := store.Get("u1"); got.UndoTokenID != "" {
+		t.Errorf("UndoTokenID = %q after Claim(), want it cleared", got.UndoTokenID)
+	}
+
+	// A claimed deletion can't be rescheduled, an unclaimed one can
+	rescheduled := p
+	rescheduled.UndoTokenID = "tok-2"
+	if err := store.Schedule(rescheduled); !errors.Is(err, service.ErrDeletionInProgress) {
+		t.Errorf("Schedule() after Claim() error = %v, want ErrDeletionInProgress", err)
+	}
+	if got, _ := store.Get("u1"); got.UndoTokenID != "" {
+		t.Errorf("UndoTokenID = %q after a refused Schedule(), want it still cleared", got.UndoTokenID)
+	}
+	rescheduled.Contact.UserID = "u3"
+	if err := store.Schedule(rescheduled); err != nil {
+		t.Fatalf("Schedule() error = %v", err)
+	}
+	rescheduled.UndoTokenID = "tok-3"
+	if err := store.Schedule(rescheduled); err != nil {
+		t.Fatalf("Schedule() again error = %v", err)
+	}
+	if got, _ := store.Get("u3"); got != rescheduled {
+		t.Errorf("Get() after Schedule() = %+v, want %+v", got, rescheduled)
+	}
+
+	p.Contact.UserID = "u2"
+	_ = store.Save(p)
+	got, err := store.Cancel("u2", "tok-1")
+	if err != nil || got != p {
+		t.Errorf("Cancel() = %+v, %v, want %+v", got, err, p)
+	}
+	if _, err := store.Get("u2"); !errors.Is(err, service.ErrDeletionNotFound) {
+		t.Errorf("Get() after Cancel() error = %v, want ErrDeletionNotFound", err)
+	}
+}
@@ -0,0 +1,101 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sponsoration/api/internal/service"
+)
+
+// RateLimitStore is a service.RateLimitStore persisted in SQLite, so limits
+// survive restarts and are shared by processes using the same database
+type RateLimitStore struct {
+	db *sql.DB
+}
+
+var _ service.RateLimitStore = (*RateLimitStore)(nil)
+
+// NewRateLimitStore creates the rate_limit_hits table if needed and returns
+// a store backed by it
+func NewRateLimitStore(db *sql.DB) (*RateLimitStore, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS rate_limit_hits (
+			key TEXT    NOT NULL,
+			at  INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS rate_limit_hits_key_at ON rate_limit_hits (key, at)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rate_limit_hits table: %w", err)
+	}
+	return &RateLimitStore{db: db}, nil
+}
+
+// Take checks and records a hit under keys in one transaction. Open starts
+// transactions with the write lock, so another process can't record a hit
+// between the check and the insert.
+func (s *RateLimitStore) Take(keys []service.RateLimitKey, at time.Time, check func(hits [][]time.Time) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to record rate limit hit: %w", err)
+	}
+	defer tx.Rollback()
+
+	hits := make([][]time.Time, len(keys))
+	for i, k := range keys {
+		if _, err := tx.Exec(`DELETE FROM rate_limit_hits WHERE key = ? AND at < ?`, k.Key, k.Since.UnixNano()); err != nil {
+			return fmt.Errorf("failed to prune rate limit hits: %w", err)
+		}
+		if hits[i], err = loadHits(tx, k.Key); err != nil {
+			return err
+		}
+	}
+	if err := check(hits); err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		if _, err := tx.Exec(`INSERT INTO rate_limit_hits (key, at) VALUES (?, ?)`, k.Key, at.UnixNano()); err != nil {
+			return fmt.Errorf("failed to record rate limit hit: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to record rate limit hit: %w", err)
+	}
+	return nil
+}
+
+// Release removes one hit recorded at at under each key
+func (s *RateLimitStore) Release(keys []service.RateLimitKey, at time.Time) error {
+	for _, k := range keys {
+		_, err := s.db.Exec(`
+			DELETE FROM rate_limit_hits WHERE rowid =
+				(SELECT rowid FROM rate_limit_hits WHERE key = ? AND at = ? LIMIT 1)`, k.Key, at.UnixNano())
+		if err != nil {
+			return fmt.Errorf("failed to release rate limit hit: %w", err)
+		}
+	}
+	return nil
+}
+
+// loadHits returns the times recorded under key, oldest first
+func loadHits(tx *sql.Tx, key string) ([]time.Time, error) {
+	rows, err := tx.Query(`SELECT at FROM rate_limit_hits WHERE key = ? ORDER BY at`, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rate limit hits: %w", err)
+	}
+	defer rows.Close()
+
+	var hits []time.Time
+	for rows.Next() {
+		var at int64
+		if err := rows.Scan(&at); err != nil {
+			return nil, fmt.Errorf("failed to load rate limit hits: %w", err)
+		}
+		hits = append(hits, time.Unix(0, at).UTC())
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load rate limit hits: %w", err)
+	}
+	return hits, nil
+}
@@ -0,0 +1,125 @@
+package sqlitestore
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sponsoration/api/internal/service"
+)
+
+var errLimited = errors.New("limited")
+
+// allowFirst is a Take check that only allows a key without hits
+func allowFirst(hits [][]time.Time) error {
+	if len(hits[0]) > 0 {
+		return errLimited
+	}
+	return nil
+}
+
+func TestRateLimitStore(t *testing.T) {
+	db, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer db.Close()
+
+	store, err := NewRateLimitStore(db)
+	if err != nil {
+		t.Fatalf("NewRateLimitStore() error = %v", err)
+	}
+	start := time.Date(2025, time.March, 14, 9, 30, 0, 0, time.UTC)
+
+	var seen [][]time.Time
+	record := func(hits [][]time.Time) error {
+		seen = hits
+		return nil
+	}
+	for i := 0; i < 3; i++ {
+		at := start.Add(time.Duration(i) * time.Minute)
+		if err := store.Take([]service.RateLimitKey{{Key: "k", Since: at.Add(-time.Hour)}}, at, record); err != nil {
+			t.Fatalf("Take() error = %v", err)
+		}
+	}
+	_ = store.Take([]service.RateLimitKey{{Key: "other", Since: start}}, start, record)
+
+	// Hits before Since are left out and forgotten
+	keys := []service.RateLimitKey{{Key: "k", Since: start.Add(time.Minute)}, {Key: "other", Since: start}}
+	if err := store.Take(keys, start.Add(time.Hour), record); err != nil {
+		t.Fatalf("Take() error = %v", err)
+	}
+	if len(seen[0]) != 2 || !seen[0][0].Equal(start.Add(time.Minute)) || !seen[0][1].Equal(start.Add(2*time.Minute)) {
+		t.Errorf("hits = %v, want the last two hits oldest first", seen[0])
+	}
+	if len(seen[1]) != 1 {
+		t.Errorf("hits of other = %v, want 1", seen[1])
+	}
+
+	// A refused check records nothing
+	if err := store.Take(keys, start.Add(time.Hour), func([][]time.Time) error { return errLimited }); !errors.Is(err, errLimited) {
+		t.Errorf("Take() error = %v, want the check's error", err)
+	}
+	_ = store.Take(keys, start.Add(time.Hour), record)
+	if len(seen[0]) != 3 || len(seen[1]) != 2 {
+		t.Errorf("hits = %d and %d, want 3 and 2", len(seen[0]), len(seen[1]))
+	}
+
+	// Release takes back one hit
+	if err := store.Release(keys, start.Add(time.Hour)); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	_ = store.Take(keys, start.Add(2*time.Hour), record)
+	if len(seen[0]) != 3 || len(seen[1]) != 2 {
+		t.Errorf("hits after Release = %d and %d, want 3 and 2", len(seen[0]), len(seen[1]))
+	}
+}
+
+func TestRateLimitStore_SharedDatabase(t *testing.T) {
+	// Each store has its own connection, like separate processes would
+	path := filepath.Join(t.TempDir(), "limits.db")
+	const n = 8
+	stores := make([]*RateLimitStore, n)
+	for i := range stores {
+		db, err := Open(path)
+		if err != nil {
+			t.Fatalf("Open() error = %v", err)
+		}
+		defer db.Close()
+		if stores[i], err = NewRateLimitStore(db); err != nil {
+			t.Fatalf("NewRateLimitStore() error = %v", err)
+		}
+	}
+
+	at := time.Date(2025, time.March, 14, 9, 30, 0, 0, time.UTC)
+	keys := []service.RateLimitKey{{Key: "k", Since: at.Add(-time.Hour)}}
+	var wg sync.WaitGroup
+	errs := make([]error, n)
+	for i, store := range stores {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = store.Take(keys, at, func(hits [][]time.Time) error {
+				// Leave time for the others to run between check and insert
+				time.Sleep(10 * time.Millisecond)
+				return allowFirst(hits)
+			})
+		}()
+	}
+	wg.Wait()
+
+	allowed := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			allowed++
+		case !errors.Is(err, errLimited):
+			t.Errorf("Take() error = %v", err)
+		}
+	}
+	if allowed != 1 {
+		t.Errorf("%d concurrent Takes passed the check, want 1", allowed)
+	}
+}
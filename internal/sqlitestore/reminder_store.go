@@ -0,0 +1,114 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sponsoration/api/internal/service"
+)
+
+// ReminderStore is a service.ReminderStore persisted in SQLite, so
+// scheduled deadline reminders survive restarts
+type ReminderStore struct {
+	db *sql.DB
+}
+
+var _ service.ReminderStore = (*ReminderStore)(nil)
+
+// NewReminderStore creates the deadline_reminders table if needed and
+// returns a store backed by it
+func NewReminderStore(db *sql.DB) (*ReminderStore, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS deadline_reminders (
+			deliverable_id TEXT    NOT NULL,
+			offset_ns      INTEGER NOT NULL,
+			send_at        INTEGER NOT NULL,
+			deal_id        TEXT    NOT NULL,
+			deal_title     TEXT    NOT NULL,
+			title          TEXT    NOT NULL,
+			creator_email  TEXT    NOT NULL,
+			creator_name   TEXT    NOT NULL,
+			deadline       INTEGER NOT NULL,
+			time_zone      TEXT    NOT NULL,
+			PRIMARY KEY (deliverable_id, offset_ns)
+		);
+		CREATE INDEX IF NOT EXISTS deadline_reminders_send_at ON deadline_reminders (send_at)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create deadline_reminders table: %w", err)
+	}
+	return &ReminderStore{db: db}, nil
+}
+
+// Replace swaps the reminders of a deliverable in one transaction
+func (s *ReminderStore) Replace(deliverableID string, reminders []service.ScheduledReminder) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to replace reminders: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM deadline_reminders WHERE deliverable_id = ?`, deliverableID); err != nil {
+		return fmt.Errorf("failed to replace reminders: %w", err)
+	}
+	for _, rem := range reminders {
+		d := rem.Deliverable
+		_, err := tx.Exec(`
+			INSERT INTO deadline_reminders (deliverable_id, offset_ns, send_at, deal_id, deal_title,
+				title, creator_email, creator_name, deadline, time_zone)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			deliverableID, int64(rem.Offset), rem.SendAt.UnixNano(), d.DealID, d.DealTitle,
+			d.Title, d.CreatorEmail, d.CreatorName, d.Deadline.UnixNano(), d.TimeZone)
+		if err != nil {
+			return fmt.Errorf("failed to replace reminders: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to replace reminders: %w", err)
+	}
+	return nil
+}
+
+// Due returns the reminders due at or before now, ordered by send time
+func (s *ReminderStore) Due(now time.Time) ([]service.ScheduledReminder, error) {
+	rows, err := s.db.Query(`
+		SELECT deliverable_id, offset_ns, send_at, deal_id, deal_title,
+			title, creator_email, creator_name, deadline, time_zone
+		FROM deadline_reminders WHERE send_at <= ?
+		ORDER BY send_at, deliverable_id`, now.UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load due reminders: %w", err)
+	}
+	defer rows.Close()
+
+	var due []service.ScheduledReminder
+	for rows.Next() {
+		var rem service.ScheduledReminder
+		var offset, sendAt, deadline int64
+		d := &rem.Deliverable
+		err := rows.Scan(&d.ID, &offset, &sendAt, &d.DealID, &d.DealTitle,
+			&d.Title, &d.CreatorEmail, &d.CreatorName, &deadline, &d.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load due reminders: %w", err)
+		}
+		rem.Offset = time.Duration(offset)
+		rem.SendAt = time.Unix(0, sendAt).UTC()
+		d.Deadline = time.Unix(0, deadline).UTC()
+		due = append(due, rem)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load due reminders: %w", err)
+	}
+	return due, nil
+}
+
+// Delete removes one reminder while it's still due at sendAt
+func (s *ReminderStore) Delete(deliverableID string, offset time.Duration, sendAt time.Time) error {
+	_, err := s.db.Exec(`
+		DELETE FROM deadline_reminders WHERE deliverable_id = ? AND offset_ns = ? AND send_at = ?`,
+		deliverableID, int64(offset), sendAt.UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to delete reminder: %w", err)
+	}
+	return nil
+}
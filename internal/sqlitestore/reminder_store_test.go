@@ -0,0 +1,75 @@
+package sqlitestore
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sponsoration/api/internal/service"
+)
+
+func TestReminderStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reminders.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	store, err := NewReminderStore(db)
+	if err != nil {
+		t.Fatalf("NewReminderStore() error = %v", err)
+	}
+	deadline := time.Date(2025, time.March, 17, 9, 30, 0, 0, time.UTC)
+	d := service.Deliverable{
+		ID: "d1", DealID: "deal-1", DealTitle: "Spring Trail", Title: "Instagram Reel",
+		CreatorEmail: "jane@example.com", CreatorName: "Jane", Deadline: deadline, TimeZone: "Europe/Berlin",
+	}
+	var reminders []service.ScheduledReminder
+	for _, offset := range []time.Duration{72 * time.Hour, 24 * time.Hour, time.Hour} {
+		reminders = append(reminders, service.ScheduledReminder{Deliverable: d, Offset: offset, SendAt: deadline.Add(-offset)})
+	}
+	if err := store.Replace("d1", reminders); err != nil {
+		t.Fatalf("Replace() error = %v", err)
+	}
+	if err := store.Delete("d1", 72*time.Hour, deadline.Add(-72*time.Hour)); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	// A reminder due at another time since is kept
+	if err := store.Delete("d1", 24*time.Hour, deadline); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	db.Close()
+
+	// The schedule is still there after reopening the database
+	db, err = Open(path)
+	if err != nil {
+		t.Fatalf("Open() again error = %v", err)
+	}
+	defer db.Close()
+	store, err = NewReminderStore(db)
+	if err != nil {
+		t.Fatalf("NewReminderStore() again error = %v", err)
+	}
+
+	due, err := store.Due(deadline)
+	if err != nil {
+		t.Fatalf("Due() error = %v", err)
+	}
+	if len(due) != 2 || due[0].Offset != 24*time.Hour || due[1].Offset != time.Hour {
+		t.Fatalf("Due() = %+v, want the 24 and 1 hour reminders in order", due)
+	}
+	if due[0].Deliverable != d {
+		t.Errorf("Deliverable = %+v, want %+v", due[0].Deliverable, d)
+	}
+	if due, _ := store.Due(deadline.Add(-2 * time.Hour)); len(due) != 1 {
+		t.Errorf("Due() two hours early = %d reminders, want 1", len(due))
+	}
+
+	// Replacing with nothing cancels
+	if err := store.Replace("d1", nil); err != nil {
+		t.Fatalf("Replace(nil) error = %v", err)
+	}
+	if due, _ := store.Due(deadline); len(due) != 0 {
+		t.Errorf("Due() after cancel = %d reminders, want none", len(due))
+	}
+}
@@ -0,0 +1,31 @@
+// Package sqlitestore implements the service stores on top of SQLite.
+package sqlitestore
+
+import (
+	"database/sql"
+	"fmt"
+
+	// Registers the "sqlite3" database/sql driver
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Open opens (creating if needed) the SQLite database at path. Use
+// ":memory:" for a throwaway database. Transactions take the write lock when
+// they begin, so one that reads and then writes can't interleave with
+// another process doing the same.
+func Open(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+
+	// SQLite serializes writers anyway, and an in-memory database only
+	// exists on the connection that created it
+	db.SetMaxOpenConns(1)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	return db, nil
+}
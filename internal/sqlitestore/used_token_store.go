@@ -0,0 +1,54 @@
+package sqlitestore
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sponsoration/api/internal/service"
+)
+
+// UsedTokenStore is a service.UsedTokenStore persisted in SQLite, so a
+// consumed link stays consumed across restarts
+type UsedTokenStore struct {
+	db *sql.DB
+}
+
+var _ service.UsedTokenStore = (*UsedTokenStore)(nil)
+
+// NewUsedTokenStore creates the used_tokens table if needed and returns a
+// store backed by it
+func NewUsedTokenStore(db *sql.DB) (*UsedTokenStore, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS used_tokens (
+			id         TEXT PRIMARY KEY,
+			expires_at INTEGER NOT NULL
+		)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create used_tokens table: %w", err)
+	}
+	return &UsedTokenStore{db: db}, nil
+}
+
+// MarkUsed records id as used. firstUse is false if it was already recorded.
+func (s *UsedTokenStore) MarkUsed(id string, expiresAt time.Time) (bool, error) {
+	res, err := s.db.Exec(
+		`INSERT INTO used_tokens (id, expires_at) VALUES (?, ?) ON CONFLICT (id) DO NOTHING`,
+		id, expiresAt.UnixNano())
+	if err != nil {
+		return false, fmt.Errorf("failed to record used token: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to record used token: %w", err)
+	}
+	return n == 1, nil
+}
+
+// DeleteExpired forgets tokens that expired before the given time
+func (s *UsedTokenStore) DeleteExpired(before time.Time) error {
+	if _, err := s.db.Exec(`DELETE FROM used_tokens WHERE expires_at < ?`, before.UnixNano()); err != nil {
+		return fmt.Errorf("failed to prune used tokens: %w", err)
+	}
+	return nil
+}
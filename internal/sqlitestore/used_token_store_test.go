@@ -0,0 +1,40 @@
+package sqlitestore
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUsedTokenStore(t *testing.T) {
+	db, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer db.Close()
+
+	store, err := NewUsedTokenStore(db)
+	if err != nil {
+		t.Fatalf("NewUsedTokenStore() error = %v", err)
+	}
+	now := time.Date(2025, time.March, 14, 9, 30, 0, 0, time.UTC)
+
+	if first, err := store.MarkUsed("a", now.Add(time.Hour)); err != nil || !first {
+		t.Errorf("MarkUsed(a) = %v, %v, want true", first, err)
+	}
+	if first, err := store.MarkUsed("a", now.Add(time.Hour)); err != nil || first {
+		t.Errorf("second MarkUsed(a) = %v, %v, want false", first, err)
+	}
+	if _, err := store.MarkUsed("b", now.Add(-time.Minute)); err != nil {
+		t.Fatalf("MarkUsed(b) error = %v", err)
+	}
+
+	if err := store.DeleteExpired(now); err != nil {
+		t.Fatalf("DeleteExpired() error = %v", err)
+	}
+	if first, _ := store.MarkUsed("b", now.Add(time.Hour)); !first {
+		t.Error("expired token b should have been forgotten")
+	}
+	if first, _ := store.MarkUsed("a", now.Add(time.Hour)); first {
+		t.Error("unexpired token a should still be recorded")
+	}
+}